@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// TestHeadless moves the balloons for a few hundred frames without a
+// window
+func TestHeadless(t *testing.T) {
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	h.Hold(200, input.NewSnapshot())
+	err := run(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Frames) != 200 {
+		t.Fatalf("presented %d frames, want 200", len(h.Frames))
+	}
+	if string(h.Frames[0].Pixels) == string(h.Frames[199].Pixels) {
+		t.Error("the balloon didn't move")
+	}
+}
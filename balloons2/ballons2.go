@@ -9,62 +9,85 @@ package main
 //			- Minimum translation vector (see pong.go) [hard]
 
 import (
+	"flag"
 	"fmt"
-	"image/png"
+	"image"
 	"math"
 	"math/rand"
-	"os"
 	"sort"
 	"time"
 
+	"github.com/stephen-mahon/games-with-go/capture"
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/loop"
+	"github.com/stephen-mahon/games-with-go/mixer"
 	"github.com/stephen-mahon/games-with-go/noise"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/platform/sdlplatform"
+	"github.com/stephen-mahon/games-with-go/replay"
+	"github.com/stephen-mahon/games-with-go/sprite"
 	. "github.com/stephen-mahon/games-with-go/vec3"
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/stephen-mahon/games-with-go/wav"
 )
 
 const winWidth, winHeight, winDepth int = 1280, 720, 100
 
 type audioState struct {
-	explosionBytes []byte
-	deviceID       sdl.AudioDeviceID
-	audioSpec      *sdl.AudioSpec
+	explosion *wav.Sound
+	mixer     *mixer.Mixer
 }
 
-type mouseState struct {
-	leftButton, rightButton bool
-	x, y                    int
+// pop plays the explosion from where the balloon is. It pans with x, and
+// deeper balloons are quieter and duller.
+func (audioState *audioState) pop(balloon *balloon) {
+	depth := clamp(0, 1, 1-balloon.pos.Z/float32(winDepth))
+	audioState.mixer.PlayOptions(audioState.explosion, mixer.Options{
+		Volume:  1 - 0.7*depth,
+		Pan:     clamp(-1, 1, balloon.pos.X/float32(winWidth)*2-1),
+		Pitch:   balloon.pitch,
+		LowPass: 20000 - 18000*depth,
+	})
 }
 
-func getMouseState() mouseState {
-	mouseX, mouseY, mouseButtonState := sdl.GetMouseState()
-	leftButton := mouseButtonState & sdl.ButtonLMask()
-	rightButton := mouseButtonState & sdl.ButtonRMask()
-	var result mouseState
-	result.x = int(mouseX)
-	result.y = int(mouseY)
-	result.leftButton = !(leftButton == 0)
-	result.rightButton = !(rightButton == 4)
-	return result
+func clamp(min, max, v float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
 }
 
 type balloon struct {
-	tex  *sdl.Texture
-	pos  Vector3
-	dir  Vector3
-	w, h int
+	tex     platform.Texture
+	pos     Vector3
+	prevPos Vector3
+	dir     Vector3
+	w, h    int
+	// pitch of the pop, each colour sounds a little different
+	pitch float32
 
 	exploding, exploded bool
-	explosionStart      time.Time
+	explosionElasped    float32
 	explosionInterval   float32
-	explosionTexture    *sdl.Texture
+	explosionTexture    platform.Texture
 }
 
-func newBalloon(tex *sdl.Texture, pos, dir Vector3, explosionTexture *sdl.Texture) *balloon {
-	_, _, w, h, err := tex.Query()
-	if err != nil {
-		panic(err)
-	}
-	return &balloon{tex, pos, dir, int(w), int(h), false, false, time.Now(), 20, explosionTexture}
+func newBalloon(tex platform.Texture, pos, dir Vector3, pitch float32, explosionTexture platform.Texture) *balloon {
+	w, h := tex.Size()
+	return &balloon{tex, pos, pos, dir, w, h, pitch, false, false, 0, 0.02, explosionTexture}
+}
+
+const numAnimation = 16
+
+// animationIndex counts down through the explosion frames, below zero the
+// explosion is over
+func (balloon *balloon) animationIndex() int {
+	return numAnimation - 1 - int(balloon.explosionElasped/balloon.explosionInterval)
 }
 
 type balloonArray []*balloon
@@ -93,41 +116,35 @@ func (balloon *balloon) getCircle() (x, y, r float32) {
 	return x, y, r
 }
 
-func updateBalloons(balloons []*balloon, elaspedTime float32,
-	currentMouseState mouseState,
-	prevMouseState mouseState,
-	audioState *audioState) []*balloon {
+func updateBalloons(balloons []*balloon, elaspedTime float32, inputs *input.Map, audioState *audioState) []*balloon {
 
-	numAnimation := 16
 	balloonClicked := false
 	balloonsExploded := false
 	for i := len(balloons) - 1; i >= 0; i-- {
 		balloon := balloons[i]
+		balloon.prevPos = balloon.pos
 
 		if balloon.exploding {
-			animationElasped := float32(time.Since(balloon.explosionStart).Seconds() * 1000)
-			animationIndex := numAnimation - 1 - int(animationElasped/balloon.explosionInterval)
-			if animationIndex < 0 {
+			balloon.explosionElasped += elaspedTime
+			if balloon.animationIndex() < 0 {
 				balloon.exploding = false
 				balloon.exploded = true
 				balloonsExploded = true
 			}
 		}
 
-		if !balloonClicked && !prevMouseState.leftButton && currentMouseState.leftButton {
+		if !balloonClicked && inputs.Pressed("pop") {
 			x, y, r := balloon.getCircle()
-			mouseX := currentMouseState.x
-			mouseY := currentMouseState.y
+			mouseX := inputs.Mouse().X
+			mouseY := inputs.Mouse().Y
 			xDiff := float32(mouseX) - x
 			yDiff := float32(mouseY) - y
 			dist := float32(math.Sqrt(float64(xDiff*xDiff + yDiff*yDiff)))
 			if dist < r {
 				balloonClicked = true
-				sdl.ClearQueuedAudio(audioState.deviceID)
-				sdl.QueueAudio(audioState.deviceID, audioState.explosionBytes)
-				sdl.PauseAudioDevice(audioState.deviceID, false)
+				audioState.pop(balloon)
 				balloon.exploding = true
-				balloon.explosionStart = time.Now()
+				balloon.explosionElasped = 0
 			}
 		}
 		p := Add(balloon.pos, Mult(balloon.dir, elaspedTime))
@@ -157,247 +174,191 @@ func updateBalloons(balloons []*balloon, elaspedTime float32,
 	return balloons
 }
 
-func (balloon *balloon) draw(renderer *sdl.Renderer) {
+func (balloon *balloon) draw(plat platform.Platform, alpha float32) {
 	scale := balloon.getScale()
-	newW := int32(float32(balloon.w) * scale)
-	newH := int32(float32(balloon.h) * scale)
-	x := int32(balloon.pos.X - float32(newW)/2)
-	y := int32(balloon.pos.Y - float32(newH)/2)
-	rect := &sdl.Rect{x, y, newW, newH}
-	renderer.Copy(balloon.tex, nil, rect)
+	newW := int(float32(balloon.w) * scale)
+	newH := int(float32(balloon.h) * scale)
+	x := int(loop.Lerp(balloon.prevPos.X, balloon.pos.X, alpha) - float32(newW)/2)
+	y := int(loop.Lerp(balloon.prevPos.Y, balloon.pos.Y, alpha) - float32(newH)/2)
+	rect := image.Rect(x, y, x+newW, y+newH)
+	plat.Draw(balloon.tex, image.Rectangle{}, rect)
 
 	if balloon.exploding {
-		numAnimation := 16
-		animationElasped := float32(time.Since(balloon.explosionStart).Seconds() * 1000)
-		animationIndex := numAnimation - 1 - int(animationElasped/balloon.explosionInterval)
+		animationIndex := balloon.animationIndex()
 		animationX := animationIndex % 4
 		animationY := 64 * ((animationIndex - animationX) / 4)
 		animationX *= 64
-		animationRect := &sdl.Rect{int32(animationX), int32(animationY), 64, 64}
-		rect.X -= rect.W / 2
-		rect.Y -= rect.H / 2
-		rect.W *= 2
-		rect.H *= 2
-		renderer.Copy(balloon.explosionTexture, animationRect, rect)
-	}
-}
-
-type rgba struct {
-	r, g, b byte
-}
-
-func clear(pixels []byte) {
-	for i := range pixels {
-		pixels[i] = 0
-	}
-}
-
-func setPixel(x, y int, c rgba, pixels []byte) {
-	index := (y*winWidth + x) * 4
-	if index < len(pixels)-4 && index >= 0 {
-		pixels[index] = c.r
-		pixels[index+1] = c.g
-		pixels[index+2] = c.b
-	}
-}
-
-func pixelsToTexture(renderer *sdl.Renderer, pixels []byte, w, h int) *sdl.Texture {
-	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(w), int32(h))
-	if err != nil {
-		panic(err)
+		animationRect := image.Rect(animationX, animationY, animationX+64, animationY+64)
+		rect.Min.X -= newW / 2
+		rect.Min.Y -= newH / 2
+		rect.Max.X = rect.Min.X + newW*2
+		rect.Max.Y = rect.Min.Y + newH*2
+		plat.Draw(balloon.explosionTexture, animationRect, rect)
 	}
-	tex.Update(nil, pixels, w*4)
-	return tex
 }
 
-func imgFileToTexture(renderer *sdl.Renderer, filename string) *sdl.Texture {
-
-	infile, err := os.Open(filename)
+func loadTexture(plat platform.Platform, filename string) platform.Texture {
+	s, err := sprite.Load(filename)
 	if err != nil {
 		panic(err)
 	}
-	defer infile.Close()
-	// png decoder outputs an image with a premultiplied alpha rgb, i.e. ((r*alpha),(g*alpha),(b*alpha),alpha)
-	img, err := png.Decode(infile)
-	if err != nil {
-		panic(err)
-	}
-
-	w := img.Bounds().Max.X
-	h := img.Bounds().Max.Y
-
-	pixels := make([]byte, w*h*4)
-	bIndex := 0
-	for y := 0; y < h; y++ {
-		for x := 0; x < w; x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			pixels[bIndex] = byte(r / 256)
-			bIndex++
-			pixels[bIndex] = byte(g / 256)
-			bIndex++
-			pixels[bIndex] = byte(b / 256)
-			bIndex++
-			pixels[bIndex] = byte(a / 256)
-			bIndex++
-		}
-	}
-	tex := pixelsToTexture(renderer, pixels, w, h)
-	err = tex.SetBlendMode(sdl.BLENDMODE_BLEND)
+	tex, err := plat.NewTexture(s)
 	if err != nil {
 		panic(err)
 	}
 	return tex
 }
 
-func loadBalloon(renderer *sdl.Renderer, numBallons int) []*balloon {
+func loadBalloon(plat platform.Platform, numBallons int) []*balloon {
 
-	explosionTexture := imgFileToTexture(renderer, "explosion.png")
+	explosionTexture := loadTexture(plat, "explosion.png")
 
 	balloonStrs := []string{"balloon_red.png", "balloon_green.png", "balloon_blue.png"}
-	balloonTextures := make([]*sdl.Texture, len(balloonStrs))
+	balloonPitches := []float32{1, 1.2, 0.85}
+	balloonTextures := make([]platform.Texture, len(balloonStrs))
 
 	for i, bstr := range balloonStrs {
-		balloonTextures[i] = imgFileToTexture(renderer, bstr)
+		balloonTextures[i] = loadTexture(plat, bstr)
 	}
 
 	balloons := make([]*balloon, numBallons)
 	for i := range balloons {
 		tex := balloonTextures[i%3]
-		pos := Vector3{rand.Float32() * float32(winWidth), rand.Float32() * float32(winHeight), rand.Float32() * float32(winDepth)}
-		dir := Vector3{rand.Float32()*0.5 - 0.25, rand.Float32()*0.5 - 0.25, rand.Float32()*0.25 - 0.25/2}
-		balloons[i] = newBalloon(tex, pos, dir, explosionTexture)
+		pos := Vector3{X: rand.Float32() * float32(winWidth), Y: rand.Float32() * float32(winHeight), Z: rand.Float32() * float32(winDepth)}
+		dir := Vector3{X: rand.Float32()*500 - 250, Y: rand.Float32()*500 - 250, Z: rand.Float32()*250 - 250/2}
+		balloons[i] = newBalloon(tex, pos, dir, balloonPitches[i%3], explosionTexture)
 	}
 
 	return balloons
 }
 
-func lerp(b1 byte, b2 byte, pct float32) byte {
-	return byte(float32(b1) + pct*(float32(b2)-float32(b1)))
-}
-
-func colorLerp(c1, c2 rgba, pct float32) rgba {
-	return rgba{lerp(c1.r, c2.r, pct), lerp(c1.g, c2.g, pct), lerp(c1.b, c2.b, pct)}
-}
+func main() {
+	recordFile := flag.String("record", "", "record the game to a file")
+	replayFile := flag.String("replay", "", "play back a recorded game")
+	framesDir := flag.String("frames", "", "save every frame as a png in this directory")
+	fps := flag.Int("fps", 60, "frame rate for -frames")
+	flag.Parse()
 
-func getGradient(c1, c2 rgba) []rgba {
-	result := make([]rgba, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		result[i] = colorLerp(c1, c2, pct)
+	sdlPlat, err := sdlplatform.New("Exploding Balloons", winWidth, winHeight)
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-	return result
-}
-
-func getDualGradient(c1, c2, c3, c4 rgba) []rgba {
-	result := make([]rgba, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		if pct < 0.5 {
-			result[i] = colorLerp(c1, c2, pct*float32(2))
-		} else {
-			result[i] = colorLerp(c3, c4, pct*float32(1.5)-float32(0.5))
+	capt := capture.New(sdlPlat)
+	if *framesDir != "" {
+		err = capt.Sequence(*framesDir, *fps)
+		if err != nil {
+			capt.Close()
+			fmt.Println(err)
+			return
 		}
 	}
-	return result
-}
-
-func clamp(min, max, v int) int {
-	if v < min {
-		v = min
-	} else if v > max {
-		v = max
-	}
-	return v
-}
-
-func rescaleAndDraw(noise []float32, min, max float32, gradient []rgba, w, h int) []byte {
-	result := make([]byte, w*h*4)
-	scale := 255.0 / (max - min)
-	offset := min * scale
-
-	for i := range noise {
-		noise[i] = noise[i]*scale - offset
-		c := gradient[clamp(0, 255, int(noise[i]))]
-		p := i * 4
-		result[p] = c.r
-		result[p+1] = c.g
-		result[p+2] = c.b
-	}
-	return result
-}
-
-func main() {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
+	plat, seed, err := replay.Open(capt, *recordFile, *replayFile)
 	if err != nil {
+		capt.Close()
 		fmt.Println(err)
 		return
 	}
-	defer sdl.Quit()
+	rand.Seed(seed)
 
-	window, err := sdl.CreateWindow("Exploding Balloons", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(winWidth), int32(winHeight), sdl.WINDOW_SHOWN)
+	err = run(plat)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer window.Destroy()
-
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	if err := capt.Err(); err != nil {
+		fmt.Println(err)
+	}
+	err = replay.Close(plat)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer renderer.Destroy()
+}
+
+// field is the sky and the balloons in it
+type field struct {
+	balloons  []*balloon
+	cloud, fb *framebuffer.Framebuffer
+	audio     audioState
+}
 
-	explosionBytes, audioSpec := sdl.LoadWAV("explode.wav")
-	audioID, err := sdl.OpenAudioDevice("", false, audioSpec, nil, 0)
+func newField(plat platform.Platform, numBalloons int) (*field, error) {
+	explosion, err := wav.Load("explode.wav")
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
 	}
-	defer sdl.FreeWAV(explosionBytes)
+	cloudNoise, min, max := noise.MakeNoise(noise.FBM, .009, .5, 3, 3, winWidth, winHeight)
+	cloudGradient := palette.NewGradient(palette.Color{B: 255}, palette.Color{R: 255, G: 255, B: 255})
+	cloud := framebuffer.New(winWidth, winHeight)
+	cloudGradient.Draw(cloudNoise, min, max, cloud.Pixels)
+	return &field{
+		balloons: loadBalloon(plat, numBalloons),
+		cloud:    cloud,
+		fb:       framebuffer.New(winWidth, winHeight),
+		audio:    audioState{explosion, mixer.New(48000)},
+	}, nil
+}
 
-	audioState := audioState{explosionBytes, audioID, audioSpec}
+// update moves the balloons on and drops the ones that have finished
+// exploding
+func (f *field) update(elaspedTime float32, inputs *input.Map) {
+	f.balloons = updateBalloons(f.balloons, elaspedTime, inputs, &f.audio)
+}
 
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "1")
+func (f *field) draw(plat platform.Platform, alpha float32) {
+	f.fb.Blit(f.cloud, 0, 0)
+	font.Default().Draw(f.fb, fmt.Sprintf("Balloons: %d", len(f.balloons)), 10, 10, 4, font.Left, palette.Color{R: 255, G: 255, B: 255})
+	plat.DrawFramebuffer(f.fb)
 
-	cloudNoise, min, max := noise.MakeNoise(noise.FBM, .009, .5, 3, 3, winWidth, winHeight)
-	cloudGradient := getGradient(rgba{0, 0, 255}, rgba{255, 255, 255})
-	cloudPixels := rescaleAndDraw(cloudNoise, min, max, cloudGradient, winWidth, winHeight)
-	cloudTexture := pixelsToTexture(renderer, cloudPixels, winWidth, winHeight)
-
-	balloons := loadBalloon(renderer, 3)
-	var elaspedTime float32
-	currentMouseState := getMouseState()
-	prevMouseState := currentMouseState
-
-	for {
-		frameStart := time.Now()
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return
-			}
-		}
-		currentMouseState = getMouseState()
+	sort.Stable(balloonArray(f.balloons))
+	for _, balloon := range f.balloons {
+		balloon.draw(plat, alpha)
+	}
+}
 
-		renderer.Copy(cloudTexture, nil, nil)
-		balloons := updateBalloons(balloons, elaspedTime, currentMouseState, prevMouseState, &audioState)
-		sort.Stable(balloonArray(balloons))
-		for _, balloon := range balloons {
-			balloon.draw(renderer)
-		}
+// run pops balloons until the platform's window closes
+func run(plat platform.Platform) error {
+	f, err := newField(plat, 3)
+	if err != nil {
+		return err
+	}
+	err = plat.OpenAudio(platform.AudioSpec{Freq: 48000, Channels: 2})
+	if err != nil {
+		return err
+	}
 
-		renderer.Present()
-		// basic framerate independence setup
-		elaspedTime = float32(time.Since(frameStart).Seconds() * 1000)
-		//fmt.Println("ms per frame:", elaspedTime)
-		if elaspedTime < 5 {
-			sdl.Delay(5 - uint32(elaspedTime))
-			elaspedTime = float32(time.Since(frameStart).Seconds() * 1000)
-		}
-		prevMouseState = currentMouseState
+	inputs := input.NewMap()
+	inputs.Bind("pop", input.BindMouse(input.MouseLeft))
+	err = inputs.LoadFile("controls.txt")
+	if err != nil {
+		return err
+	}
+
+	update := func(elaspedTime float32) {
+		f.update(elaspedTime, inputs)
+		f.audio.mixer.Pump(plat, time.Second/20)
+		inputs.Tick()
+	}
 
+	render := func(alpha float32) {
+		f.draw(plat, alpha)
+		plat.Present()
 	}
 
+	gameLoop, err := loop.New(120)
+	if err != nil {
+		return err
+	}
+	gameLoop.MaxFPS = 200
+	gameLoop.Clock = plat
+	// read the input every frame, so a tap on a frame with no update
+	// still counts
+	events := func() bool {
+		if !plat.Poll() {
+			return false
+		}
+		inputs.Update(plat.Input())
+		return true
+	}
+	gameLoop.Run(events, update, render)
+	return nil
 }
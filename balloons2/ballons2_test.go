@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/golden"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+	. "github.com/stephen-mahon/games-with-go/vec3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// TestGolden renders a balloon field from a fixed seed headless and
+// compares it with testdata/field.png
+func TestGolden(t *testing.T) {
+	rand.Seed(1)
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	h.Script(input.NewSnapshot())
+	err := run(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = golden.Check("testdata/field.png", h.Frames[0], golden.DefaultTolerance, *update)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestHeadless pops balloons for a few hundred frames without a window
+func TestHeadless(t *testing.T) {
+	rand.Seed(1)
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	// click all over the field, a few frames at each place
+	frames := 0
+	for y := 40; y < winHeight; y += 80 {
+		for x := 40; x < winWidth; x += 80 {
+			click := input.NewSnapshot()
+			click.Mouse = input.Mouse{X: x, Y: y, Buttons: 1 << uint(input.MouseLeft-1)}
+			h.Hold(2, click)
+			h.Hold(1, input.NewSnapshot())
+			frames += 3
+		}
+	}
+	err := run(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Frames) != frames {
+		t.Fatalf("presented %d frames, want %d", len(h.Frames), frames)
+	}
+	if string(h.Frames[0].Pixels) == string(h.Frames[frames-1].Pixels) {
+		t.Error("nothing moved")
+	}
+}
+
+// TestPopRemovesBalloon clicks one balloon and steps until its explosion
+// is over, the field should be left with the other two once each and
+// count them
+func TestPopRemovesBalloon(t *testing.T) {
+	rand.Seed(1)
+	h := platform.NewHeadless(winWidth, winHeight)
+	f, err := newField(h, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hold the balloons still and well apart
+	for i, b := range f.balloons {
+		b.pos = Vector3{X: float32(200 + 400*i), Y: 300, Z: 50}
+		b.prevPos, b.dir = b.pos, Vector3{}
+	}
+	popped := f.balloons[1]
+	x, y, _ := popped.getCircle()
+
+	checkCounter(t, h, f, 3)
+
+	inputs := input.NewMap()
+	inputs.Bind("pop", input.BindMouse(input.MouseLeft))
+	click := input.NewSnapshot()
+	click.Mouse = input.Mouse{X: int(x), Y: int(y), Buttons: 1 << uint(input.MouseLeft-1)}
+	inputs.Update(click)
+	for i := 0; i < 120; i++ {
+		f.update(1.0/120, inputs)
+		inputs.Tick()
+		inputs.Update(input.NewSnapshot())
+	}
+
+	if len(f.balloons) != 2 {
+		t.Fatalf("%d balloons left, want 2", len(f.balloons))
+	}
+	seen := make(map[*balloon]bool)
+	for _, b := range f.balloons {
+		if b == popped {
+			t.Error("the popped balloon is still in the field")
+		}
+		if seen[b] {
+			t.Error("a balloon is in the field twice")
+		}
+		seen[b] = true
+	}
+	checkCounter(t, h, f, 2)
+}
+
+// checkCounter draws the field and checks the sky shows the counter for n
+// balloons
+func checkCounter(t *testing.T, h *platform.Headless, f *field, n int) {
+	t.Helper()
+	f.draw(h, 0)
+	want := framebuffer.New(winWidth, winHeight)
+	want.Blit(f.cloud, 0, 0)
+	font.Default().Draw(want, fmt.Sprintf("Balloons: %d", n), 10, 10, 4, font.Left, palette.Color{R: 255, G: 255, B: 255})
+	if string(f.fb.Pixels) != string(want.Pixels) {
+		t.Errorf("the counter doesn't read %d", n)
+	}
+}
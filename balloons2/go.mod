@@ -3,6 +3,10 @@ module github.com/stephen-mahon/games-with-go/baloons
 go 1.16
 
 require (
-	github.com/stephen-mahon/games-with-go v0.0.0-20210704045746-b888eebdf9b4 // indirect
-	github.com/veandco/go-sdl2 v0.4.8
+	github.com/stephen-mahon/games-with-go v0.0.0-00010101000000-000000000000
+	github.com/stephen-mahon/games-with-go/vec3 v0.0.0-00010101000000-000000000000
 )
+
+replace github.com/stephen-mahon/games-with-go => ../
+
+replace github.com/stephen-mahon/games-with-go/vec3 => ../vec3
@@ -0,0 +1,145 @@
+// Screenshots and numbered png frame sequences
+package capture
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// SavePNG writes a framebuffer to a png file
+func SavePNG(filename string, fb *framebuffer.Framebuffer) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, fb.Image())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Capture is a platform that saves a screenshot of the next frame when Key
+// is pressed. With a sequence started it also saves every frame and runs
+// the clock a fixed step per frame, however long drawing and saving take,
+// so the frames play back smoothly at that rate.
+//
+// Wrap Capture in a replay recorder or player rather than the other way
+// round. A replay's recorded times then win over the fixed step.
+type Capture struct {
+	platform.Platform
+	// Key takes a screenshot
+	Key input.Key
+	// Dir is where screenshots go
+	Dir string
+
+	shot     bool
+	keyDown  bool
+	seqDir   string
+	seqStep  time.Duration
+	seqFrame int
+	now      time.Time
+	err      error
+}
+
+// New wraps p, taking screenshots into the working directory on F12
+func New(p platform.Platform) *Capture {
+	return &Capture{Platform: p, Key: input.KeyF12, Dir: "."}
+}
+
+// Sequence saves every frame from now on to dir as frame-00000.png and so
+// on, with the clock running at fps frames a second
+func (c *Capture) Sequence(dir string, fps int) error {
+	if fps <= 0 {
+		return fmt.Errorf("capture: bad frame rate %d", fps)
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	c.seqDir = dir
+	c.seqStep = time.Second / time.Duration(fps)
+	c.now = c.Platform.Now()
+	return nil
+}
+
+func (c *Capture) Poll() bool {
+	if !c.Platform.Poll() {
+		return false
+	}
+	s := c.Platform.Input()
+	down := int(c.Key) < len(s.Keys) && s.Keys[c.Key] != 0
+	if down && !c.keyDown {
+		c.shot = true
+	}
+	c.keyDown = down
+	if c.seqDir != "" {
+		c.now = c.now.Add(c.seqStep)
+	}
+	return true
+}
+
+func (c *Capture) Now() time.Time {
+	if c.seqDir != "" {
+		return c.now
+	}
+	return c.Platform.Now()
+}
+
+func (c *Capture) Sleep(d time.Duration) {
+	if c.seqDir == "" {
+		c.Platform.Sleep(d)
+	}
+}
+
+// Present saves the frame if a screenshot is due or a sequence is running,
+// then shows it. A failure to save doesn't stop the game, it ends any
+// sequence and is kept for Err.
+func (c *Capture) Present() {
+	if c.shot || c.seqDir != "" {
+		fb, err := c.Platform.ReadPixels()
+		if err == nil {
+			err = c.save(fb)
+		}
+		if err != nil {
+			c.fail(err)
+		}
+	}
+	c.Platform.Present()
+}
+
+// Err is the first screenshot or frame that couldn't be saved
+func (c *Capture) Err() error {
+	return c.err
+}
+
+func (c *Capture) fail(err error) {
+	c.shot = false
+	c.seqDir = ""
+	if c.err == nil {
+		c.err = fmt.Errorf("capture: %v", err)
+	}
+}
+
+func (c *Capture) save(fb *framebuffer.Framebuffer) error {
+	if c.shot {
+		c.shot = false
+		name := filepath.Join(c.Dir, "screenshot-"+time.Now().Format("20060102-150405.000")+".png")
+		if err := SavePNG(name, fb); err != nil {
+			return err
+		}
+	}
+	if c.seqDir != "" {
+		name := filepath.Join(c.seqDir, fmt.Sprintf("frame-%05d.png", c.seqFrame))
+		c.seqFrame++
+		return SavePNG(name, fb)
+	}
+	return nil
+}
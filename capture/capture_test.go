@@ -0,0 +1,53 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+func TestScreenshot(t *testing.T) {
+	h := platform.NewHeadless(4, 4)
+	up, down := input.NewSnapshot(), input.NewSnapshot(input.KeyF12)
+	h.Script(up, down, down, up)
+
+	c := New(h)
+	c.Dir = t.TempDir()
+	for c.Poll() {
+		c.Present()
+	}
+	if err := c.Err(); err != nil {
+		t.Fatal(err)
+	}
+	shots, _ := filepath.Glob(filepath.Join(c.Dir, "screenshot-*.png"))
+	if len(shots) != 1 {
+		t.Errorf("holding the key took %d screenshots, want 1", len(shots))
+	}
+}
+
+func TestSequenceError(t *testing.T) {
+	h := platform.NewHeadless(4, 4)
+	h.Hold(3, &input.Snapshot{})
+	c := New(h)
+	dir := t.TempDir()
+	if err := c.Sequence(dir, 30); err != nil {
+		t.Fatal(err)
+	}
+	c.Poll()
+	c.Present()
+	// frames can't be saved once the directory has gone
+	os.RemoveAll(dir)
+	c.Poll()
+	c.Present()
+	if c.Err() == nil {
+		t.Fatal("a frame that couldn't be saved wasn't reported")
+	}
+	c.Poll()
+	c.Present()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("the sequence carried on after failing")
+	}
+}
@@ -70,7 +70,7 @@ func (op *OpAtan2) Eval(x, y float32) float32 {
 }
 
 func (op *OpAtan2) String() string {
-	return "( Atan2 " + op.LeftChild.String() + op.RightChild.String() + " )"
+	return "( Atan2 " + op.LeftChild.String() + " " + op.RightChild.String() + " )"
 }
 
 type OpNoise struct {
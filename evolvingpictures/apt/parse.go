@@ -0,0 +1,117 @@
+package apt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse reads a tree back from the form String writes, e.g.
+//
+//	( + Y ( Sin ( Atan2 X ( SimplexNoise X Y ) ) ) )
+func Parse(s string) (Node, error) {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	p := &parser{tokens: strings.Fields(s)}
+	node, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("apt: unexpected %q after the expression", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("apt: expression ends early")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) node() (Node, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case "X":
+		return &OpX{}, nil
+	case "Y":
+		return &OpY{}, nil
+	case "(":
+	default:
+		v, err := strconv.ParseFloat(t, 32)
+		if err != nil {
+			return nil, fmt.Errorf("apt: unknown leaf %q", t)
+		}
+		return &OpConstant{value: float32(v)}, nil
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	var node Node
+	var single *SingleNode
+	var double *DoubleNode
+	switch op {
+	case "Sin":
+		n := &OpSin{}
+		node, single = n, &n.SingleNode
+	case "Cos":
+		n := &OpCos{}
+		node, single = n, &n.SingleNode
+	case "Atan":
+		n := &OpAtan{}
+		node, single = n, &n.SingleNode
+	case "Atan2":
+		n := &OpAtan2{}
+		node, double = n, &n.DoubleNode
+	case "SimplexNoise":
+		n := &OpNoise{}
+		node, double = n, &n.DoubleNode
+	case "+":
+		n := &OpPlus{}
+		node, double = n, &n.DoubleNode
+	case "-":
+		n := &OpMinus{}
+		node, double = n, &n.DoubleNode
+	case "*":
+		n := &OpMult{}
+		node, double = n, &n.DoubleNode
+	case "/":
+		n := &OpDiv{}
+		node, double = n, &n.DoubleNode
+	default:
+		return nil, fmt.Errorf("apt: unknown operator %q", op)
+	}
+
+	if single != nil {
+		single.Child, err = p.node()
+	} else {
+		double.LeftChild, err = p.node()
+		if err == nil {
+			double.RightChild, err = p.node()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err = p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t != ")" {
+		return nil, fmt.Errorf("apt: %s has too many arguments", op)
+	}
+	return node, nil
+}
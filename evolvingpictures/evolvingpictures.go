@@ -1,71 +1,27 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"time"
+	"image"
+	"os"
 
+	"github.com/stephen-mahon/games-with-go/capture"
 	. "github.com/stephen-mahon/games-with-go/evolvingpictures/apt"
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/loop"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/platform/sdlplatform"
+	"github.com/stephen-mahon/games-with-go/sprite"
 )
 
 const winWidth, winHeight, winDepth int = 800, 600, 100
 
-type audioState struct {
-	explosionBytes []byte
-	deviceID       sdl.AudioDeviceID
-	audioSpec      *sdl.AudioSpec
-}
-
-type mouseState struct {
-	leftButton, rightButton bool
-	x, y                    int
-}
-
-func getMouseState() mouseState {
-	mouseX, mouseY, mouseButtonState := sdl.GetMouseState()
-	leftButton := mouseButtonState & sdl.ButtonLMask()
-	rightButton := mouseButtonState & sdl.ButtonRMask()
-	var result mouseState
-	result.x = int(mouseX)
-	result.y = int(mouseY)
-	result.leftButton = !(leftButton == 0)
-	result.rightButton = !(rightButton == 4)
-	return result
-}
-
-type rgba struct {
-	r, g, b byte
-}
-
-func clear(pixels []byte) {
-	for i := range pixels {
-		pixels[i] = 0
-	}
-}
-
-func setPixel(x, y int, c rgba, pixels []byte) {
-	index := (y*winWidth + x) * 4
-	if index < len(pixels)-4 && index >= 0 {
-		pixels[index] = c.r
-		pixels[index+1] = c.g
-		pixels[index+2] = c.b
-	}
-}
-
-func pixelsToTexture(renderer *sdl.Renderer, pixels []byte, w, h int) *sdl.Texture {
-	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(w), int32(h))
-	if err != nil {
-		panic(err)
-	}
-	tex.Update(nil, pixels, w*4)
-	return tex
-}
-
-func aptToTexture(redNode, greenNode, blueNode Node, w, h int, renderer *sdl.Renderer) *sdl.Texture {
+func aptToSprite(redNode, greenNode, blueNode Node, w, h int) *sprite.Sprite {
 	scale := float32(255 / 2)
 	offset := float32(-1.0 * scale)
-	pixels := make([]byte, w*h*4)
-	pixelIndex := 0
+	fb := framebuffer.New(w, h)
 	for yi := 0; yi < h; yi++ {
 		y := float32(yi)/float32(h)*2 - 1
 		for xi := 0; xi < w; xi++ {
@@ -75,58 +31,53 @@ func aptToTexture(redNode, greenNode, blueNode Node, w, h int, renderer *sdl.Ren
 			g := greenNode.Eval(x, y)
 			b := blueNode.Eval(x, y)
 
-			pixels[pixelIndex] = byte(r*scale - offset)
-			pixelIndex++
-			pixels[pixelIndex] = byte(g*scale - offset)
-			pixelIndex++
-			pixels[pixelIndex] = byte(b*scale - offset)
-			pixelIndex++
-			pixelIndex++
+			fb.SetPixel(xi, yi, palette.Color{R: byte(r*scale - offset), G: byte(g*scale - offset), B: byte(b*scale - offset)})
 		}
 	}
-	return pixelsToTexture(renderer, pixels, w, h)
+	return &sprite.Sprite{Pixels: fb.Pixels, W: fb.W, H: fb.H, Pitch: fb.Stride}
 }
 
 func main() {
-	err := sdl.Init(sdl.INIT_EVERYTHING)
-	if err != nil {
-		fmt.Println(err)
-		return
+	framesDir := flag.String("frames", "", "save every frame as a png in this directory")
+	fps := flag.Int("fps", 60, "frame rate for -frames")
+	load := flag.String("load", "", "show the picture saved in this file")
+	flag.Parse()
+
+	picture := defaultPicture()
+	if *load != "" {
+		var err error
+		picture, err = loadPicture(*load)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
 	}
-	defer sdl.Quit()
 
-	window, err := sdl.CreateWindow("Evolving Pictures", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(winWidth), int32(winHeight), sdl.WINDOW_SHOWN)
+	sdlPlat, err := sdlplatform.New("Evolving Pictures", winWidth, winHeight)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer window.Destroy()
+	plat := capture.New(sdlPlat)
+	defer plat.Close()
+	if *framesDir != "" {
+		err = plat.Sequence(*framesDir, *fps)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	err = run(plat, picture)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer renderer.Destroy()
-
-	/* explosionBytes, audioSpec := sdl.LoadWAV("explode.wav")
-	audioID, err := sdl.OpenAudioDevice("", false, audioSpec, nil, 0)
-	if err != nil {
+	if err := plat.Err(); err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer sdl.FreeWAV(explosionBytes)
-
-	audioState := audioState{explosionBytes, audioID, audioSpec}
-	*/
-
-	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "1")
-
-	var elaspedTime float32
-	//currentMouseState := getMouseState()
-	//prevMouseState := currentMouseState
+}
 
+func defaultPicture() Node {
 	x := &OpX{}
 	y := &OpY{}
 	sine := &OpSin{}
@@ -134,34 +85,49 @@ func main() {
 	atan2 := &OpAtan2{}
 	plus := &OpPlus{}
 
+	noise.LeftChild = x
+	noise.RightChild = y
 	atan2.LeftChild = x
 	atan2.RightChild = noise
 
 	sine.Child = atan2
 	plus.LeftChild = y
 	plus.RightChild = sine
+	return plus
+}
 
-	tex := aptToTexture(plus, plus, plus, 640, 480, renderer)
+// loadPicture reads a tree saved in the form Node.String writes
+func loadPicture(filename string) (Node, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(b))
+}
 
-	for {
-		frameStart := time.Now()
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return
-			}
-		}
-		//currentMouseState = getMouseState()
+// run shows the picture until the platform's window closes
+func run(plat platform.Platform, picture Node) error {
+	tex, err := plat.NewTexture(aptToSprite(picture, picture, picture, 640, 480))
+	if err != nil {
+		return err
+	}
+	defer tex.Destroy()
 
-		renderer.Copy(tex, nil, nil)
+	update := func(elaspedTime float32) {
+	}
 
-		renderer.Present()
-		elaspedTime = float32(time.Since(frameStart).Seconds() * 1000)
-		//fmt.Println("ms per frame:", elaspedTime)
-		if elaspedTime < 5 {
-			sdl.Delay(5 - uint32(elaspedTime))
-			elaspedTime = float32(time.Since(frameStart).Seconds() * 1000)
-		}
-		//prevMouseState = currentMouseState
+	render := func(alpha float32) {
+		w, h := plat.Size()
+		plat.Draw(tex, image.Rectangle{}, image.Rect(0, 0, w, h))
+		plat.Present()
+	}
+
+	gameLoop, err := loop.New(120)
+	if err != nil {
+		return err
 	}
+	gameLoop.MaxFPS = 200
+	gameLoop.Clock = plat
+	gameLoop.Run(plat.Poll, update, render)
+	return nil
 }
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/golden"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// TestGolden renders the picture saved in testdata/picture.apt headless
+// and compares it with testdata/picture.png
+func TestGolden(t *testing.T) {
+	picture, err := loadPicture("testdata/picture.apt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	h.Script(input.NewSnapshot())
+	err = run(h, picture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = golden.Check("testdata/picture.png", h.Frames[0], golden.DefaultTolerance, *update)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -2,7 +2,6 @@ module github.com/stephen-mahon/games-with-go/evolvingpictures
 
 go 1.16
 
-require (
-	github.com/stephen-mahon/games-with-go v0.0.0-20210713200303-69ba5697b73e
-	github.com/veandco/go-sdl2 v0.4.8
-)
+require github.com/stephen-mahon/games-with-go v0.0.0-00010101000000-000000000000
+
+replace github.com/stephen-mahon/games-with-go => ../
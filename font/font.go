@@ -0,0 +1,207 @@
+// Bitmap fonts drawn from a glyph sheet
+package font
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// Glyph is where a character sits on the sheet and how to place it
+type Glyph struct {
+	X, Y, W, H       int
+	XOffset, YOffset int
+	Advance          int
+}
+
+// Font is a glyph sheet plus the metrics to lay text out with it. Only the
+// sheet's alpha is used, so glyphs can be drawn in any colour.
+type Font struct {
+	Sheet      *sprite.Sprite
+	Glyphs     map[rune]Glyph
+	Kerning    map[[2]rune]int
+	LineHeight int
+	// Fallback is drawn for runes with no glyph
+	Fallback rune
+}
+
+// Align indicates where the x position given to Draw is on each line
+type Align int
+
+const (
+	Left Align = iota
+	Center
+	Right
+)
+
+// Placed is a glyph laid out at a screen position
+type Placed struct {
+	Glyph
+	X, Y  int
+	Scale int
+}
+
+// Load reads a png glyph sheet and its metrics file
+func Load(sheetFile, metricsFile string) (*Font, error) {
+	sheet, err := sprite.Load(sheetFile)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(metricsFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadMetrics(f, sheet)
+}
+
+// LoadMetrics reads the metrics for a sheet. Each line is one of
+//
+//	lineheight 6
+//	glyph 65 0 0 3 5 4 0 0   // code x y w h advance [xoffset yoffset]
+//	kern 65 86 -1            // code code adjustment
+//
+// Characters are given by their unicode code point. Without a lineheight
+// line the line height is that of the tallest glyph.
+func LoadMetrics(r io.Reader, sheet *sprite.Sprite) (*Font, error) {
+	f := &Font{Sheet: sheet, Glyphs: make(map[rune]Glyph), Kerning: make(map[[2]rune]int), Fallback: '?'}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		nums := make([]int, len(fields)-1)
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseInt(field, 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("font: line %d: %v", line, err)
+			}
+			nums[i] = int(v)
+		}
+		switch {
+		case fields[0] == "lineheight" && len(nums) == 1:
+			f.LineHeight = nums[0]
+		case fields[0] == "glyph" && (len(nums) == 6 || len(nums) == 8):
+			g := Glyph{X: nums[1], Y: nums[2], W: nums[3], H: nums[4], Advance: nums[5]}
+			if len(nums) == 8 {
+				g.XOffset, g.YOffset = nums[6], nums[7]
+			}
+			if g.X < 0 || g.Y < 0 || g.X+g.W > sheet.W || g.Y+g.H > sheet.H {
+				return nil, fmt.Errorf("font: line %d: glyph is off the sheet", line)
+			}
+			f.Glyphs[rune(nums[0])] = g
+		case fields[0] == "kern" && len(nums) == 3:
+			f.Kerning[[2]rune{rune(nums[0]), rune(nums[1])}] = nums[2]
+		default:
+			return nil, fmt.Errorf("font: line %d: can't read %q", line, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if f.LineHeight == 0 {
+		for _, g := range f.Glyphs {
+			if g.H+g.YOffset > f.LineHeight {
+				f.LineHeight = g.H + g.YOffset
+			}
+		}
+	}
+	if f.LineHeight <= 0 {
+		// text is laid out and scaled by the line height
+		return nil, fmt.Errorf("font: line height is %d, want a lineheight line or glyphs", f.LineHeight)
+	}
+	return f, nil
+}
+
+func (f *Font) glyph(r rune) (Glyph, bool) {
+	g, ok := f.Glyphs[r]
+	if !ok {
+		g, ok = f.Glyphs[f.Fallback]
+	}
+	return g, ok
+}
+
+// lineWidth is the unscaled width of one line of text. Like Layout it
+// skips runes with no glyph and no fallback, kerning across them.
+func (f *Font) lineWidth(line string) int {
+	w := 0
+	prev := rune(-1)
+	for _, r := range line {
+		g, ok := f.glyph(r)
+		if !ok {
+			continue
+		}
+		w += g.Advance + f.Kerning[[2]rune{prev, r}]
+		prev = r
+	}
+	return w
+}
+
+// Measure returns the size of text, which may span several lines
+func (f *Font) Measure(text string, scale int) (w, h int) {
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		if lw := f.lineWidth(line); lw > w {
+			w = lw
+		}
+	}
+	return w * scale, len(lines) * f.LineHeight * scale
+}
+
+// Layout works out where each glyph of text goes. The top of the text is
+// at y and each line is aligned on x.
+func (f *Font) Layout(text string, x, y, scale int, align Align) []Placed {
+	var placed []Placed
+	for _, line := range strings.Split(text, "\n") {
+		penX := x
+		switch align {
+		case Center:
+			penX -= f.lineWidth(line) * scale / 2
+		case Right:
+			penX -= f.lineWidth(line) * scale
+		}
+		prev := rune(-1)
+		for _, r := range line {
+			g, ok := f.glyph(r)
+			if !ok {
+				continue
+			}
+			penX += f.Kerning[[2]rune{prev, r}] * scale
+			if g.W > 0 && g.H > 0 {
+				placed = append(placed, Placed{g, penX + g.XOffset*scale, y + g.YOffset*scale, scale})
+			}
+			penX += g.Advance * scale
+			prev = r
+		}
+		y += f.LineHeight * scale
+	}
+	return placed
+}
+
+// Draw writes text onto a framebuffer, each sheet pixel becoming a scale by
+// scale block. Sheet pixels more than half opaque are drawn.
+func (f *Font) Draw(fb *framebuffer.Framebuffer, text string, x, y, scale int, align Align, c palette.Color) {
+	sheet := f.Sheet
+	for _, p := range f.Layout(text, x, y, scale, align) {
+		for gy := 0; gy < p.H; gy++ {
+			row := (p.Glyph.Y+gy)*sheet.Pitch + p.Glyph.X*4
+			for gx := 0; gx < p.W; gx++ {
+				if sheet.Pixels[row+gx*4+3] >= 128 {
+					fb.FillRect(p.X+gx*scale, p.Y+gy*scale, scale, scale, c)
+				}
+			}
+		}
+	}
+}
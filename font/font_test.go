@@ -0,0 +1,156 @@
+package font
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// sheet is an opaque w by h glyph sheet
+func sheet(w, h int) *sprite.Sprite {
+	s := &sprite.Sprite{Pixels: make([]byte, w*h*4), W: w, H: h, Pitch: w * 4, Premultiplied: true}
+	for i := range s.Pixels {
+		s.Pixels[i] = 255
+	}
+	return s
+}
+
+const metrics = `
+lineheight 6
+glyph 65 0 0 3 5 4     // A
+glyph 86 4 0 3 5 4     // V
+glyph 0x2e 8 4 1 1 2 0 4
+kern 65 86 -1
+`
+
+func TestLoadMetrics(t *testing.T) {
+	f, err := LoadMetrics(strings.NewReader(metrics), sheet(12, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.LineHeight != 6 || len(f.Glyphs) != 3 {
+		t.Errorf("line height %d and %d glyphs", f.LineHeight, len(f.Glyphs))
+	}
+	if g := f.Glyphs['.']; g.YOffset != 4 || g.Advance != 2 {
+		t.Errorf("full stop is %+v", g)
+	}
+	if k := f.Kerning[[2]rune{'A', 'V'}]; k != -1 {
+		t.Errorf("AV kerns by %d", k)
+	}
+
+	bad := []string{
+		"glyph 65 10 0 3 5 4",
+		"glyph 65 0 0 3",
+		"lineheight six",
+		"size 6",
+		// nothing to give a line height
+		"",
+		"// a comment\nkern 65 86 -1",
+		"lineheight 0",
+		"lineheight -6\nglyph 65 0 0 3 5 4",
+		"glyph 32 0 0 0 0 4",
+	}
+	for _, m := range bad {
+		if _, err := LoadMetrics(strings.NewReader(m), sheet(12, 5)); err == nil {
+			t.Errorf("%q loaded", m)
+		}
+	}
+}
+
+func TestMeasureAndLayout(t *testing.T) {
+	f, err := LoadMetrics(strings.NewReader(metrics), sheet(12, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A then V kerned in, then A
+	if w, h := f.Measure("AVA", 2); w != 22 || h != 12 {
+		t.Errorf("AVA measures %d by %d, want 22 by 12", w, h)
+	}
+	if w, h := f.Measure("A\nAVA.", 1); w != 13 || h != 12 {
+		t.Errorf("two lines measure %d by %d, want 13 by 12", w, h)
+	}
+
+	placed := f.Layout("AV", 10, 20, 1, Left)
+	if len(placed) != 2 || placed[0].X != 10 || placed[1].X != 13 || placed[1].Y != 20 {
+		t.Errorf("left aligned AV is %+v", placed)
+	}
+	if p := f.Layout("AV", 10, 20, 1, Center); p[0].X != 7 {
+		t.Errorf("centred AV starts at %d, want 7", p[0].X)
+	}
+	if p := f.Layout("AV", 10, 20, 1, Right); p[0].X != 3 {
+		t.Errorf("right aligned AV starts at %d, want 3", p[0].X)
+	}
+	if p := f.Layout("A\nA", 0, 0, 2, Left); p[1].Y != 12 {
+		t.Errorf("second line is at %d, want 12", p[1].Y)
+	}
+	// the full stop sits on the baseline
+	if p := f.Layout(".", 0, 0, 1, Left); p[0].Y != 4 {
+		t.Errorf("full stop is at %d, want 4", p[0].Y)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	f, err := LoadMetrics(strings.NewReader(metrics), sheet(12, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := f.Layout("Z", 0, 0, 1, Left); len(p) != 0 {
+		t.Errorf("Z with no fallback glyph placed %+v", p)
+	}
+	// a missing glyph takes no room and A still kerns with V across it,
+	// so aligned text lines up with where the glyphs are placed
+	if w, _ := f.Measure("AZV", 1); w != 7 {
+		t.Errorf("AZV measures %d wide, want 7", w)
+	}
+	if p := f.Layout("AZV", 7, 0, 1, Right); len(p) != 2 || p[0].X != 0 || p[1].X != 3 {
+		t.Errorf("right aligned AZV is %+v", p)
+	}
+	f.Fallback = 'A'
+	if p := f.Layout("Z", 0, 0, 1, Left); len(p) != 1 || p[0].Glyph != f.Glyphs['A'] {
+		t.Errorf("Z placed %+v, want the A glyph", p)
+	}
+}
+
+func TestDraw(t *testing.T) {
+	f := Default()
+	fb := framebuffer.New(20, 10)
+	red := palette.Color{R: 255}
+	f.Draw(fb, "1", 0, 0, 2, Left, red)
+	// the 1 glyph is 8 pixels, each drawn 2 by 2
+	n := 0
+	for y := 0; y < fb.H; y++ {
+		for x := 0; x < fb.W; x++ {
+			switch fb.Pixel(x, y) {
+			case red:
+				n++
+			case palette.Color{}:
+			default:
+				t.Fatalf("pixel %d, %d is %v", x, y, fb.Pixel(x, y))
+			}
+		}
+	}
+	if n != 32 {
+		t.Errorf("drew %d pixels, want 32", n)
+	}
+	if fb.Pixel(0, 0) != red || fb.Pixel(5, 0) == red {
+		t.Error("1 is drawn in the wrong place")
+	}
+}
+
+func TestDefaultCoversASCII(t *testing.T) {
+	f := Default()
+	for r := rune(' '); r <= '~'; r++ {
+		if _, ok := f.Glyphs[r]; !ok {
+			t.Errorf("no glyph for %q", r)
+		}
+	}
+	if f.Glyphs['a'] == f.Glyphs['A'] {
+		t.Error("lower case shares the upper case glyph's place on the sheet")
+	}
+	if w, _ := f.Measure("score 10", 1); w != 32 {
+		t.Errorf("score 10 is %d wide, want 32", w)
+	}
+}
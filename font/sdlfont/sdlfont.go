@@ -0,0 +1,59 @@
+// Draws bitmap fonts with an sdl renderer
+package sdlfont
+
+import (
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Font is a font whose glyph sheet has been uploaded as a texture
+type Font struct {
+	*font.Font
+	tex *sdl.Texture
+}
+
+// New uploads the font's glyph sheet. Glyphs are drawn white and coloured
+// with the texture's colour mod.
+func New(renderer *sdl.Renderer, f *font.Font) (*Font, error) {
+	sheet := f.Sheet
+	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STATIC, int32(sheet.W), int32(sheet.H))
+	if err != nil {
+		return nil, err
+	}
+	// only the alpha of the sheet matters, so whiten the colour
+	pixels := make([]byte, sheet.W*sheet.H*4)
+	for y := 0; y < sheet.H; y++ {
+		for x := 0; x < sheet.W; x++ {
+			p := y*sheet.W*4 + x*4
+			a := sheet.Pixels[y*sheet.Pitch+x*4+3]
+			pixels[p], pixels[p+1], pixels[p+2], pixels[p+3] = 255, 255, 255, a
+		}
+	}
+	err = tex.Update(nil, pixels, sheet.W*4)
+	if err != nil {
+		tex.Destroy()
+		return nil, err
+	}
+	err = tex.SetBlendMode(sdl.BLENDMODE_BLEND)
+	if err != nil {
+		tex.Destroy()
+		return nil, err
+	}
+	return &Font{f, tex}, nil
+}
+
+// Draw copies each glyph of text to the renderer, see font.Font.Layout
+func (f *Font) Draw(renderer *sdl.Renderer, text string, x, y, scale int, align font.Align, c palette.Color) {
+	f.tex.SetColorMod(c.R, c.G, c.B)
+	for _, p := range f.Layout(text, x, y, scale, align) {
+		src := &sdl.Rect{X: int32(p.Glyph.X), Y: int32(p.Glyph.Y), W: int32(p.W), H: int32(p.H)}
+		dst := &sdl.Rect{X: int32(p.X), Y: int32(p.Y), W: int32(p.W * p.Scale), H: int32(p.H * p.Scale)}
+		renderer.Copy(f.tex, src, dst)
+	}
+}
+
+// Destroy frees the glyph texture
+func (f *Font) Destroy() {
+	f.tex.Destroy()
+}
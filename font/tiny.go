@@ -0,0 +1,117 @@
+package font
+
+import (
+	"sync"
+
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// The built in font is 3x5 pixels per glyph, lower case letters share the
+// upper case glyphs
+var tinyGlyphs = map[rune][5]string{
+	' ':  {"...", "...", "...", "...", "..."},
+	'!':  {".#.", ".#.", ".#.", "...", ".#."},
+	'"':  {"#.#", "#.#", "...", "...", "..."},
+	'#':  {"#.#", "###", "#.#", "###", "#.#"},
+	'$':  {".##", "##.", ".#.", ".##", "##."},
+	'%':  {"#.#", "..#", ".#.", "#..", "#.#"},
+	'&':  {".#.", "#.#", ".#.", "#.#", ".##"},
+	'\'': {".#.", ".#.", "...", "...", "..."},
+	'(':  {"..#", ".#.", ".#.", ".#.", "..#"},
+	')':  {"#..", ".#.", ".#.", ".#.", "#.."},
+	'*':  {"...", "#.#", ".#.", "#.#", "..."},
+	'+':  {"...", ".#.", "###", ".#.", "..."},
+	',':  {"...", "...", "...", ".#.", "#.."},
+	'-':  {"...", "...", "###", "...", "..."},
+	'.':  {"...", "...", "...", "...", ".#."},
+	'/':  {"..#", "..#", ".#.", "#..", "#.."},
+	'0':  {"###", "#.#", "#.#", "#.#", "###"},
+	'1':  {"##.", ".#.", ".#.", ".#.", "###"},
+	'2':  {"###", "..#", "###", "#..", "###"},
+	'3':  {"###", "..#", ".##", "..#", "###"},
+	'4':  {"#.#", "#.#", "###", "..#", "..#"},
+	'5':  {"###", "#..", "###", "..#", "###"},
+	'6':  {"###", "#..", "###", "#.#", "###"},
+	'7':  {"###", "..#", "..#", ".#.", ".#."},
+	'8':  {"###", "#.#", "###", "#.#", "###"},
+	'9':  {"###", "#.#", "###", "..#", "###"},
+	':':  {"...", ".#.", "...", ".#.", "..."},
+	';':  {"...", ".#.", "...", ".#.", "#.."},
+	'<':  {"..#", ".#.", "#..", ".#.", "..#"},
+	'=':  {"...", "###", "...", "###", "..."},
+	'>':  {"#..", ".#.", "..#", ".#.", "#.."},
+	'?':  {"###", "..#", ".##", "...", ".#."},
+	'@':  {"###", "#.#", "#.#", "#..", "###"},
+	'A':  {".#.", "#.#", "###", "#.#", "#.#"},
+	'B':  {"##.", "#.#", "##.", "#.#", "##."},
+	'C':  {".##", "#..", "#..", "#..", ".##"},
+	'D':  {"##.", "#.#", "#.#", "#.#", "##."},
+	'E':  {"###", "#..", "##.", "#..", "###"},
+	'F':  {"###", "#..", "##.", "#..", "#.."},
+	'G':  {".##", "#..", "#.#", "#.#", ".##"},
+	'H':  {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I':  {"###", ".#.", ".#.", ".#.", "###"},
+	'J':  {"..#", "..#", "..#", "#.#", ".#."},
+	'K':  {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L':  {"#..", "#..", "#..", "#..", "###"},
+	'M':  {"#.#", "###", "###", "#.#", "#.#"},
+	'N':  {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O':  {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P':  {"##.", "#.#", "##.", "#..", "#.."},
+	'Q':  {".#.", "#.#", "#.#", "##.", ".##"},
+	'R':  {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S':  {".##", "#..", ".#.", "..#", "##."},
+	'T':  {"###", ".#.", ".#.", ".#.", ".#."},
+	'U':  {"#.#", "#.#", "#.#", "#.#", ".##"},
+	'V':  {"#.#", "#.#", "#.#", ".#.", ".#."},
+	'W':  {"#.#", "#.#", "###", "###", "#.#"},
+	'X':  {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y':  {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z':  {"###", "..#", ".#.", "#..", "###"},
+	'[':  {"##.", "#..", "#..", "#..", "##."},
+	'\\': {"#..", "#..", ".#.", "..#", "..#"},
+	']':  {".##", "..#", "..#", "..#", ".##"},
+	'^':  {".#.", "#.#", "...", "...", "..."},
+	'_':  {"...", "...", "...", "...", "###"},
+	'`':  {"#..", ".#.", "...", "...", "..."},
+	'{':  {".##", ".#.", "##.", ".#.", ".##"},
+	'|':  {".#.", ".#.", ".#.", ".#.", ".#."},
+	'}':  {"##.", ".#.", ".##", ".#.", "##."},
+	'~':  {"...", "..#", "###", "#..", "..."},
+}
+
+var tiny struct {
+	once sync.Once
+	font *Font
+}
+
+// Default returns the built in font covering printable ascii
+func Default() *Font {
+	tiny.once.Do(func() {
+		// lay the glyphs out in a single row, one column apart
+		const w, h, gap = 3, 5, 1
+		first, last := rune(' '), rune('~')
+		n := int(last-first) + 1
+		sheet := &sprite.Sprite{Pixels: make([]byte, n*(w+gap)*h*4), W: n * (w + gap), H: h, Pitch: n * (w + gap) * 4, Premultiplied: true}
+		f := &Font{Sheet: sheet, Glyphs: make(map[rune]Glyph), Kerning: make(map[[2]rune]int), LineHeight: h + 1, Fallback: '?'}
+
+		for r := first; r <= last; r++ {
+			rows, ok := tinyGlyphs[r]
+			if !ok {
+				rows = tinyGlyphs[r-'a'+'A']
+			}
+			x := int(r-first) * (w + gap)
+			for y, row := range rows {
+				for i := 0; i < w; i++ {
+					if row[i] == '#' {
+						p := y*sheet.Pitch + (x+i)*4
+						sheet.Pixels[p], sheet.Pixels[p+1], sheet.Pixels[p+2], sheet.Pixels[p+3] = 255, 255, 255, 255
+					}
+				}
+			}
+			f.Glyphs[r] = Glyph{X: x, Y: 0, W: w, H: h, Advance: w + gap}
+		}
+		tiny.font = f
+	})
+	return tiny.font
+}
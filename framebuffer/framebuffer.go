@@ -0,0 +1,423 @@
+// Software rgba pixel buffer with simple drawing primitives
+package framebuffer
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"math/bits"
+
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// Framebuffer is a w by h block of rgba pixels, Stride bytes per row
+type Framebuffer struct {
+	Pixels       []byte
+	W, H, Stride int
+}
+
+// New makes a cleared w by h framebuffer
+func New(w, h int) *Framebuffer {
+	fb := &Framebuffer{make([]byte, w*h*4), w, h, w * 4}
+	fb.Clear()
+	return fb
+}
+
+// FromImage copies any image into a new framebuffer
+func FromImage(img image.Image) *Framebuffer {
+	b := img.Bounds()
+	fb := New(b.Dx(), b.Dy())
+	draw.Draw(fb.Image(), fb.Image().Bounds(), img, b.Min, draw.Src)
+	return fb
+}
+
+// Image wraps the framebuffer's pixels, without copying, as an image.RGBA
+func (fb *Framebuffer) Image() *image.RGBA {
+	return &image.RGBA{Pix: fb.Pixels, Stride: fb.Stride, Rect: image.Rect(0, 0, fb.W, fb.H)}
+}
+
+func (fb *Framebuffer) inside(x, y int) bool {
+	return x >= 0 && x < fb.W && y >= 0 && y < fb.H
+}
+
+// SetPixel sets the pixel at x, y, anything off the buffer is ignored
+func (fb *Framebuffer) SetPixel(x, y int, c palette.Color) {
+	if !fb.inside(x, y) {
+		return
+	}
+	index := y*fb.Stride + x*4
+	fb.Pixels[index] = c.R
+	fb.Pixels[index+1] = c.G
+	fb.Pixels[index+2] = c.B
+	fb.Pixels[index+3] = 255
+}
+
+// Pixel returns the colour at x, y, or black off the buffer
+func (fb *Framebuffer) Pixel(x, y int) palette.Color {
+	if !fb.inside(x, y) {
+		return palette.Color{}
+	}
+	index := y*fb.Stride + x*4
+	return palette.Color{R: fb.Pixels[index], G: fb.Pixels[index+1], B: fb.Pixels[index+2]}
+}
+
+// Clear fills the framebuffer with black
+func (fb *Framebuffer) Clear() {
+	fb.Fill(palette.Color{})
+}
+
+// Fill sets every pixel to c
+func (fb *Framebuffer) Fill(c palette.Color) {
+	fb.FillRect(0, 0, fb.W, fb.H, c)
+}
+
+// hline draws the pixels from x0 to x1 inclusive on row y
+func (fb *Framebuffer) hline(x0, x1, y int, c palette.Color) {
+	if y < 0 || y >= fb.H {
+		return
+	}
+	if x0 > x1 {
+		x0, x1 = x1, x0
+	}
+	if x0 < 0 {
+		x0 = 0
+	}
+	if x1 >= fb.W {
+		x1 = fb.W - 1
+	}
+	row := fb.Pixels[y*fb.Stride:]
+	for x := x0; x <= x1; x++ {
+		i := x * 4
+		row[i] = c.R
+		row[i+1] = c.G
+		row[i+2] = c.B
+		row[i+3] = 255
+	}
+}
+
+// Line draws from x0, y0 to x1, y1 using Bresenham's algorithm
+// https://en.wikipedia.org/wiki/Bresenham%27s_line_algorithm
+// Only the steps on the framebuffer are visited, so a line far off it
+// costs no more than one across it.
+func (fb *Framebuffer) Line(x0, y0, x1, y1 int, c palette.Color) {
+	l := newLine(x0, y0, x1, y1)
+	// each coordinate only moves one way along the line, so the pixels on
+	// the framebuffer are a run of steps, starting from the first where
+	// both have reached it and ending when either leaves it
+	reached := func(k uint64) bool {
+		x, y := l.at(k)
+		return (l.sx > 0 && x >= 0 || l.sx < 0 && x < fb.W) && (l.sy > 0 && y >= 0 || l.sy < 0 && y < fb.H)
+	}
+	if !reached(l.n) {
+		return
+	}
+	for k := search(l.n, reached); ; k++ {
+		x, y := l.at(k)
+		if !fb.inside(x, y) {
+			return
+		}
+		fb.SetPixel(x, y, c)
+		if k == l.n {
+			return
+		}
+	}
+}
+
+// line is the pixels Bresenham's algorithm draws, as n steps along the
+// axis it goes furthest in. Distances are unsigned, so they hold the
+// distance between any two ints.
+type line struct {
+	x0, y0, sx, sy int
+	dx, dy, n      uint64
+}
+
+func newLine(x0, y0, x1, y1 int) line {
+	l := line{x0: x0, y0: y0, sx: 1, sy: 1, dx: uint64(x1) - uint64(x0), dy: uint64(y1) - uint64(y0)}
+	if x1 < x0 {
+		l.sx, l.dx = -1, uint64(x0)-uint64(x1)
+	}
+	if y1 < y0 {
+		l.sy, l.dy = -1, uint64(y0)-uint64(y1)
+	}
+	l.n = l.dx
+	if l.dy > l.n {
+		l.n = l.dy
+	}
+	return l
+}
+
+// at is the pixel after k steps. Each step moves the long way by one and
+// the short way by d/n, rounded half up, the same pixels stepping one at
+// a time picks.
+func (l line) at(k uint64) (x, y int) {
+	// wrapping arithmetic is right, the answer lies between the ends
+	return l.x0 + l.sx*int(l.moved(l.dx, k)), l.y0 + l.sy*int(l.moved(l.dy, k))
+}
+
+// moved is how far an axis going d in the whole line has gone after k
+// steps, k*d/n rounded half up. k and d are at most n, so k*d/n fits.
+func (l line) moved(d, k uint64) uint64 {
+	if d == l.n {
+		return k
+	}
+	hi, lo := bits.Mul64(d, k)
+	q, r := bits.Div64(hi, lo, l.n)
+	if r >= l.n-r {
+		q++
+	}
+	return q
+}
+
+// search is the first k up to n for which f is true, f being false then
+// true as k goes up, and true for n
+func search(n uint64, f func(k uint64) bool) uint64 {
+	lo, hi := uint64(0), n
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if f(mid) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo
+}
+
+// Rect draws the outline of a w by h rectangle with its top left at x, y
+func (fb *Framebuffer) Rect(x, y, w, h int, c palette.Color) {
+	x0, x1, left, right := span(x, w, fb.W)
+	y0, y1, top, bottom := span(y, h, fb.H)
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+	if top {
+		fb.hline(x0, x1-1, y0, c)
+	}
+	if bottom {
+		fb.hline(x0, x1-1, y1-1, c)
+	}
+	for yy := y0; yy < y1; yy++ {
+		if left {
+			fb.SetPixel(x0, yy, c)
+		}
+		if right {
+			fb.SetPixel(x1-1, yy, c)
+		}
+	}
+}
+
+// FillRect fills a w by h rectangle with its top left at x, y
+func (fb *Framebuffer) FillRect(x, y, w, h int, c palette.Color) {
+	x0, y0, x1, y1, ok := fb.clip(x, y, w, h)
+	if !ok {
+		return
+	}
+	for yy := y0; yy < y1; yy++ {
+		fb.hline(x0, x1-1, yy, c)
+	}
+}
+
+// clip is the part of a w by h rectangle at x, y inside the framebuffer,
+// from x0, y0 up to but not including x1, y1
+func (fb *Framebuffer) clip(x, y, w, h int) (x0, y0, x1, y1 int, ok bool) {
+	x0, x1, _, _ = span(x, w, fb.W)
+	y0, y1, _, _ = span(y, h, fb.H)
+	return x0, y0, x1, y1, x0 < x1 && y0 < y1
+}
+
+// span is the part of the n pixels from x on that lies in 0 to n, from lo
+// up to but not including hi, and whether its first and last pixels are
+// in it. It is worked out without adding x and w, so huge values can't
+// overflow.
+func span(x, w, n int) (lo, hi int, first, last bool) {
+	if w <= 0 || x >= n {
+		return 0, 0, false, false
+	}
+	first = x >= 0
+	if !first {
+		// x and w have opposite signs, so the sum can't overflow
+		w += x
+		x = 0
+		if w <= 0 {
+			return 0, 0, false, false
+		}
+	}
+	if w <= n-x {
+		return x, x + w, first, true
+	}
+	return x, n, first, false
+}
+
+// FillCircle fills every pixel closer than r to cx, cy
+func (fb *Framebuffer) FillCircle(cx, cy, r int, c palette.Color) {
+	if r <= 0 {
+		return
+	}
+	// only the rows of the circle on the framebuffer
+	y0, y1, _, _ := span(cy-r, 2*r, fb.H)
+	for y := y0 - cy; y < y1-cy; y++ {
+		if x := halfWidth(r, y); x >= 0 {
+			fb.hline(cx-x, cx+x, cy+y, c)
+		}
+	}
+}
+
+// halfWidth is the widest x with x*x + y*y < r*r, or -1 if there is none
+func halfWidth(r, y int) int {
+	d := float64(r)*float64(r) - float64(y)*float64(y)
+	if d <= 0 {
+		return -1
+	}
+	x := int(math.Ceil(math.Sqrt(d))) - 1
+	// the square root can be a pixel out, put it right while r*r fits
+	if r < 1<<26 {
+		for (x+1)*(x+1)+y*y < r*r {
+			x++
+		}
+		for x >= 0 && x*x+y*y >= r*r {
+			x--
+		}
+	}
+	return x
+}
+
+// Triangle draws the outline of a triangle
+func (fb *Framebuffer) Triangle(x0, y0, x1, y1, x2, y2 int, c palette.Color) {
+	fb.Line(x0, y0, x1, y1, c)
+	fb.Line(x1, y1, x2, y2, c)
+	fb.Line(x2, y2, x0, y0, c)
+}
+
+// FillTriangle fills a triangle a scanline at a time
+func (fb *Framebuffer) FillTriangle(x0, y0, x1, y1, x2, y2 int, c palette.Color) {
+	// sort the corners top to bottom
+	if y1 < y0 {
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	if y2 < y0 {
+		x0, y0, x2, y2 = x2, y2, x0, y0
+	}
+	if y2 < y1 {
+		x1, y1, x2, y2 = x2, y2, x1, y1
+	}
+	if y0 == y2 {
+		fb.hline(min3(x0, x1, x2), max3(x0, x1, x2), y0, c)
+		return
+	}
+
+	// the long edge runs from corner 0 to corner 2, the short edges
+	// change over at corner 1
+	// only the rows on the framebuffer
+	top, bottom := y0, y2
+	if top < 0 {
+		top = 0
+	}
+	if bottom >= fb.H {
+		bottom = fb.H - 1
+	}
+	for y := top; y <= bottom; y++ {
+		xa := edgeX(x0, y0, x2, y2, y)
+		xb := edgeX(x1, y1, x2, y2, y)
+		if y < y1 || y1 == y2 {
+			xb = edgeX(x0, y0, x1, y1, y)
+		}
+		fb.hline(xa, xb, y, c)
+	}
+}
+
+// edgeX is where the edge from x0, y0 to x1, y1 crosses row y
+func edgeX(x0, y0, x1, y1, y int) int {
+	if y1 == y0 {
+		return x0
+	}
+	num := (x1 - x0) * (y - y0)
+	den := y1 - y0
+	if abs(x1-x0) >= 1<<31 || abs(y-y0) >= 1<<31 {
+		// the product could overflow
+		return x0 + int(math.Round(float64(x1-x0)*float64(y-y0)/float64(den)))
+	}
+	// round to nearest rather than towards zero
+	if (num < 0) != (den < 0) {
+		return x0 + (num-den/2)/den
+	}
+	return x0 + (num+den/2)/den
+}
+
+// Blit copies all of src to the framebuffer with its top left at x, y
+func (fb *Framebuffer) Blit(src *Framebuffer, x, y int) {
+	fb.BlitRect(src, 0, 0, src.W, src.H, x, y)
+}
+
+// BlitRect copies the w by h block of src at sx, sy so its top left is at
+// dx, dy, clipping against both buffers
+func (fb *Framebuffer) BlitRect(src *Framebuffer, sx, sy, w, h, dx, dy int) {
+	// clip against the source
+	if sx < 0 {
+		w += sx
+		dx -= sx
+		sx = 0
+	}
+	if sy < 0 {
+		h += sy
+		dy -= sy
+		sy = 0
+	}
+	if sx+w > src.W {
+		w = src.W - sx
+	}
+	if sy+h > src.H {
+		h = src.H - sy
+	}
+	// clip against the destination
+	if dx < 0 {
+		w += dx
+		sx -= dx
+		dx = 0
+	}
+	if dy < 0 {
+		h += dy
+		sy -= dy
+		dy = 0
+	}
+	if dx+w > fb.W {
+		w = fb.W - dx
+	}
+	if dy+h > fb.H {
+		h = fb.H - dy
+	}
+	if w <= 0 || h <= 0 {
+		return
+	}
+	for y := 0; y < h; y++ {
+		srcIndex := (sy+y)*src.Stride + sx*4
+		dstIndex := (dy+y)*fb.Stride + dx*4
+		copy(fb.Pixels[dstIndex:dstIndex+w*4], src.Pixels[srcIndex:srcIndex+w*4])
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func max3(a, b, c int) int {
+	if b > a {
+		a = b
+	}
+	if c > a {
+		a = c
+	}
+	return a
+}
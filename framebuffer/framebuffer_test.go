@@ -0,0 +1,326 @@
+package framebuffer
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+var white = palette.Color{R: 255, G: 255, B: 255}
+
+// count is how many pixels are c
+func count(fb *Framebuffer, c palette.Color) int {
+	n := 0
+	for y := 0; y < fb.H; y++ {
+		for x := 0; x < fb.W; x++ {
+			if fb.Pixel(x, y) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestSetPixel(t *testing.T) {
+	fb := New(4, 3)
+	// the old per game setPixel skipped the last pixel
+	fb.SetPixel(3, 2, white)
+	if fb.Pixel(3, 2) != white {
+		t.Error("last pixel wasn't set")
+	}
+	if fb.Pixels[len(fb.Pixels)-1] != 255 {
+		t.Error("last pixel isn't opaque")
+	}
+	for _, p := range [][2]int{{-1, 0}, {0, -1}, {4, 0}, {0, 3}} {
+		fb.SetPixel(p[0], p[1], white)
+	}
+	if n := count(fb, white); n != 1 {
+		t.Errorf("%d pixels set, want 1", n)
+	}
+	if fb.Pixel(-1, -1) != (palette.Color{}) {
+		t.Error("off the buffer isn't black")
+	}
+}
+
+func TestFillAndClear(t *testing.T) {
+	fb := New(5, 5)
+	fb.Fill(white)
+	if n := count(fb, white); n != 25 {
+		t.Errorf("fill set %d pixels", n)
+	}
+	fb.Clear()
+	if n := count(fb, palette.Color{}); n != 25 {
+		t.Errorf("clear left %d pixels", 25-n)
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		x0, y0, x1, y1 int
+		want           int
+	}{
+		{0, 0, 9, 0, 10},
+		{0, 0, 0, 9, 10},
+		{0, 0, 9, 9, 10},
+		{9, 9, 0, 0, 10},
+		{0, 0, 9, 4, 10},
+		{2, 7, 8, 1, 7},
+	}
+	for _, tt := range tests {
+		fb := New(10, 10)
+		fb.Line(tt.x0, tt.y0, tt.x1, tt.y1, white)
+		if n := count(fb, white); n != tt.want {
+			t.Errorf("line %v drew %d pixels, want %d", tt, n, tt.want)
+		}
+		if fb.Pixel(tt.x0, tt.y0) != white || fb.Pixel(tt.x1, tt.y1) != white {
+			t.Errorf("line %v missed an end", tt)
+		}
+	}
+}
+
+// bresenham is Line one step at a time over every pixel, the way it was
+// before it skipped the steps off the framebuffer
+func bresenham(fb *Framebuffer, x0, y0, x1, y1 int, c palette.Color) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		fb.SetPixel(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// TestLineClipped draws lines hanging off the framebuffer every way, which
+// should draw the same pixels as stepping along all of them
+func TestLineClipped(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	coord := func(n int) int { return rng.Intn(4*n) - 2*n }
+	for i := 0; i < 20000; i++ {
+		x0, y0, x1, y1 := coord(12), coord(7), coord(12), coord(7)
+		got, want := New(12, 7), New(12, 7)
+		got.Line(x0, y0, x1, y1, white)
+		bresenham(want, x0, y0, x1, y1, white)
+		if string(got.Pixels) != string(want.Pixels) {
+			t.Fatalf("line from %d,%d to %d,%d drew the wrong pixels", x0, y0, x1, y1)
+		}
+	}
+}
+
+func TestLineHuge(t *testing.T) {
+	const huge = 1 << 40
+	tests := []struct {
+		x0, y0, x1, y1 int
+		// on is a pixel on the line, the line crosses the whole
+		// framebuffer in want pixels
+		on   [2]int
+		want int
+	}{
+		{-huge, -huge, huge, huge, [2]int{3, 3}, 10},
+		{huge, huge, -huge, -huge, [2]int{3, 3}, 10},
+		{math.MinInt64, math.MinInt64, math.MaxInt64, math.MaxInt64, [2]int{0, 0}, 10},
+		{huge + 9, -huge, -huge + 9, huge, [2]int{9, 0}, 10},
+		{-huge, 5, huge, 5, [2]int{0, 5}, 10},
+		{math.MinInt64, 5, math.MaxInt64, 5, [2]int{9, 5}, 10},
+		{3, huge, 3, -huge, [2]int{3, 0}, 10},
+		// a shallow line crossing in its middle row
+		{-huge, 4, huge, 6, [2]int{0, 5}, 10},
+		// far off every side
+		{-huge, -huge, -huge + 5, huge, [2]int{-1, -1}, 0},
+		{huge, 0, 2 * huge, 9, [2]int{-1, -1}, 0},
+		{math.MinInt64, math.MaxInt64, math.MaxInt64, math.MaxInt64, [2]int{-1, -1}, 0},
+		{0, -huge, huge, 0, [2]int{-1, -1}, 0},
+	}
+	for _, tt := range tests {
+		fb := New(10, 10)
+		fb.Line(tt.x0, tt.y0, tt.x1, tt.y1, white)
+		if n := count(fb, white); n != tt.want {
+			t.Errorf("line %v drew %d pixels, want %d", tt, n, tt.want)
+		}
+		if tt.want > 0 && fb.Pixel(tt.on[0], tt.on[1]) != white {
+			t.Errorf("line %v missed %v", tt, tt.on)
+		}
+	}
+}
+
+func TestRects(t *testing.T) {
+	fb := New(10, 10)
+	fb.Rect(1, 1, 4, 3, white)
+	if n := count(fb, white); n != 10 {
+		t.Errorf("outline drew %d pixels, want 10", n)
+	}
+	if fb.Pixel(2, 2) == white {
+		t.Error("outline filled the middle")
+	}
+	fb.Clear()
+	fb.FillRect(-2, 8, 5, 5, white)
+	if n := count(fb, white); n != 6 {
+		t.Errorf("clipped fill drew %d pixels, want 6", n)
+	}
+
+	// only the rows on screen are visited, so these return straight away
+	fb.Clear()
+	fb.FillRect(2, 3, 2, math.MaxInt32, white)
+	if n := count(fb, white); n != 14 {
+		t.Errorf("fill of huge height drew %d pixels, want 14", n)
+	}
+	fb.Clear()
+	fb.FillRect(-100, -100, 50, 50, white)
+	if n := count(fb, white); n != 0 {
+		t.Errorf("fill ending above and left of the screen drew %d pixels", n)
+	}
+	fb.FillRect(-5, -5, math.MaxInt32, math.MaxInt32, white)
+	if n := count(fb, white); n != 100 {
+		t.Errorf("fill over the whole screen drew %d pixels, want 100", n)
+	}
+	fb.Clear()
+	fb.FillRect(10, 0, 5, 5, white)
+	if n := count(fb, white); n != 0 {
+		t.Errorf("fill right of the screen drew %d pixels", n)
+	}
+
+	outlines := []struct {
+		x, y, w, h int
+		want       int
+	}{
+		// top, bottom and the right side, the left is off screen
+		{-3, 2, 6, 4, 8},
+		// only the two sides cross the screen
+		{2, -5, 3, math.MaxInt64, 20},
+		// every side is off screen
+		{-5, -5, math.MaxInt64, math.MaxInt64, 0},
+		{math.MinInt64, 0, math.MaxInt64, 5, 0},
+		{1 << 40, 1 << 40, 5, 5, 0},
+	}
+	for _, tt := range outlines {
+		fb.Clear()
+		fb.Rect(tt.x, tt.y, tt.w, tt.h, white)
+		if n := count(fb, white); n != tt.want {
+			t.Errorf("outline %v drew %d pixels, want %d", tt, n, tt.want)
+		}
+	}
+}
+
+func TestFillCircle(t *testing.T) {
+	fb := New(40, 40)
+	fb.FillCircle(20, 20, 10, white)
+	// the area of the circle, give or take the edge
+	if n := count(fb, white); n < 290 || n > 340 {
+		t.Errorf("circle drew %d pixels, want about 314", n)
+	}
+	for _, p := range [][2]int{{20, 11}, {11, 20}, {29, 20}, {20, 29}} {
+		if fb.Pixel(p[0], p[1]) != white {
+			t.Errorf("circle is missing %v", p)
+		}
+	}
+	for _, p := range [][2]int{{10, 10}, {30, 20}, {20, 30}} {
+		if fb.Pixel(p[0], p[1]) == white {
+			t.Errorf("circle reaches %v", p)
+		}
+	}
+
+	// these return straight away, only the rows on screen are visited
+	fb.Clear()
+	fb.FillCircle(20, 20, 1<<40, white)
+	if n := count(fb, white); n != 40*40 {
+		t.Errorf("circle round the screen drew %d pixels, want %d", n, 40*40)
+	}
+	fb.Clear()
+	fb.FillCircle(1<<40, 20, 10, white)
+	fb.FillCircle(20, -1<<40, 1<<20, white)
+	fb.FillCircle(-1<<40, -1<<40, 1<<40, white)
+	if n := count(fb, white); n != 0 {
+		t.Errorf("circles off the screen drew %d pixels", n)
+	}
+}
+
+func TestFillTriangle(t *testing.T) {
+	fb := New(20, 20)
+	// corners in every order draw the same triangle
+	corners := [][6]int{
+		{0, 0, 10, 0, 0, 10},
+		{10, 0, 0, 10, 0, 0},
+		{0, 10, 0, 0, 10, 0},
+	}
+	var first []byte
+	for _, c := range corners {
+		fb.Clear()
+		fb.FillTriangle(c[0], c[1], c[2], c[3], c[4], c[5], white)
+		if first == nil {
+			first = append([]byte(nil), fb.Pixels...)
+			// a right triangle of side 11 pixels, rows of 11 down to 1
+			if n := count(fb, white); n != 66 {
+				t.Errorf("triangle drew %d pixels, want 66", n)
+			}
+			continue
+		}
+		if string(fb.Pixels) != string(first) {
+			t.Errorf("corners %v drew a different triangle", c)
+		}
+	}
+	fb.Clear()
+	fb.FillTriangle(0, 5, 5, 5, 9, 5, white)
+	if n := count(fb, white); n != 10 {
+		t.Errorf("flat triangle drew %d pixels, want 10", n)
+	}
+
+	fb.Clear()
+	fb.FillTriangle(-1<<40, -1<<40, 1<<40, -1<<40, 10, 1<<40, white)
+	if n := count(fb, white); n != 20*20 {
+		t.Errorf("triangle round the screen drew %d pixels, want %d", n, 20*20)
+	}
+	fb.Clear()
+	fb.FillTriangle(0, 1<<40, 5, 1<<40+5, 0, 1<<40+10, white)
+	fb.FillTriangle(0, -1<<40, -1<<40, 0, -1<<40, -1<<40, white)
+	if n := count(fb, white); n != 0 {
+		t.Errorf("triangles off the screen drew %d pixels", n)
+	}
+}
+
+func TestBlitClips(t *testing.T) {
+	src := New(4, 4)
+	src.Fill(white)
+	src.SetPixel(0, 0, palette.Color{R: 255})
+	tests := []struct {
+		x, y int
+		want int
+	}{
+		{0, 0, 16},
+		{-2, -2, 4},
+		{8, 8, 4},
+		{-4, 0, 0},
+		{10, 10, 0},
+	}
+	for _, tt := range tests {
+		fb := New(10, 10)
+		fb.Blit(src, tt.x, tt.y)
+		n := count(fb, white) + count(fb, palette.Color{R: 255})
+		if n != tt.want {
+			t.Errorf("blit at %d, %d copied %d pixels, want %d", tt.x, tt.y, n, tt.want)
+		}
+	}
+	fb := New(10, 10)
+	fb.BlitRect(src, -1, -1, 3, 3, 5, 5)
+	if fb.Pixel(6, 6) != (palette.Color{R: 255}) || fb.Pixel(5, 5) != (palette.Color{}) {
+		t.Error("source clipping moved the block")
+	}
+}
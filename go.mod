@@ -0,0 +1,5 @@
+module github.com/stephen-mahon/games-with-go
+
+go 1.16
+
+require github.com/veandco/go-sdl2 v0.4.8
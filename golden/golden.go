@@ -0,0 +1,89 @@
+// Compares rendered frames with checked in png images
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/stephen-mahon/games-with-go/capture"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+)
+
+// DefaultTolerance allows for float rounding differing between machines
+const DefaultTolerance = 8
+
+// Compare counts the pixels where any channel of got and want differ by
+// more than tolerance. The diff image shows want faded to grey with those
+// pixels in red. Images of different sizes differ everywhere.
+func Compare(got, want image.Image, tolerance uint8) (bad int, diff *image.RGBA) {
+	gb, wb := got.Bounds(), want.Bounds()
+	diff = image.NewRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy()))
+	if gb.Dx() != wb.Dx() || gb.Dy() != wb.Dy() {
+		for i := range diff.Pix {
+			diff.Pix[i] = 255
+			if i%4 == 1 || i%4 == 2 {
+				diff.Pix[i] = 0
+			}
+		}
+		return wb.Dx() * wb.Dy(), diff
+	}
+	t := int(tolerance)
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			g := color.NRGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.NRGBA)
+			w := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			if abs(int(g.R)-int(w.R)) > t || abs(int(g.G)-int(w.G)) > t ||
+				abs(int(g.B)-int(w.B)) > t || abs(int(g.A)-int(w.A)) > t {
+				bad++
+				diff.Set(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			grey := byte((int(w.R)+int(w.G)+int(w.B))/6 + 128)
+			diff.Set(x, y, color.RGBA{grey, grey, grey, 255})
+		}
+	}
+	return bad, diff
+}
+
+// Check compares a frame with the png in filename. On a mismatch it writes
+// the diff next to it, name_diff.png for name.png, and returns an error.
+// With update set it writes the frame as the new golden image instead.
+func Check(filename string, got *framebuffer.Framebuffer, tolerance uint8, update bool) error {
+	if update {
+		return capture.SavePNG(filename, got)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	want, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	bad, diff := Compare(got.Image(), want, tolerance)
+	if bad == 0 {
+		return nil
+	}
+	diffName := strings.TrimSuffix(filename, ".png") + "_diff.png"
+	out, err := os.Create(diffName)
+	if err == nil {
+		err = png.Encode(out, diff)
+		out.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %d pixels differ, writing the diff failed: %v", filename, bad, err)
+	}
+	return fmt.Errorf("%s: %d pixels differ, see %s", filename, bad, diffName)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
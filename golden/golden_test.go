@@ -0,0 +1,46 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+func TestCompare(t *testing.T) {
+	want := framebuffer.New(4, 4)
+	got := framebuffer.New(4, 4)
+	got.SetPixel(0, 0, palette.Color{R: DefaultTolerance})
+	got.SetPixel(1, 0, palette.Color{G: DefaultTolerance + 1})
+	bad, diff := Compare(got.Image(), want.Image(), DefaultTolerance)
+	if bad != 1 {
+		t.Errorf("%d pixels differ, want 1", bad)
+	}
+	if r, g, _, _ := diff.At(1, 0).RGBA(); r != 0xffff || g != 0 {
+		t.Error("the differing pixel isn't red in the diff")
+	}
+	if bad, _ := Compare(framebuffer.New(2, 2).Image(), want.Image(), 0); bad != 16 {
+		t.Errorf("a smaller image differs at %d pixels, want 16", bad)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "frame.png")
+	fb := framebuffer.New(4, 4)
+	fb.FillRect(0, 0, 2, 2, palette.Color{R: 255})
+	if err := Check(filename, fb, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(filename, fb, 0, false); err != nil {
+		t.Errorf("the image it just wrote doesn't match: %v", err)
+	}
+	fb.SetPixel(3, 3, palette.Color{B: 255})
+	if err := Check(filename, fb, 0, false); err == nil {
+		t.Error("a changed frame matched")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filename), "frame_diff.png")); err != nil {
+		t.Errorf("no diff was written: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseBinding reads one binding in the form used by binding files
+//
+//	key:up  mouse:left  button:a  button:start@1  axis:lefty-  axis:leftx+@0
+//
+// The @n suffix picks a controller, without it any controller will do. A
+// key, button or axis with no name is written as its number, as in
+// key:300.
+func ParseBinding(s string) (Binding, error) {
+	parts := strings.SplitN(strings.ToLower(s), ":", 2)
+	if len(parts) != 2 {
+		return Binding{}, fmt.Errorf("input: bad binding %q", s)
+	}
+	kind, name := parts[0], parts[1]
+	pad := AnyPad
+	if i := strings.Index(name, "@"); i >= 0 && (kind == "button" || kind == "axis") {
+		n, err := strconv.Atoi(name[i+1:])
+		if err != nil || n < 0 {
+			return Binding{}, fmt.Errorf("input: bad controller in %q", s)
+		}
+		name, pad = name[:i], n
+	}
+	dir := 1
+	if kind == "axis" {
+		if strings.HasSuffix(name, "-") {
+			dir = -1
+		}
+		name = strings.TrimRight(name, "+-")
+	}
+	code, err := strconv.Atoi(name)
+	numbered := err == nil && code >= 0
+	switch kind {
+	case "key":
+		if k, ok := keyNames[name]; ok {
+			return BindKey(k), nil
+		}
+		if numbered {
+			return BindKey(Key(code)), nil
+		}
+	case "mouse":
+		if b, ok := mouseNames[name]; ok {
+			return BindMouse(b), nil
+		}
+		if numbered {
+			return BindMouse(MouseButton(code)), nil
+		}
+	case "button":
+		if b, ok := buttonNames[name]; ok {
+			return BindButton(b, pad), nil
+		}
+		if numbered {
+			return BindButton(Button(code), pad), nil
+		}
+	case "axis":
+		if a, ok := axisNames[name]; ok {
+			return BindAxis(a, dir, pad), nil
+		}
+		if numbered {
+			return BindAxis(Axis(code), dir, pad), nil
+		}
+	}
+	return Binding{}, fmt.Errorf("input: unknown binding %q", s)
+}
+
+// String writes the binding in the form ParseBinding reads. A binding of
+// no kind ParseBinding knows, or with a negative code, comes out as
+// unknown:kind:code, which ParseBinding rejects.
+func (b Binding) String() string {
+	if b.Code < 0 {
+		return fmt.Sprintf("unknown:%d:%d", b.Kind, b.Code)
+	}
+	suffix := ""
+	if b.Pad != AnyPad {
+		suffix = "@" + strconv.Itoa(b.Pad)
+	}
+	name := strconv.Itoa(b.Code)
+	switch b.Kind {
+	case KeyBinding:
+		if s, ok := keyStrings[Key(b.Code)]; ok {
+			name = s
+		}
+		return "key:" + name
+	case MouseBinding:
+		if s, ok := mouseStrings[MouseButton(b.Code)]; ok {
+			name = s
+		}
+		return "mouse:" + name
+	case ButtonBinding:
+		if s, ok := buttonStrings[Button(b.Code)]; ok {
+			name = s
+		}
+		return "button:" + name + suffix
+	case AxisBinding:
+		if s, ok := axisStrings[Axis(b.Code)]; ok {
+			name = s
+		}
+		dir := "+"
+		if b.Dir < 0 {
+			dir = "-"
+		}
+		return "axis:" + name + dir + suffix
+	}
+	return fmt.Sprintf("unknown:%d:%d", b.Kind, b.Code)
+}
+
+// Load rebinds actions from a bindings file. Each line is an action and
+// what it is bound to, // starts a comment as it does in the other text
+// formats
+//
+//	paddle_up    key:up key:w axis:lefty-
+//	deadzone     0.1
+//
+// Actions named in the file lose their old bindings, others keep theirs.
+func (m *Map) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "deadzone" && len(fields) == 2 {
+			v, err := strconv.ParseFloat(fields[1], 32)
+			if err != nil || v < 0 || v >= 1 {
+				return fmt.Errorf("input: line %d: bad dead zone %q", line, fields[1])
+			}
+			m.DeadZone = float32(v)
+			continue
+		}
+		bindings := make([]Binding, 0, len(fields)-1)
+		for _, f := range fields[1:] {
+			b, err := ParseBinding(f)
+			if err != nil {
+				return fmt.Errorf("input: line %d: %v", line, err)
+			}
+			bindings = append(bindings, b)
+		}
+		m.Unbind(fields[0])
+		m.Bind(fields[0], bindings...)
+	}
+	return scanner.Err()
+}
+
+// LoadFile rebinds actions from a file if it exists. A missing file is not
+// an error so games can ship without one.
+func (m *Map) LoadFile(filename string) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.Load(f)
+}
+
+// Save writes every action's bindings in the form Load reads. It fails
+// without writing anything if a binding can't be written in that form.
+func (m *Map) Save(w io.Writer) error {
+	for _, action := range m.order {
+		for _, b := range m.bindings[action] {
+			if _, err := ParseBinding(b.String()); err != nil {
+				return fmt.Errorf("input: can't save %s: %v", action, err)
+			}
+		}
+	}
+	if _, err := fmt.Fprintf(w, "deadzone %g\n", m.DeadZone); err != nil {
+		return err
+	}
+	for _, action := range m.order {
+		strs := make([]string, len(m.bindings[action]))
+		for i, b := range m.bindings[action] {
+			strs[i] = b.String()
+		}
+		if _, err := fmt.Fprintf(w, "%s %s\n", action, strings.Join(strs, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package input
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const bindingsFile = `
+// two players on one keyboard
+deadzone   0.2
+p1_up      key:w              // and nothing else
+fire       button:a@1 axis:lefty-
+jump       key:300 axis:7+@2
+`
+
+func TestLoad(t *testing.T) {
+	m := NewMap()
+	m.Bind("p1_up", BindKey(KeyUp))
+	m.Bind("pause", BindKey(KeyP))
+	if err := m.Load(strings.NewReader(bindingsFile)); err != nil {
+		t.Fatal(err)
+	}
+	if m.DeadZone != 0.2 {
+		t.Errorf("dead zone is %v", m.DeadZone)
+	}
+	if b := m.Bindings("p1_up"); len(b) != 1 || b[0] != BindKey(KeyW) {
+		t.Errorf("p1_up is bound to %v, want only key:w", b)
+	}
+	if b := m.Bindings("fire"); len(b) != 2 || b[0] != BindButton(ButtonA, 1) || b[1] != BindAxis(AxisLeftY, -1, AnyPad) {
+		t.Errorf("fire is bound to %v", b)
+	}
+	if b := m.Bindings("jump"); len(b) != 2 || b[0] != BindKey(Key(300)) || b[1] != BindAxis(Axis(7), 1, 2) {
+		t.Errorf("jump is bound to %v", b)
+	}
+	if b := m.Bindings("pause"); len(b) != 1 {
+		t.Error("an action the file left out lost its binding")
+	}
+
+	bad := []string{
+		"p1_up key:nothing",
+		"deadzone 1",
+		"fire button:a@x",
+		"# a comment in the old style",
+		"p1_up key:-1",
+	}
+	for _, s := range bad {
+		if err := NewMap().Load(strings.NewReader(s)); err == nil {
+			t.Errorf("%q loaded", s)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	m := NewMap()
+	if err := m.Load(strings.NewReader(bindingsFile)); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewMap()
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range m.Actions() {
+		want, got := m.Bindings(action), loaded.Bindings(action)
+		if len(got) != len(want) {
+			t.Fatalf("%s came back as %v, want %v", action, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s came back as %v, want %v", action, got, want)
+			}
+		}
+	}
+}
+
+func TestSaveUnknown(t *testing.T) {
+	m := NewMap()
+	m.Bind("p1_up", BindKey(KeyW))
+	m.Bind("fire", Binding{Kind: Kind(99), Code: 1})
+	var buf bytes.Buffer
+	if err := m.Save(&buf); err == nil {
+		t.Error("a binding of an unknown kind saved")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("a failed save wrote %q", buf.String())
+	}
+}
@@ -0,0 +1,263 @@
+// Named actions bound to keys, mouse buttons and game controllers
+package input
+
+// Mouse is the pointer position and which buttons are down
+type Mouse struct {
+	X, Y    int
+	Buttons uint32
+}
+
+// Down reports whether a mouse button is held
+func (m Mouse) Down(b MouseButton) bool {
+	return m.Buttons&(1<<uint(b-1)) != 0
+}
+
+// Pad is the state of one game controller
+type Pad struct {
+	Buttons uint32
+	Axes    [NumAxes]int16
+}
+
+// Down reports whether a controller button is held
+func (p Pad) Down(b Button) bool {
+	return p.Buttons&(1<<uint(b)) != 0
+}
+
+// Snapshot is the raw state of every device for one frame
+type Snapshot struct {
+	// Keys is indexed by Key, non zero when held
+	Keys  []uint8
+	Mouse Mouse
+	Pads  []Pad
+}
+
+// NewSnapshot makes a snapshot with only the given keys held, for scripted
+// input. Keys outside 0 to NumKeys are left out.
+func NewSnapshot(keys ...Key) *Snapshot {
+	s := &Snapshot{Keys: make([]uint8, NumKeys)}
+	for _, k := range keys {
+		if k >= 0 && k < NumKeys {
+			s.Keys[k] = 1
+		}
+	}
+	return s
+}
+
+// Kind is the sort of device a binding reads
+type Kind int
+
+const (
+	KeyBinding Kind = iota
+	MouseBinding
+	ButtonBinding
+	AxisBinding
+)
+
+// AnyPad is a Binding.Pad matching every controller
+const AnyPad = -1
+
+// Binding is one physical input that triggers an action
+type Binding struct {
+	Kind Kind
+	// Code is the Key, MouseButton, Button or Axis
+	Code int
+	// Pad is the controller index for buttons and axes
+	Pad int
+	// Dir picks which half of an axis counts, +1 or -1
+	Dir int
+}
+
+// BindKey binds a keyboard key
+func BindKey(k Key) Binding {
+	return Binding{Kind: KeyBinding, Code: int(k)}
+}
+
+// BindMouse binds a mouse button
+func BindMouse(b MouseButton) Binding {
+	return Binding{Kind: MouseBinding, Code: int(b)}
+}
+
+// BindButton binds a button on controller pad, or AnyPad
+func BindButton(b Button, pad int) Binding {
+	return Binding{Kind: ButtonBinding, Code: int(b), Pad: pad}
+}
+
+// BindAxis binds one direction, +1 or -1, of an axis on controller pad
+func BindAxis(a Axis, dir int, pad int) Binding {
+	return Binding{Kind: AxisBinding, Code: int(a), Pad: pad, Dir: dir}
+}
+
+// Map turns device snapshots into action values and edges. Update reads a
+// snapshot every frame, but a fixed timestep game may run no update on a
+// frame, or several. So edges are latched: an action pressed or released
+// on any frame stays Pressed or Released until Tick says an update has
+// seen it.
+type Map struct {
+	// DeadZone is the fraction of an axis's travel that is ignored
+	DeadZone float32
+
+	bindings          map[string][]Binding
+	order             []string
+	cur, prev         map[string]float32
+	pressed, released map[string]bool
+	mouse             Mouse
+}
+
+// NewMap makes an empty map with a dead zone close to what the games used
+func NewMap() *Map {
+	return &Map{
+		DeadZone: 0.05,
+		bindings: make(map[string][]Binding),
+		cur:      make(map[string]float32),
+		prev:     make(map[string]float32),
+		pressed:  make(map[string]bool),
+		released: make(map[string]bool),
+	}
+}
+
+// Bind adds bindings to an action
+func (m *Map) Bind(action string, bindings ...Binding) {
+	if _, ok := m.bindings[action]; !ok {
+		m.order = append(m.order, action)
+	}
+	m.bindings[action] = append(m.bindings[action], bindings...)
+}
+
+// Unbind removes every binding from an action
+func (m *Map) Unbind(action string) {
+	if _, ok := m.bindings[action]; ok {
+		m.bindings[action] = nil
+	}
+}
+
+// Bindings returns what an action is bound to
+func (m *Map) Bindings(action string) []Binding {
+	return m.bindings[action]
+}
+
+// Actions lists the actions in the order they were first bound
+func (m *Map) Actions() []string {
+	return m.order
+}
+
+// Update reads the snapshot for a new frame. Call it once a frame, whether
+// or not the frame runs an update, so no press is missed.
+func (m *Map) Update(s *Snapshot) {
+	m.prev, m.cur = m.cur, m.prev
+	for action := range m.cur {
+		delete(m.cur, action)
+	}
+	for action, bindings := range m.bindings {
+		var v float32
+		for _, b := range bindings {
+			if bv := m.value(s, b); bv > v {
+				v = bv
+			}
+		}
+		if v > 0 {
+			m.cur[action] = v
+		}
+	}
+	for action := range m.bindings {
+		if m.cur[action] > 0 && m.prev[action] == 0 {
+			m.pressed[action] = true
+		}
+		if m.cur[action] == 0 && m.prev[action] > 0 {
+			m.released[action] = true
+		}
+	}
+	m.mouse = s.Mouse
+}
+
+// Tick ends an update, clearing the edges it has seen. Call it after each
+// fixed step update.
+func (m *Map) Tick() {
+	for action := range m.pressed {
+		delete(m.pressed, action)
+	}
+	for action := range m.released {
+		delete(m.released, action)
+	}
+}
+
+func (m *Map) value(s *Snapshot, b Binding) float32 {
+	switch b.Kind {
+	case KeyBinding:
+		if b.Code >= 0 && b.Code < len(s.Keys) && s.Keys[b.Code] != 0 {
+			return 1
+		}
+	case MouseBinding:
+		if s.Mouse.Down(MouseButton(b.Code)) {
+			return 1
+		}
+	case ButtonBinding, AxisBinding:
+		var v float32
+		for i, pad := range s.Pads {
+			if b.Pad != AnyPad && b.Pad != i {
+				continue
+			}
+			if pv := m.padValue(pad, b); pv > v {
+				v = pv
+			}
+		}
+		return v
+	}
+	return 0
+}
+
+func (m *Map) padValue(pad Pad, b Binding) float32 {
+	if b.Kind == ButtonBinding {
+		if pad.Down(Button(b.Code)) {
+			return 1
+		}
+		return 0
+	}
+	if b.Code < 0 || b.Code >= int(NumAxes) {
+		return 0
+	}
+	v := float32(pad.Axes[b.Code]) / 32767
+	if b.Dir < 0 {
+		v = -v
+	}
+	if v <= m.DeadZone {
+		return 0
+	}
+	// rescale so the value starts from zero at the edge of the dead zone
+	v = (v - m.DeadZone) / (1 - m.DeadZone)
+	if v > 1 {
+		v = 1
+	}
+	return v
+}
+
+// Value is how far an action is held, 1 for keys and buttons and from 0
+// to 1 for axes
+func (m *Map) Value(action string) float32 {
+	return m.cur[action]
+}
+
+// Axis combines two actions into a value from -1 to 1
+func (m *Map) Axis(negative, positive string) float32 {
+	return m.cur[positive] - m.cur[negative]
+}
+
+// Held reports whether an action is held this frame
+func (m *Map) Held(action string) bool {
+	return m.cur[action] > 0
+}
+
+// Pressed reports whether an action went down since the last Tick. A tap
+// that starts and ends between two updates is both Pressed and Released.
+func (m *Map) Pressed(action string) bool {
+	return m.pressed[action]
+}
+
+// Released reports whether an action came up since the last Tick
+func (m *Map) Released(action string) bool {
+	return m.released[action]
+}
+
+// Mouse returns the mouse state from the last snapshot
+func (m *Map) Mouse() Mouse {
+	return m.mouse
+}
@@ -0,0 +1,85 @@
+package input
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEdgesLatchUntilTick(t *testing.T) {
+	m := NewMap()
+	m.Bind("fire", BindKey(KeySpace))
+
+	// a tap over two frames with no update between them
+	m.Update(NewSnapshot(KeySpace))
+	m.Update(NewSnapshot())
+	if !m.Pressed("fire") || !m.Released("fire") {
+		t.Fatal("a tap between updates was lost")
+	}
+	if m.Held("fire") {
+		t.Error("fire is held after it came up")
+	}
+	m.Tick()
+	if m.Pressed("fire") || m.Released("fire") {
+		t.Error("Tick left the edges set")
+	}
+
+	// held over several frames it is pressed once
+	m.Update(NewSnapshot(KeySpace))
+	m.Tick()
+	m.Update(NewSnapshot(KeySpace))
+	if m.Pressed("fire") {
+		t.Error("a held key was pressed again")
+	}
+	if !m.Held("fire") {
+		t.Error("fire isn't held")
+	}
+}
+
+func TestNewSnapshot(t *testing.T) {
+	s := NewSnapshot(KeyW, Key(300), NumKeys, -1)
+	if len(s.Keys) != int(NumKeys) {
+		t.Fatalf("snapshot has %d keys, want %d", len(s.Keys), NumKeys)
+	}
+	held := 0
+	for _, v := range s.Keys {
+		if v != 0 {
+			held++
+		}
+	}
+	if held != 2 || s.Keys[KeyW] == 0 || s.Keys[300] == 0 {
+		t.Errorf("%d keys held, want key:w and key:300", held)
+	}
+}
+
+func TestDeadZone(t *testing.T) {
+	m := NewMap()
+	m.DeadZone = 0.2
+	m.Bind("up", BindAxis(AxisLeftY, -1, AnyPad))
+	m.Bind("down", BindAxis(AxisLeftY, 1, AnyPad))
+	tests := []struct {
+		axis int16
+		want float32
+	}{
+		{0, 0},
+		// inside the dead zone either way
+		{6553, 0},
+		{-6553, 0},
+		// just past its edge the value starts from zero
+		{9830, 0.125},
+		{-9830, -0.125},
+		{32767, 1},
+		{-32768, -1},
+	}
+	for _, tt := range tests {
+		s := NewSnapshot()
+		s.Pads = []Pad{{}}
+		s.Pads[0].Axes[AxisLeftY] = tt.axis
+		m.Update(s)
+		if got := m.Axis("up", "down"); math.Abs(float64(got-tt.want)) > 1e-3 {
+			t.Errorf("axis at %d reads %v, want %v", tt.axis, got, tt.want)
+		}
+		if m.Held("up") && m.Held("down") {
+			t.Errorf("axis at %d holds both ways", tt.axis)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package input
+
+// Key is a keyboard scancode. The values are the usb usage ids that sdl
+// scancodes use, so a Key indexes sdl.GetKeyboardState directly.
+type Key int
+
+const (
+	KeyA Key = iota + 4
+	KeyB
+	KeyC
+	KeyD
+	KeyE
+	KeyF
+	KeyG
+	KeyH
+	KeyI
+	KeyJ
+	KeyK
+	KeyL
+	KeyM
+	KeyN
+	KeyO
+	KeyP
+	KeyQ
+	KeyR
+	KeyS
+	KeyT
+	KeyU
+	KeyV
+	KeyW
+	KeyX
+	KeyY
+	KeyZ
+	Key1
+	Key2
+	Key3
+	Key4
+	Key5
+	Key6
+	Key7
+	Key8
+	Key9
+	Key0
+	KeyReturn
+	KeyEscape
+	KeyBackspace
+	KeyTab
+	KeySpace
+)
+
+const (
+	KeyF1 Key = iota + 58
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+)
+
+const (
+	KeyRight Key = iota + 79
+	KeyLeft
+	KeyDown
+	KeyUp
+)
+
+const (
+	KeyLCtrl Key = iota + 224
+	KeyLShift
+	KeyLAlt
+	_
+	KeyRCtrl
+	KeyRShift
+	KeyRAlt
+)
+
+// NumKeys is one more than the highest scancode, as sdl's
+// SDL_NUM_SCANCODES
+const NumKeys Key = 512
+
+// MouseButton numbers match sdl's
+type MouseButton int
+
+const (
+	MouseLeft MouseButton = iota + 1
+	MouseMiddle
+	MouseRight
+)
+
+// Button is a game controller button, numbered as sdl does
+type Button int
+
+const (
+	ButtonA Button = iota
+	ButtonB
+	ButtonX
+	ButtonY
+	ButtonBack
+	ButtonGuide
+	ButtonStart
+	ButtonLeftStick
+	ButtonRightStick
+	ButtonLeftShoulder
+	ButtonRightShoulder
+	ButtonDPadUp
+	ButtonDPadDown
+	ButtonDPadLeft
+	ButtonDPadRight
+	NumButtons
+)
+
+// Axis is a game controller axis, numbered as sdl does
+type Axis int
+
+const (
+	AxisLeftX Axis = iota
+	AxisLeftY
+	AxisRightX
+	AxisRightY
+	AxisTriggerLeft
+	AxisTriggerRight
+	NumAxes
+)
+
+var keyNames = map[string]Key{
+	"return": KeyReturn, "escape": KeyEscape, "backspace": KeyBackspace, "tab": KeyTab, "space": KeySpace,
+	"right": KeyRight, "left": KeyLeft, "down": KeyDown, "up": KeyUp,
+	"lctrl": KeyLCtrl, "lshift": KeyLShift, "lalt": KeyLAlt, "rctrl": KeyRCtrl, "rshift": KeyRShift, "ralt": KeyRAlt,
+	"f1": KeyF1, "f2": KeyF2, "f3": KeyF3, "f4": KeyF4, "f5": KeyF5, "f6": KeyF6,
+	"f7": KeyF7, "f8": KeyF8, "f9": KeyF9, "f10": KeyF10, "f11": KeyF11, "f12": KeyF12,
+}
+
+var mouseNames = map[string]MouseButton{"left": MouseLeft, "middle": MouseMiddle, "right": MouseRight}
+
+var buttonNames = map[string]Button{
+	"a": ButtonA, "b": ButtonB, "x": ButtonX, "y": ButtonY,
+	"back": ButtonBack, "guide": ButtonGuide, "start": ButtonStart,
+	"leftstick": ButtonLeftStick, "rightstick": ButtonRightStick,
+	"leftshoulder": ButtonLeftShoulder, "rightshoulder": ButtonRightShoulder,
+	"dpup": ButtonDPadUp, "dpdown": ButtonDPadDown, "dpleft": ButtonDPadLeft, "dpright": ButtonDPadRight,
+}
+
+var axisNames = map[string]Axis{
+	"leftx": AxisLeftX, "lefty": AxisLeftY, "rightx": AxisRightX, "righty": AxisRightY,
+	"lefttrigger": AxisTriggerLeft, "righttrigger": AxisTriggerRight,
+}
+
+var (
+	keyStrings    = make(map[Key]string)
+	mouseStrings  = make(map[MouseButton]string)
+	buttonStrings = make(map[Button]string)
+	axisStrings   = make(map[Axis]string)
+)
+
+func init() {
+	for k := KeyA; k <= KeyZ; k++ {
+		keyNames[string(rune('a'+k-KeyA))] = k
+	}
+	for k := Key1; k <= Key9; k++ {
+		keyNames[string(rune('1'+k-Key1))] = k
+	}
+	keyNames["0"] = Key0
+
+	for name, k := range keyNames {
+		keyStrings[k] = name
+	}
+	for name, b := range mouseNames {
+		mouseStrings[b] = name
+	}
+	for name, b := range buttonNames {
+		buttonStrings[b] = name
+	}
+	for name, a := range axisNames {
+		axisStrings[a] = name
+	}
+}
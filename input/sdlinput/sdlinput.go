@@ -0,0 +1,54 @@
+// Reads input snapshots from sdl
+package sdlinput
+
+import (
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// OpenControllers opens every attached game controller. Pads in a snapshot
+// are in the same order.
+func OpenControllers() []*sdl.GameController {
+	var controllers []*sdl.GameController
+	for i := 0; i < sdl.NumJoysticks(); i++ {
+		if sdl.IsGameController(i) {
+			if c := sdl.GameControllerOpen(i); c != nil {
+				controllers = append(controllers, c)
+			}
+		}
+	}
+	return controllers
+}
+
+// CloseControllers closes controllers from OpenControllers
+func CloseControllers(controllers []*sdl.GameController) {
+	for _, c := range controllers {
+		c.Close()
+	}
+}
+
+// Snapshot copies the current keyboard, mouse and controller state. Call it
+// after polling events.
+func Snapshot(controllers []*sdl.GameController) *input.Snapshot {
+	var s input.Snapshot
+	// the keyboard state belongs to sdl and changes under us, so copy it
+	keys := sdl.GetKeyboardState()
+	s.Keys = make([]uint8, len(keys))
+	copy(s.Keys, keys)
+
+	x, y, buttons := sdl.GetMouseState()
+	s.Mouse = input.Mouse{X: int(x), Y: int(y), Buttons: buttons}
+
+	s.Pads = make([]input.Pad, len(controllers))
+	for i, c := range controllers {
+		for b := input.Button(0); b < input.NumButtons; b++ {
+			if c.Button(sdl.GameControllerButton(b)) != 0 {
+				s.Pads[i].Buttons |= 1 << uint(b)
+			}
+		}
+		for a := input.Axis(0); a < input.NumAxes; a++ {
+			s.Pads[i].Axes[a] = c.Axis(sdl.GameControllerAxis(a))
+		}
+	}
+	return &s
+}
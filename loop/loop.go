@@ -0,0 +1,147 @@
+// Fixed timestep game loop
+// https://gafferongames.com/post/fix_your_timestep/
+package loop
+
+import (
+	"fmt"
+	"time"
+)
+
+// Loop runs updates at a fixed time step however fast frames are rendered.
+// Render gets an alpha between 0 and 1 saying how far between the last two
+// updates the frame is, so it can interpolate positions.
+type Loop struct {
+	// Step is the simulated time each update covers
+	Step time.Duration
+	// MaxFPS caps the frame rate, zero leaves it uncapped
+	MaxFPS int
+	// MaxFrameTime stops one long frame turning into a pile of updates
+	MaxFrameTime time.Duration
+	// Ticks counts the updates run so far
+	Ticks uint64
+	// Clock is what Run reads the time from, nil uses the real clock
+	Clock Clock
+
+	accumulator time.Duration
+	paused      bool
+	steps       int
+}
+
+// Clock tells the time and waits. Headless platforms supply one so a game
+// runs the same however fast the machine is.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time        { return time.Now() }
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// New makes a loop running updatesPerSecond updates per second, which must
+// be above zero and leave a step of at least a nanosecond
+func New(updatesPerSecond int) (*Loop, error) {
+	if updatesPerSecond <= 0 {
+		return nil, fmt.Errorf("loop: %d updates per second, want at least 1", updatesPerSecond)
+	}
+	step := time.Second / time.Duration(updatesPerSecond)
+	if step <= 0 {
+		// a step of nothing would never catch up with the clock
+		return nil, fmt.Errorf("loop: %d updates per second, want at most %d", updatesPerSecond, time.Second)
+	}
+	return &Loop{Step: step, MaxFrameTime: 250 * time.Millisecond}, nil
+}
+
+// Pause stops updates until Resume, frames keep rendering
+func (l *Loop) Pause() {
+	l.paused = true
+}
+
+// Resume carries on after a Pause
+func (l *Loop) Resume() {
+	l.paused = false
+	l.steps = 0
+}
+
+// TogglePause pauses a running loop or resumes a paused one
+func (l *Loop) TogglePause() {
+	if l.paused {
+		l.Resume()
+	} else {
+		l.Pause()
+	}
+}
+
+// Paused reports whether the loop is paused
+func (l *Loop) Paused() bool {
+	return l.paused
+}
+
+// StepOnce runs a single update on the next frame while paused
+func (l *Loop) StepOnce() {
+	if l.paused {
+		l.steps++
+	}
+}
+
+// Advance moves the simulation on by elapsed real time, running as many
+// updates as fit, and returns the render alpha. dt passed to update is the
+// step in seconds.
+func (l *Loop) Advance(elapsed time.Duration, update func(dt float32)) (alpha float32) {
+	dt := float32(l.Step.Seconds())
+	if l.paused {
+		for ; l.steps > 0; l.steps-- {
+			update(dt)
+			l.Ticks++
+		}
+		return 1
+	}
+	if l.MaxFrameTime > 0 && elapsed > l.MaxFrameTime {
+		elapsed = l.MaxFrameTime
+	}
+	l.accumulator += elapsed
+	for l.accumulator >= l.Step {
+		update(dt)
+		l.Ticks++
+		l.accumulator -= l.Step
+	}
+	return float32(l.accumulator) / float32(l.Step)
+}
+
+// Run loops until events returns false. Each frame it polls events,
+// advances by the real time since the last frame and renders, then sleeps
+// off any time left under MaxFPS.
+func (l *Loop) Run(events func() bool, update func(dt float32), render func(alpha float32)) {
+	if update == nil {
+		update = func(float32) {}
+	}
+	clock := l.Clock
+	if clock == nil {
+		clock = realClock{}
+	}
+	last := clock.Now()
+	for {
+		frameStart := clock.Now()
+		if events != nil && !events() {
+			return
+		}
+		alpha := l.Advance(frameStart.Sub(last), update)
+		last = frameStart
+		if render != nil {
+			render(alpha)
+		}
+		if l.MaxFPS > 0 {
+			frameTime := time.Second / time.Duration(l.MaxFPS)
+			if elapsed := clock.Now().Sub(frameStart); elapsed < frameTime {
+				clock.Sleep(frameTime - elapsed)
+			}
+		}
+	}
+}
+
+// Lerp interpolates between the previous and current value of something
+// updated by the loop
+func Lerp(prev, cur, alpha float32) float32 {
+	return prev + (cur-prev)*alpha
+}
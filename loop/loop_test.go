@@ -0,0 +1,172 @@
+package loop
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when it is told to or slept on
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+}
+
+func newLoop(t *testing.T) *Loop {
+	l, err := New(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestNew(t *testing.T) {
+	l := newLoop(t)
+	if l.Step != 10*time.Millisecond || l.MaxFrameTime != 250*time.Millisecond {
+		t.Errorf("New(100) steps %v with frames up to %v", l.Step, l.MaxFrameTime)
+	}
+	if l, err := New(1e9); err != nil || l.Step != time.Nanosecond {
+		t.Errorf("New(1e9) gave %v, %v, want a step of 1ns", l, err)
+	}
+	for _, n := range []int{0, -60, 1e9 + 1, math.MaxInt64} {
+		if _, err := New(n); err == nil {
+			t.Errorf("New(%d) made a loop", n)
+		}
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		max     time.Duration
+		frames  []time.Duration
+		updates int
+		alpha   float32
+	}{
+		{"no time", 250 * ms, []time.Duration{0}, 0, 0},
+		{"part of a step", 250 * ms, []time.Duration{4 * ms}, 0, 0.4},
+		{"exactly a step", 250 * ms, []time.Duration{10 * ms}, 1, 0},
+		{"steps and a bit", 250 * ms, []time.Duration{35 * ms}, 3, 0.5},
+		{"short frames add up", 250 * ms, []time.Duration{3 * ms, 3 * ms, 3 * ms, 3 * ms}, 1, 0.2},
+		{"left over carries on", 250 * ms, []time.Duration{15 * ms, 15 * ms}, 3, 0},
+		{"long frame clamped", 250 * ms, []time.Duration{2 * time.Second}, 25, 0},
+		{"clamped then carried", 20 * ms, []time.Duration{time.Second, 5 * ms}, 2, 0.5},
+		{"no clamp", 0, []time.Duration{2 * time.Second}, 200, 0},
+	}
+	for _, tc := range tests {
+		l := newLoop(t)
+		l.MaxFrameTime = tc.max
+		updates := 0
+		var alpha float32
+		for _, f := range tc.frames {
+			alpha = l.Advance(f, func(dt float32) {
+				if dt != 0.01 {
+					t.Errorf("%s: update given %v, want the step in seconds", tc.name, dt)
+				}
+				updates++
+			})
+		}
+		if updates != tc.updates || l.Ticks != uint64(tc.updates) {
+			t.Errorf("%s: %d updates and %d ticks, want %d", tc.name, updates, l.Ticks, tc.updates)
+		}
+		if d := alpha - tc.alpha; d < -1e-4 || d > 1e-4 {
+			t.Errorf("%s: alpha %v, want %v", tc.name, alpha, tc.alpha)
+		}
+	}
+}
+
+func TestPauseAndStep(t *testing.T) {
+	l := newLoop(t)
+	updates := 0
+	update := func(float32) { updates++ }
+
+	l.Advance(5*time.Millisecond, update)
+	l.TogglePause()
+	if !l.Paused() {
+		t.Fatal("TogglePause didn't pause")
+	}
+	if a := l.Advance(time.Second, update); updates != 0 || a != 1 {
+		t.Errorf("paused loop ran %d updates with alpha %v", updates, a)
+	}
+
+	l.StepOnce()
+	l.StepOnce()
+	l.Advance(0, update)
+	if updates != 2 || l.Ticks != 2 {
+		t.Errorf("two steps ran %d updates and %d ticks", updates, l.Ticks)
+	}
+	l.Advance(time.Second, update)
+	if updates != 2 {
+		t.Errorf("steps were run again, %d updates", updates)
+	}
+
+	// steps asked for while running do nothing, and unused steps are
+	// dropped on resume
+	l.Pause()
+	l.StepOnce()
+	l.Resume()
+	l.StepOnce()
+	l.Advance(0, update)
+	if updates != 2 {
+		t.Errorf("steps outside a pause ran, %d updates", updates)
+	}
+	// time before the pause is still owed, time during it isn't
+	if a := l.Advance(5*time.Millisecond, update); updates != 3 || a != 0 {
+		t.Errorf("after the pause %d updates with alpha %v, want 3 and 0", updates, a)
+	}
+}
+
+func TestRun(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := newLoop(t)
+	l.Clock = clock
+	l.MaxFPS = 50
+
+	frames, updates := 0, 0
+	var alphas []float32
+	events := func() bool {
+		frames++
+		return frames <= 5
+	}
+	render := func(alpha float32) {
+		alphas = append(alphas, alpha)
+		// each render takes 5ms of the 20ms a frame has at 50 fps
+		clock.now = clock.now.Add(5 * time.Millisecond)
+	}
+	l.Run(events, func(float32) { updates++ }, render)
+
+	if len(alphas) != 5 {
+		t.Fatalf("rendered %d frames, want 5", len(alphas))
+	}
+	for i, d := range clock.slept {
+		if d != 15*time.Millisecond {
+			t.Errorf("frame %d slept %v, want the 15ms left of the frame", i, d)
+		}
+	}
+	// the first frame has no time behind it, the other four each cover
+	// 20ms, two steps
+	if updates != 8 || alphas[0] != 0 || alphas[4] != 0 {
+		t.Errorf("%d updates with alphas %v", updates, alphas)
+	}
+}
+
+func TestLerp(t *testing.T) {
+	for _, tc := range []struct{ prev, cur, alpha, want float32 }{
+		{10, 20, 0, 10},
+		{10, 20, 1, 20},
+		{10, 20, 0.25, 12.5},
+		{20, 10, 0.5, 15},
+	} {
+		if got := Lerp(tc.prev, tc.cur, tc.alpha); got != tc.want {
+			t.Errorf("Lerp(%v, %v, %v) = %v, want %v", tc.prev, tc.cur, tc.alpha, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+// Mixes any number of sounds into one stereo stream
+package mixer
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/wav"
+)
+
+// Sink is where mixed audio is queued. Platforms are sinks, and the
+// headless platform keeps everything queued to it for checking.
+type Sink interface {
+	QueueAudio(data []byte) error
+	QueuedAudio() int
+}
+
+// Mixer plays voices into signed 16 bit stereo at Freq samples a second
+type Mixer struct {
+	Freq int
+
+	mu     sync.Mutex
+	volume float32
+	voices []*Voice
+	mix    []float32
+	out    []byte
+}
+
+// Options for a voice. As with Play, a Volume of zero is silent, so set
+// it to 1 to play the sound as it is.
+type Options struct {
+	// Volume is 1 for unchanged and 0 for silent
+	Volume float32
+	// Pitch of zero or below is treated as one, a pitch of 2 is an
+	// octave up
+	Pitch float32
+	// Pan is from -1 for the left speaker to 1 for the right
+	Pan float32
+	// LowPass is a cutoff frequency in Hz, zero leaves the sound unfiltered
+	LowPass float32
+	// Loop plays the sound over and over until it is stopped
+	Loop bool
+}
+
+// Voice is one sound playing in a mixer
+type Voice struct {
+	m        *Mixer
+	sound    *wav.Sound
+	pos      float64
+	volume   float32
+	pan      float32
+	pitch    float32
+	lowPass  float32
+	lpL, lpR float32
+	loop     bool
+	done     bool
+}
+
+// New makes a mixer for an output of freq samples a second
+func New(freq int) *Mixer {
+	return &Mixer{Freq: freq, volume: 1}
+}
+
+// SetVolume sets the master volume, 1 is unchanged
+func (m *Mixer) SetVolume(volume float32) {
+	m.mu.Lock()
+	m.volume = volume
+	m.mu.Unlock()
+}
+
+// Play starts a sound at a volume, 1 is unchanged and 0 is silent, and a
+// pan from -1 for the left speaker to 1 for the right
+func (m *Mixer) Play(s *wav.Sound, volume, pan float32) *Voice {
+	return m.add(&Voice{m: m, sound: s, volume: volume, pan: pan, pitch: 1})
+}
+
+// Loop plays a sound over and over until it is stopped, for music
+func (m *Mixer) Loop(s *wav.Sound, volume, pan float32) *Voice {
+	return m.add(&Voice{m: m, sound: s, volume: volume, pan: pan, pitch: 1, loop: true})
+}
+
+// PlayOptions starts a sound with everything about the voice set up front,
+// so none of it is missed by a mix running on another goroutine
+func (m *Mixer) PlayOptions(s *wav.Sound, opts Options) *Voice {
+	v := &Voice{m: m, sound: s, volume: opts.Volume, pan: opts.Pan, pitch: opts.Pitch, lowPass: opts.LowPass, loop: opts.Loop}
+	if !(v.pitch > 0) {
+		v.pitch = 1
+	}
+	return m.add(v)
+}
+
+func (m *Mixer) add(v *Voice) *Voice {
+	m.mu.Lock()
+	m.voices = append(m.voices, v)
+	m.mu.Unlock()
+	return v
+}
+
+// Voices is how many voices are playing
+func (m *Mixer) Voices() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.voices)
+}
+
+// StopAll stops every voice
+func (m *Mixer) StopAll() {
+	m.mu.Lock()
+	for _, v := range m.voices {
+		v.done = true
+	}
+	m.voices = m.voices[:0]
+	m.mu.Unlock()
+}
+
+func (v *Voice) SetVolume(volume float32) {
+	v.m.mu.Lock()
+	v.volume = volume
+	v.m.mu.Unlock()
+}
+
+func (v *Voice) SetPan(pan float32) {
+	v.m.mu.Lock()
+	v.pan = pan
+	v.m.mu.Unlock()
+}
+
+var errPitch = errors.New("mixer: pitch must be above zero")
+
+// SetPitch changes the playback speed, 2 is an octave up. A pitch of zero
+// or below is an error and leaves the pitch as it was.
+func (v *Voice) SetPitch(pitch float32) error {
+	if !(pitch > 0) {
+		return errPitch
+	}
+	v.m.mu.Lock()
+	v.pitch = pitch
+	v.m.mu.Unlock()
+	return nil
+}
+
+// SetLowPass sets the filter cutoff in Hz, zero turns the filter off
+func (v *Voice) SetLowPass(cutoff float32) {
+	v.m.mu.Lock()
+	v.lowPass = cutoff
+	v.m.mu.Unlock()
+}
+
+// Stop ends the voice, a stopped voice can't be restarted
+func (v *Voice) Stop() {
+	v.m.mu.Lock()
+	v.done = true
+	v.m.mu.Unlock()
+}
+
+// Playing reports whether the voice is still going
+func (v *Voice) Playing() bool {
+	v.m.mu.Lock()
+	defer v.m.mu.Unlock()
+	return !v.done
+}
+
+// Mix fills out with the next len(out)/4 stereo samples, left then right,
+// and moves every voice on. It can be called from an audio callback.
+func (m *Mixer) Mix(out []byte) {
+	frames := len(out) / 4
+	m.mu.Lock()
+	if cap(m.mix) < frames*2 {
+		m.mix = make([]float32, frames*2)
+	}
+	mix := m.mix[:frames*2]
+	for i := range mix {
+		mix[i] = 0
+	}
+
+	live := m.voices[:0]
+	for _, v := range m.voices {
+		if !v.done {
+			v.mixInto(mix, m.Freq)
+		}
+		if !v.done {
+			live = append(live, v)
+		}
+	}
+	// let go of finished voices so they can be collected
+	for i := len(live); i < len(m.voices); i++ {
+		m.voices[i] = nil
+	}
+	m.voices = live
+	volume := m.volume
+	m.mu.Unlock()
+
+	for i, s := range mix {
+		s *= volume
+		if s > 32767 {
+			s = 32767
+		} else if s < -32768 {
+			s = -32768
+		}
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(s)))
+	}
+}
+
+// sample reads channel c of source frame i
+func (v *Voice) sample(i, c int) float32 {
+	s := v.sound
+	if c >= s.Channels {
+		c = 0
+	}
+	p := (i*s.Channels + c) * 2
+	return float32(int16(binary.LittleEndian.Uint16(s.Data[p:])))
+}
+
+// mixInto adds the voice to a stereo mix, resampling from the sound's rate
+// by linear interpolation
+func (v *Voice) mixInto(mix []float32, freq int) {
+	s := v.sound
+	frames := 0
+	if s.Channels > 0 {
+		frames = len(s.Data) / (2 * s.Channels)
+	}
+	if frames == 0 || freq <= 0 {
+		v.done = true
+		return
+	}
+	step := float64(s.Freq) / float64(freq) * float64(v.pitch)
+	left, right := v.volume, v.volume
+	if v.pan > 0 {
+		left *= 1 - v.pan
+	} else if v.pan < 0 {
+		right *= 1 + v.pan
+	}
+	// one pole low pass, https://en.wikipedia.org/wiki/Low-pass_filter#Simple_infinite_impulse_response_filter
+	var lp float32
+	if v.lowPass > 0 {
+		lp = float32(1 - math.Exp(-2*math.Pi*float64(v.lowPass)/float64(freq)))
+	}
+
+	for i := 0; i < len(mix); i += 2 {
+		if v.pos >= float64(frames) {
+			if !v.loop {
+				v.done = true
+				return
+			}
+			// a high pitch can step over the whole sound at once
+			v.pos = math.Mod(v.pos, float64(frames))
+		}
+		i0 := int(v.pos)
+		i1 := i0 + 1
+		if i1 >= frames {
+			i1 = 0
+			if !v.loop {
+				i1 = i0
+			}
+		}
+		t := float32(v.pos - float64(i0))
+		l := v.sample(i0, 0) + (v.sample(i1, 0)-v.sample(i0, 0))*t
+		r := v.sample(i0, 1) + (v.sample(i1, 1)-v.sample(i0, 1))*t
+		if lp > 0 {
+			v.lpL += (l - v.lpL) * lp
+			v.lpR += (r - v.lpR) * lp
+			l, r = v.lpL, v.lpR
+		}
+		mix[i] += l * left
+		mix[i+1] += r * right
+		v.pos += step
+	}
+}
+
+// Pump mixes enough to keep ahead of audio queued on the sink. Call it
+// every update, a longer ahead is safer against stalls but slower to
+// respond.
+func (m *Mixer) Pump(sink Sink, ahead time.Duration) error {
+	want := int(ahead.Seconds()*float64(m.Freq)) * 4
+	need := want - sink.QueuedAudio()
+	need -= need % 4
+	if need <= 0 {
+		return nil
+	}
+	if cap(m.out) < need {
+		m.out = make([]byte, need)
+	}
+	out := m.out[:need]
+	m.Mix(out)
+	return sink.QueueAudio(out)
+}
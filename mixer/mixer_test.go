@@ -0,0 +1,242 @@
+package mixer
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/wav"
+)
+
+// constant is a mono sound of n samples all at level
+func constant(freq, n int, level int16) *wav.Sound {
+	s := &wav.Sound{Freq: freq, Channels: 1, Data: make([]byte, n*2)}
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(s.Data[i*2:], uint16(level))
+	}
+	return s
+}
+
+// ramp is a mono sound of n samples going up by step
+func ramp(freq, n int, step int16) *wav.Sound {
+	s := &wav.Sound{Freq: freq, Channels: 1, Data: make([]byte, n*2)}
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(s.Data[i*2:], uint16(int16(i)*step))
+	}
+	return s
+}
+
+// stereo splits mixed output into left and right samples
+func stereo(out []byte) (left, right []int16) {
+	for i := 0; i+4 <= len(out); i += 4 {
+		left = append(left, int16(binary.LittleEndian.Uint16(out[i:])))
+		right = append(right, int16(binary.LittleEndian.Uint16(out[i+2:])))
+	}
+	return left, right
+}
+
+// mix runs the mixer for frames stereo samples
+func mix(m *Mixer, frames int) (left, right []int16) {
+	out := make([]byte, frames*4)
+	m.Mix(out)
+	return stereo(out)
+}
+
+func TestMixSumsAndClips(t *testing.T) {
+	m := New(100)
+	m.Play(constant(100, 4, 1000), 1, 0)
+	m.Play(constant(100, 4, 2000), 0.5, 0)
+	left, right := mix(m, 4)
+	for i := range left {
+		if left[i] != 2000 || right[i] != 2000 {
+			t.Fatalf("sample %d is %d, %d, want 2000", i, left[i], right[i])
+		}
+	}
+
+	m.Play(constant(100, 4, 30000), 1, 0)
+	m.Play(constant(100, 4, 30000), 1, 0)
+	if left, _ := mix(m, 1); left[0] != 32767 {
+		t.Errorf("loud voices mixed to %d, want clipped to 32767", left[0])
+	}
+	m.StopAll()
+	m.SetVolume(0.5)
+	m.Play(constant(100, 4, -1000), 1, 0)
+	if left, _ := mix(m, 1); left[0] != -500 {
+		t.Errorf("master volume of a half gave %d, want -500", left[0])
+	}
+}
+
+func TestPan(t *testing.T) {
+	m := New(100)
+	m.Play(constant(100, 4, 1000), 1, -1)
+	left, right := mix(m, 1)
+	if left[0] != 1000 || right[0] != 0 {
+		t.Errorf("hard left is %d, %d", left[0], right[0])
+	}
+	m.StopAll()
+	m.Play(constant(100, 4, 1000), 1, 0.5)
+	left, right = mix(m, 1)
+	if left[0] != 500 || right[0] != 1000 {
+		t.Errorf("half right is %d, %d", left[0], right[0])
+	}
+}
+
+func TestPlayVolume(t *testing.T) {
+	m := New(100)
+	quiet := m.Play(constant(100, 4, 1000), 0, 0)
+	m.Loop(constant(100, 4, 1000), 0, 0)
+	if left, right := mix(m, 1); left[0] != 0 || right[0] != 0 {
+		t.Errorf("voices at volume 0 played %d, %d", left[0], right[0])
+	}
+	quiet.SetVolume(1)
+	if left, _ := mix(m, 1); left[0] != 1000 {
+		t.Errorf("turned up to 1 played %d, want 1000", left[0])
+	}
+
+	// volume 0 is silent in options too
+	m.StopAll()
+	quiet = m.PlayOptions(constant(100, 4, 1000), Options{Pitch: 1})
+	if left, right := mix(m, 1); left[0] != 0 || right[0] != 0 {
+		t.Errorf("options at volume 0 played %d, %d", left[0], right[0])
+	}
+	quiet.SetVolume(1)
+	if left, _ := mix(m, 1); left[0] != 1000 {
+		t.Errorf("options turned up to 1 played %d, want 1000", left[0])
+	}
+	m.StopAll()
+	m.PlayOptions(constant(100, 4, 1000), Options{Volume: 1})
+	if left, _ := mix(m, 1); left[0] != 1000 {
+		t.Errorf("options at volume 1 played %d, want 1000", left[0])
+	}
+}
+
+func TestResample(t *testing.T) {
+	// a sound at half the mixer's rate is stretched out, in between
+	// samples interpolated
+	m := New(200)
+	m.Play(ramp(100, 4, 100), 1, 0)
+	left, _ := mix(m, 6)
+	want := []int16{0, 50, 100, 150, 200, 250}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Fatalf("resampled to %v, want %v", left, want)
+		}
+	}
+
+	m.StopAll()
+	v := m.PlayOptions(ramp(100, 8, 100), Options{Volume: 1, Pitch: 2})
+	left, _ = mix(m, 2)
+	if left[1] != 100 {
+		t.Errorf("an octave up at twice the rate gave %v", left)
+	}
+	if err := v.SetPitch(4); err != nil {
+		t.Fatal(err)
+	}
+	left, _ = mix(m, 2)
+	if left[0] != 200 || left[1] != 400 {
+		t.Errorf("pitch of 4 gave %v, want 200 then 400", left)
+	}
+}
+
+func TestSetPitchRejectsZero(t *testing.T) {
+	m := New(100)
+	v := m.Play(ramp(100, 8, 100), 1, 0)
+	for _, p := range []float32{0, -1} {
+		if err := v.SetPitch(p); err == nil {
+			t.Errorf("pitch %v was accepted", p)
+		}
+	}
+	left, _ := mix(m, 3)
+	if left[2] != 200 {
+		t.Errorf("a rejected pitch changed playback to %v", left)
+	}
+
+	// options with no sensible pitch play as they are
+	m.StopAll()
+	m.PlayOptions(ramp(100, 8, 100), Options{Volume: 1, Pitch: -2})
+	left, _ = mix(m, 3)
+	if left[2] != 200 {
+		t.Errorf("a negative pitch played %v", left)
+	}
+}
+
+func TestLoopWraps(t *testing.T) {
+	m := New(100)
+	v := m.Loop(ramp(100, 3, 100), 1, 0)
+	left, _ := mix(m, 7)
+	want := []int16{0, 100, 200, 0, 100, 200, 0}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Fatalf("looped %v, want %v", left, want)
+		}
+	}
+
+	// a step of more than the whole sound wraps back inside it
+	if err := v.SetPitch(7); err != nil {
+		t.Fatal(err)
+	}
+	left, _ = mix(m, 4)
+	// from 1 on by 7 each time, modulo 3
+	want = []int16{100, 200, 0, 100}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Fatalf("a big step looped %v, want %v", left, want)
+		}
+	}
+	if !v.Playing() {
+		t.Error("a looping voice stopped")
+	}
+}
+
+func TestVoicesEnd(t *testing.T) {
+	m := New(100)
+	v := m.Play(constant(100, 3, 1000), 1, 0)
+	m.Loop(constant(100, 3, 1000), 1, 0)
+	left, _ := mix(m, 4)
+	if left[2] != 2000 || left[3] != 1000 {
+		t.Errorf("mixed %v, want the short voice gone after 3 samples", left)
+	}
+	if v.Playing() || m.Voices() != 1 {
+		t.Errorf("%d voices after one ended", m.Voices())
+	}
+
+	m.Play(&wav.Sound{Freq: 100, Channels: 1}, 1, 0)
+	mix(m, 1)
+	if m.Voices() != 1 {
+		t.Error("an empty sound kept playing")
+	}
+	m.StopAll()
+	if left, _ := mix(m, 1); m.Voices() != 0 || left[0] != 0 {
+		t.Error("voices played on after StopAll")
+	}
+}
+
+func TestPump(t *testing.T) {
+	h := platform.NewHeadless(1, 1)
+	if err := h.OpenAudio(platform.AudioSpec{Freq: 100, Channels: 2}); err != nil {
+		t.Fatal(err)
+	}
+	m := New(100)
+	m.Play(ramp(100, 20, 10), 1, 0)
+
+	// a tenth of a second ahead at 100 samples a second is 10 samples
+	if err := m.Pump(h, time.Second/10); err != nil {
+		t.Fatal(err)
+	}
+	if h.QueuedAudio() != 40 {
+		t.Fatalf("queued %d bytes, want 40", h.QueuedAudio())
+	}
+	// already far enough ahead
+	m.Pump(h, time.Second/10)
+	if h.QueuedAudio() != 40 {
+		t.Errorf("queued %d bytes when already ahead", h.QueuedAudio())
+	}
+
+	left, right := stereo(h.Audio)
+	for i := range left {
+		if left[i] != int16(i*10) || right[i] != left[i] {
+			t.Fatalf("sample %d heard as %d, %d, want %d", i, left[i], right[i], i*10)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Simulates a poor network by delaying and dropping packets sent over a
+// real one, so networked games can be tried over loopback
+package netsim
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Conn is a packet connection whose writes arrive Latency plus up to
+// Jitter later, never sooner, with a Loss fraction of them never arriving
+// at all. Jitter can reorder packets, as real networks do.
+type Conn struct {
+	net.PacketConn
+	Latency time.Duration
+	Jitter  time.Duration
+	// Loss is from 0 for none to 1 for every packet
+	Loss float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// Wrap adds simulated latency and loss to c. The seed picks which packets
+// are lost.
+func Wrap(c net.PacketConn, latency, jitter time.Duration, loss float64, seed int64) *Conn {
+	return &Conn{PacketConn: c, Latency: latency, Jitter: jitter, Loss: loss, rng: rand.New(rand.NewSource(seed))}
+}
+
+// WriteTo sends p to addr later, or not at all. It always reports success,
+// the sender of a lost packet can't tell.
+func (c *Conn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	lost := c.Loss > 0 && c.rng.Float64() < c.Loss
+	delay := c.Latency
+	if c.Jitter > 0 {
+		delay += time.Duration(c.rng.Int63n(int64(c.Jitter)))
+	}
+	c.mu.Unlock()
+
+	if lost {
+		return len(p), nil
+	}
+	if delay <= 0 {
+		return c.PacketConn.WriteTo(p, addr)
+	}
+	buf := append([]byte(nil), p...)
+	time.AfterFunc(delay, func() {
+		// a closed connection is the only likely error, and then nobody
+		// is waiting for the packet
+		c.PacketConn.WriteTo(buf, addr)
+	})
+	return len(p), nil
+}
@@ -0,0 +1,113 @@
+package netsim
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// arrival is a packet written to a sink and when it was
+type arrival struct {
+	id int
+	at time.Time
+}
+
+// sink is a packet connection that keeps what is written to it
+type sink struct {
+	net.PacketConn
+	arrivals chan arrival
+}
+
+func newSink() *sink {
+	return &sink{arrivals: make(chan arrival, 10000)}
+}
+
+func (s *sink) WriteTo(p []byte, addr net.Addr) (int, error) {
+	s.arrivals <- arrival{int(p[0]) | int(p[1])<<8, time.Now()}
+	return len(p), nil
+}
+
+// send writes n packets numbered from 0 as fast as it can and returns
+// when each was sent
+func send(c *Conn, n int) []time.Time {
+	sent := make([]time.Time, n)
+	for i := range sent {
+		sent[i] = time.Now()
+		c.WriteTo([]byte{byte(i), byte(i >> 8)}, nil)
+	}
+	return sent
+}
+
+// receive waits for n packets
+func receive(t *testing.T, s *sink, n int) []arrival {
+	t.Helper()
+	var got []arrival
+	timeout := time.After(5 * time.Second)
+	for len(got) < n {
+		select {
+		case a := <-s.arrivals:
+			got = append(got, a)
+		case <-timeout:
+			t.Fatalf("%d of %d packets arrived", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestDelay(t *testing.T) {
+	const latency, jitter = 20 * time.Millisecond, 30 * time.Millisecond
+	for _, j := range []time.Duration{0, jitter} {
+		s := newSink()
+		c := Wrap(s, latency, j, 0, 1)
+		sent := send(c, 50)
+		reordered := false
+		for i, a := range receive(t, s, len(sent)) {
+			if d := a.at.Sub(sent[a.id]); d < latency {
+				t.Errorf("with jitter %v packet %d arrived after %v, want at least %v", j, a.id, d, latency)
+			}
+			if a.id != i {
+				reordered = true
+			}
+		}
+		if j > 0 && !reordered {
+			t.Errorf("jitter of %v didn't reorder packets sent together", j)
+		}
+	}
+}
+
+func TestLoss(t *testing.T) {
+	// with no delay writes arrive straight away, in order
+	lost := func(loss float64, seed int64, n int) map[int]bool {
+		s := newSink()
+		send(Wrap(s, 0, 0, loss, seed), n)
+		lost := make(map[int]bool)
+		for i := 0; i < n; i++ {
+			lost[i] = true
+		}
+		for len(s.arrivals) > 0 {
+			delete(lost, (<-s.arrivals).id)
+		}
+		return lost
+	}
+
+	const n = 4000
+	got := lost(0.25, 1, n)
+	if f := float64(len(got)) / n; f < 0.22 || f > 0.28 {
+		t.Errorf("lost %v of the packets, want about 0.25", f)
+	}
+	again := lost(0.25, 1, n)
+	if len(again) != len(got) {
+		t.Fatalf("the same seed lost %d packets then %d", len(got), len(again))
+	}
+	for id := range got {
+		if !again[id] {
+			t.Fatalf("the same seed lost packet %d once but not again", id)
+		}
+	}
+	if l := lost(0, 1, n); len(l) != 0 {
+		t.Errorf("no loss lost %d packets", len(l))
+	}
+	if l := lost(1, 1, n); len(l) != n {
+		t.Errorf("a loss of 1 let %d packets through", n-len(l))
+	}
+}
@@ -0,0 +1,141 @@
+// Colours and gradients shared by the games
+package palette
+
+import (
+	"image"
+	"math"
+)
+
+// Color is an opaque rgb colour
+type Color struct {
+	R, G, B byte
+}
+
+// Space indicates which colour space a gradient is interpolated in
+type Space int
+
+const (
+	RGB Space = iota
+	HSV
+	OKLab
+)
+
+// Stop is a colour at a position between 0 and 1 along a gradient
+type Stop struct {
+	Pos   float32
+	Color Color
+}
+
+// Gradient is a 256 entry colour lookup table
+type Gradient []Color
+
+func lerp(b1 byte, b2 byte, pct float32) byte {
+	return byte(float32(b1) + pct*(float32(b2)-float32(b1)))
+}
+
+// Lerp linearly interpolates between two colours in rgb
+func Lerp(c1, c2 Color, pct float32) Color {
+	return Color{lerp(c1.R, c2.R, pct), lerp(c1.G, c2.G, pct), lerp(c1.B, c2.B, pct)}
+}
+
+// LerpSpace interpolates between two colours in the given colour space
+func LerpSpace(c1, c2 Color, pct float32, space Space) Color {
+	switch space {
+	case HSV:
+		return lerpHSV(c1, c2, pct)
+	case OKLab:
+		return lerpOKLab(c1, c2, pct)
+	}
+	return Lerp(c1, c2, pct)
+}
+
+// NewGradient makes a gradient from c1 to c2
+func NewGradient(c1, c2 Color) Gradient {
+	return NewMultiGradient(RGB, Stop{0, c1}, Stop{1, c2})
+}
+
+// NewDualGradient makes a gradient from c1 to c2 over the first half and
+// from c3 to c4 over the second half
+func NewDualGradient(c1, c2, c3, c4 Color) Gradient {
+	result := make(Gradient, 256)
+	for i := range result {
+		pct := float32(i) / float32(255)
+		if pct < 0.5 {
+			result[i] = Lerp(c1, c2, pct*2)
+		} else {
+			result[i] = Lerp(c3, c4, pct*2-1)
+		}
+	}
+	return result
+}
+
+// NewMultiGradient makes a gradient passing through each of the stops, which
+// must be sorted by position. Before the first stop and after the last the
+// end colours are held.
+func NewMultiGradient(space Space, stops ...Stop) Gradient {
+	result := make(Gradient, 256)
+	if len(stops) == 0 {
+		return result
+	}
+	s := 0
+	for i := range result {
+		pct := float32(i) / float32(255)
+		for s < len(stops)-1 && pct > stops[s+1].Pos {
+			s++
+		}
+		switch {
+		case pct <= stops[0].Pos:
+			result[i] = stops[0].Color
+		case s == len(stops)-1:
+			result[i] = stops[s].Color
+		default:
+			a, b := stops[s], stops[s+1]
+			t := float32(0)
+			if b.Pos > a.Pos {
+				t = (pct - a.Pos) / (b.Pos - a.Pos)
+			}
+			result[i] = LerpSpace(a.Color, b.Color, t, space)
+		}
+	}
+	return result
+}
+
+// At returns the colour pct of the way along the gradient
+func (g Gradient) At(pct float32) Color {
+	return g[clamp(0, len(g)-1, int(pct*float32(len(g)-1)))]
+}
+
+func clamp(min, max, v int) int {
+	if v < min {
+		v = min
+	} else if v > max {
+		v = max
+	}
+	return v
+}
+
+// Draw rescales a block of noise between min and max onto the gradient and
+// writes it into an rgba pixel buffer. The noise itself is left untouched.
+func (g Gradient) Draw(noise []float32, min, max float32, pixels []byte) {
+	scale := float32(len(g)-1) / (max - min)
+	offset := min * scale
+	if math.IsInf(float64(scale), 0) || math.IsNaN(float64(scale)) {
+		scale, offset = 0, 0
+	}
+
+	for i := range noise {
+		c := g[clamp(0, len(g)-1, int(noise[i]*scale-offset))]
+		p := i * 4
+		pixels[p] = c.R
+		pixels[p+1] = c.G
+		pixels[p+2] = c.B
+		pixels[p+3] = 255
+	}
+}
+
+// Image rescales a w by h block of noise onto the gradient as an image
+func (g Gradient) Image(noise []float32, min, max float32, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	g.Draw(noise[:w*h], min, max, img.Pix)
+	return img
+}
@@ -0,0 +1,121 @@
+package palette
+
+import (
+	"strings"
+	"testing"
+)
+
+func near(a, b Color, tolerance int) bool {
+	d := func(x, y byte) bool {
+		diff := int(x) - int(y)
+		return diff >= -tolerance && diff <= tolerance
+	}
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B)
+}
+
+func TestGradientEnds(t *testing.T) {
+	red, blue := Color{R: 255}, Color{B: 255}
+	g := NewGradient(red, blue)
+	if len(g) != 256 {
+		t.Fatalf("gradient has %d entries, want 256", len(g))
+	}
+	if g[0] != red || g[255] != blue {
+		t.Errorf("gradient runs from %v to %v, want %v to %v", g[0], g[255], red, blue)
+	}
+	if got := g.At(0.5); !near(got, Color{R: 127, B: 127}, 1) {
+		t.Errorf("half way is %v", got)
+	}
+	if g.At(-1) != red || g.At(2) != blue {
+		t.Error("At doesn't clamp to the ends")
+	}
+}
+
+func TestMultiGradientHoldsEnds(t *testing.T) {
+	black, white := Color{}, Color{R: 255, G: 255, B: 255}
+	g := NewMultiGradient(RGB, Stop{0.25, black}, Stop{0.75, white})
+	for i := 0; i < 64; i++ {
+		if g[i] != black {
+			t.Fatalf("entry %d is %v before the first stop", i, g[i])
+		}
+	}
+	for i := 192; i < 256; i++ {
+		if g[i] != white {
+			t.Fatalf("entry %d is %v after the last stop", i, g[i])
+		}
+	}
+	if !near(g[128], Color{R: 128, G: 128, B: 128}, 2) {
+		t.Errorf("middle is %v", g[128])
+	}
+}
+
+func TestLerpSpaceEnds(t *testing.T) {
+	c1, c2 := Color{R: 200, G: 30, B: 90}, Color{R: 10, G: 220, B: 160}
+	for _, space := range []Space{RGB, HSV, OKLab} {
+		if got := LerpSpace(c1, c2, 0, space); !near(got, c1, 1) {
+			t.Errorf("space %d at 0 is %v, want %v", space, got, c1)
+		}
+		if got := LerpSpace(c1, c2, 1, space); !near(got, c2, 1) {
+			t.Errorf("space %d at 1 is %v, want %v", space, got, c2)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Color
+		ok   bool
+	}{
+		{"#ff8000", Color{R: 255, G: 128}, true},
+		{" 255, 128 0 ", Color{R: 255, G: 128}, true},
+		{"#ff80", Color{}, false},
+		{"256 0 0", Color{}, false},
+		{"1 2", Color{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestLoadFormatsAgree(t *testing.T) {
+	text := "space oklab // the rest are stops\n0 #0000af\n1 255 255 255\n"
+	json := `{"space": "oklab", "stops": [{"pos": 0, "color": "#0000af"}, {"pos": 1, "color": "#ffffff"}]}`
+	g1, err := Load(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := Load(strings.NewReader(json))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range g1 {
+		if g1[i] != g2[i] {
+			t.Fatalf("entry %d is %v from text and %v from json", i, g1[i], g2[i])
+		}
+	}
+	if _, err := Load(strings.NewReader("// nothing\n")); err == nil {
+		t.Error("a gradient with no stops loaded")
+	}
+}
+
+func TestDrawRescales(t *testing.T) {
+	g := NewGradient(Color{}, Color{R: 255, G: 255, B: 255})
+	pixels := make([]byte, 3*4)
+	g.Draw([]float32{-2, 0, 2}, -2, 2, pixels)
+	for i, want := range []byte{0, 127, 255} {
+		if got := pixels[i*4]; got != want {
+			t.Errorf("pixel %d is %d, want %d", i, got, want)
+		}
+		if pixels[i*4+3] != 255 {
+			t.Errorf("pixel %d isn't opaque", i)
+		}
+	}
+	// flat noise has no range to rescale, it all goes to the start
+	g.Draw([]float32{1, 1, 1}, 1, 1, pixels)
+	if pixels[0] != 0 {
+		t.Errorf("flat noise drew %d", pixels[0])
+	}
+}
@@ -0,0 +1,179 @@
+package palette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Presets are named gradients that games can pick by name
+var Presets = map[string]func() Gradient{
+	"sky":     Sky,
+	"fire":    Fire,
+	"terrain": Terrain,
+}
+
+// Preset looks up one of the Presets by name
+func Preset(name string) (Gradient, error) {
+	p, ok := Presets[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("palette: unknown preset %q", name)
+	}
+	return p(), nil
+}
+
+// Sky runs from deep blue through to white clouds
+func Sky() Gradient {
+	return NewMultiGradient(OKLab,
+		Stop{0, Color{0, 0, 175}},
+		Stop{0.5, Color{80, 160, 244}},
+		Stop{1, Color{255, 255, 255}})
+}
+
+// Fire runs from black through red and orange to yellow
+func Fire() Gradient {
+	return NewMultiGradient(RGB,
+		Stop{0, Color{0, 0, 0}},
+		Stop{0.4, Color{255, 0, 0}},
+		Stop{0.8, Color{255, 160, 0}},
+		Stop{1, Color{255, 242, 0}})
+}
+
+// Terrain is deep water, shallows, sand, grass, rock and snow
+func Terrain() Gradient {
+	return NewMultiGradient(RGB,
+		Stop{0, Color{0, 0, 175}},
+		Stop{0.4, Color{80, 160, 244}},
+		Stop{0.45, Color{240, 220, 130}},
+		Stop{0.5, Color{12, 192, 75}},
+		Stop{0.75, Color{110, 90, 60}},
+		Stop{0.9, Color{255, 255, 255}},
+		Stop{1, Color{255, 255, 255}})
+}
+
+var spaceNames = map[string]Space{"rgb": RGB, "hsv": HSV, "oklab": OKLab}
+
+// ParseColor reads a colour as either #rrggbb or three numbers r g b
+func ParseColor(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil || len(s) != 7 {
+			return Color{}, fmt.Errorf("palette: bad colour %q", s)
+		}
+		return Color{byte(v >> 16), byte(v >> 8), byte(v)}, nil
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+	if len(fields) != 3 {
+		return Color{}, fmt.Errorf("palette: bad colour %q", s)
+	}
+	var rgb [3]byte
+	for i, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 8)
+		if err != nil {
+			return Color{}, fmt.Errorf("palette: bad colour %q", s)
+		}
+		rgb[i] = byte(v)
+	}
+	return Color{rgb[0], rgb[1], rgb[2]}, nil
+}
+
+type jsonGradient struct {
+	Space string `json:"space"`
+	Stops []struct {
+		Pos   float32 `json:"pos"`
+		Color string  `json:"color"`
+	} `json:"stops"`
+}
+
+// Load reads a gradient in either the json form
+//
+//	{"space": "oklab", "stops": [{"pos": 0, "color": "#0000af"}, ...]}
+//
+// or the text form, one stop per line with // comments
+//
+//	space oklab
+//	0.0 #0000af
+//	1.0 255 255 255
+func Load(r io.Reader) (Gradient, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	space := RGB
+	var stops []Stop
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var jg jsonGradient
+		if err := json.Unmarshal(trimmed, &jg); err != nil {
+			return nil, fmt.Errorf("palette: %v", err)
+		}
+		if jg.Space != "" {
+			if space, err = parseSpace(jg.Space); err != nil {
+				return nil, err
+			}
+		}
+		for _, js := range jg.Stops {
+			c, err := ParseColor(js.Color)
+			if err != nil {
+				return nil, err
+			}
+			stops = append(stops, Stop{js.Pos, c})
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for line := 1; scanner.Scan(); line++ {
+			text := scanner.Text()
+			if i := strings.Index(text, "//"); i >= 0 {
+				text = text[:i]
+			}
+			fields := strings.Fields(text)
+			if len(fields) == 0 {
+				continue
+			}
+			if fields[0] == "space" && len(fields) == 2 {
+				if space, err = parseSpace(fields[1]); err != nil {
+					return nil, fmt.Errorf("palette: line %d: %v", line, err)
+				}
+				continue
+			}
+			pos, err := strconv.ParseFloat(fields[0], 32)
+			if err != nil || len(fields) < 2 {
+				return nil, fmt.Errorf("palette: line %d: expected position and colour", line)
+			}
+			c, err := ParseColor(strings.Join(fields[1:], " "))
+			if err != nil {
+				return nil, fmt.Errorf("palette: line %d: %v", line, err)
+			}
+			stops = append(stops, Stop{float32(pos), c})
+		}
+	}
+	if len(stops) == 0 {
+		return nil, fmt.Errorf("palette: gradient has no stops")
+	}
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].Pos < stops[j].Pos })
+	return NewMultiGradient(space, stops...), nil
+}
+
+// LoadFile reads a gradient file, see Load for the formats
+func LoadFile(filename string) (Gradient, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func parseSpace(s string) (Space, error) {
+	space, ok := spaceNames[strings.ToLower(s)]
+	if !ok {
+		return RGB, fmt.Errorf("palette: unknown colour space %q", s)
+	}
+	return space, nil
+}
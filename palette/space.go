@@ -0,0 +1,132 @@
+package palette
+
+import "math"
+
+func flerp(a, b, pct float32) float32 {
+	return a + (b-a)*pct
+}
+
+func toByte(v float32) byte {
+	return byte(clamp(0, 255, int(v*255+0.5)))
+}
+
+// hue is in degrees, saturation and value are between 0 and 1
+func toHSV(c Color) (h, s, v float32) {
+	r, g, b := float32(c.R)/255, float32(c.G)/255, float32(c.B)/255
+	max := float32(math.Max(float64(r), math.Max(float64(g), float64(b))))
+	min := float32(math.Min(float64(r), math.Min(float64(g), float64(b))))
+	d := max - min
+	v = max
+	if max > 0 {
+		s = d / max
+	}
+	if d == 0 {
+		return 0, s, v
+	}
+	switch max {
+	case r:
+		h = (g - b) / d
+		if h < 0 {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h * 60, s, v
+}
+
+func fromHSV(h, s, v float32) Color {
+	h = float32(math.Mod(float64(h), 360))
+	if h < 0 {
+		h += 360
+	}
+	c := v * s
+	x := c * (1 - float32(math.Abs(math.Mod(float64(h/60), 2)-1)))
+	m := v - c
+	var r, g, b float32
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return Color{toByte(r + m), toByte(g + m), toByte(b + m)}
+}
+
+// Hue goes the short way round the colour wheel. Greys have no hue so they
+// take the hue of the other colour rather than swinging through red.
+func lerpHSV(c1, c2 Color, pct float32) Color {
+	h1, s1, v1 := toHSV(c1)
+	h2, s2, v2 := toHSV(c2)
+	if s1 == 0 {
+		h1 = h2
+	} else if s2 == 0 {
+		h2 = h1
+	}
+	if h2-h1 > 180 {
+		h1 += 360
+	} else if h1-h2 > 180 {
+		h2 += 360
+	}
+	return fromHSV(flerp(h1, h2, pct), flerp(s1, s2, pct), flerp(v1, v2, pct))
+}
+
+func toLinear(c byte) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func fromLinear(v float64) byte {
+	if v <= 0.0031308 {
+		v *= 12.92
+	} else {
+		v = 1.055*math.Pow(v, 1/2.4) - 0.055
+	}
+	return toByte(float32(v))
+}
+
+// https://bottosson.github.io/posts/oklab/
+func toOKLab(c Color) (l, a, b float64) {
+	r, g, bl := toLinear(c.R), toLinear(c.G), toLinear(c.B)
+
+	lc := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl)
+	mc := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl)
+	sc := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl)
+
+	l = 0.2104542553*lc + 0.7936177850*mc - 0.0040720468*sc
+	a = 1.9779984951*lc - 2.4285922050*mc + 0.4505937099*sc
+	b = 0.0259040371*lc + 0.7827717662*mc - 0.8086757660*sc
+	return l, a, b
+}
+
+func fromOKLab(l, a, b float64) Color {
+	lc := l + 0.3963377774*a + 0.2158037573*b
+	mc := l - 0.1055613458*a - 0.0638541728*b
+	sc := l - 0.0894841775*a - 1.2914855480*b
+	lc, mc, sc = lc*lc*lc, mc*mc*mc, sc*sc*sc
+
+	r := 4.0767416621*lc - 3.3077115913*mc + 0.2309699292*sc
+	g := -1.2684380046*lc + 2.6097574011*mc - 0.3413193965*sc
+	bl := -0.0041960863*lc - 0.7034186147*mc + 1.7076147010*sc
+	return Color{fromLinear(r), fromLinear(g), fromLinear(bl)}
+}
+
+func lerpOKLab(c1, c2 Color, pct float32) Color {
+	l1, a1, b1 := toOKLab(c1)
+	l2, a2, b2 := toOKLab(c2)
+	t := float64(pct)
+	return fromOKLab(l1+(l2-l1)*t, a1+(a2-a1)*t, b1+(b2-b1)*t)
+}
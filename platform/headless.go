@@ -0,0 +1,198 @@
+package platform
+
+import (
+	"errors"
+	"image"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// Headless is a platform in memory. Input comes from a script, one snapshot
+// per Poll, and the run ends when the script does. The clock moves on by
+// FrameTime each Poll so a game runs the same on any machine.
+type Headless struct {
+	// Screen is what has been drawn since the last Present
+	Screen *framebuffer.Framebuffer
+	// Frames gets a copy of the screen at every Present when Record is set
+	Record bool
+	Frames []*framebuffer.Framebuffer
+	// FrameTime is how far the clock moves each Poll
+	FrameTime time.Duration
+	// Audio is everything that would have been heard, queued audio that
+	// was cleared before it played is dropped
+	Audio     []byte
+	AudioSpec AudioSpec
+
+	script     []*input.Snapshot
+	input      *input.Snapshot
+	now        time.Time
+	queued     int
+	closed     bool
+	fullscreen bool
+}
+
+var _ Platform = (*Headless)(nil)
+
+// NewHeadless makes a w by h headless platform running at 60 frames a second
+func NewHeadless(w, h int) *Headless {
+	return &Headless{
+		Screen:    framebuffer.New(w, h),
+		FrameTime: time.Second / 60,
+		input:     &input.Snapshot{},
+		now:       time.Unix(0, 0),
+	}
+}
+
+// Script adds snapshots for the coming Polls
+func (h *Headless) Script(snapshots ...*input.Snapshot) {
+	h.script = append(h.script, snapshots...)
+}
+
+// Hold scripts the same snapshot for a number of frames
+func (h *Headless) Hold(frames int, s *input.Snapshot) {
+	for i := 0; i < frames; i++ {
+		h.script = append(h.script, s)
+	}
+}
+
+func (h *Headless) Size() (int, int) {
+	return h.Screen.W, h.Screen.H
+}
+
+func (h *Headless) Poll() bool {
+	if h.closed || len(h.script) == 0 {
+		return false
+	}
+	h.input, h.script = h.script[0], h.script[1:]
+	h.now = h.now.Add(h.FrameTime)
+
+	// play what the frame would have used of the audio queue
+	played := int(h.FrameTime.Seconds()*float64(h.AudioSpec.Freq)) * h.AudioSpec.Channels * 2
+	if played > h.queued {
+		played = h.queued
+	}
+	h.queued -= played
+	return true
+}
+
+func (h *Headless) Input() *input.Snapshot {
+	return h.input
+}
+
+// Resize changes the size of the screen, as a window being resized would.
+// What was drawn is lost.
+func (h *Headless) Resize(w, height int) {
+	h.Screen = framebuffer.New(w, height)
+}
+
+// SetFullscreen only remembers the setting, the screen keeps its size
+func (h *Headless) SetFullscreen(on bool) error {
+	h.fullscreen = on
+	return nil
+}
+
+func (h *Headless) Fullscreen() bool {
+	return h.fullscreen
+}
+
+type headlessTexture struct {
+	*sprite.Sprite
+}
+
+func (t headlessTexture) Size() (w, h int) {
+	return t.W, t.H
+}
+
+func (t headlessTexture) Destroy() {}
+
+func (h *Headless) NewTexture(s *sprite.Sprite) (Texture, error) {
+	return headlessTexture{s}, nil
+}
+
+func (h *Headless) Draw(tex Texture, src, dst image.Rectangle) {
+	s := tex.(headlessTexture).Sprite
+	bounds := image.Rect(0, 0, s.W, s.H)
+	if src.Empty() {
+		src = bounds
+	}
+	opts := sprite.Options{
+		ScaleX: float32(dst.Dx()) / float32(src.Dx()),
+		ScaleY: float32(dst.Dy()) / float32(src.Dy()),
+		Filter: sprite.Bilinear,
+	}
+	// like SDL, only the part of src on the texture is drawn, where it
+	// would have been in dst
+	clipped := src.Intersect(bounds)
+	if clipped.Empty() {
+		return
+	}
+	x := float32(dst.Min.X) + float32(clipped.Min.X-src.Min.X)*opts.ScaleX
+	y := float32(dst.Min.Y) + float32(clipped.Min.Y-src.Min.Y)*opts.ScaleY
+	// a sub sprite shares the pixels, only the start and size change
+	sub := &sprite.Sprite{
+		Pixels:        s.Pixels[clipped.Min.Y*s.Pitch+clipped.Min.X*4:],
+		W:             clipped.Dx(),
+		H:             clipped.Dy(),
+		Pitch:         s.Pitch,
+		Premultiplied: s.Premultiplied,
+	}
+	sprite.Draw(h.Screen, sub, x, y, opts)
+}
+
+func (h *Headless) DrawFramebuffer(fb *framebuffer.Framebuffer) {
+	h.Screen.Blit(fb, 0, 0)
+}
+
+func (h *Headless) ReadPixels() (*framebuffer.Framebuffer, error) {
+	fb := framebuffer.New(h.Screen.W, h.Screen.H)
+	copy(fb.Pixels, h.Screen.Pixels)
+	return fb, nil
+}
+
+func (h *Headless) Present() {
+	if !h.Record {
+		return
+	}
+	frame, _ := h.ReadPixels()
+	h.Frames = append(h.Frames, frame)
+}
+
+func (h *Headless) OpenAudio(spec AudioSpec) error {
+	if spec.Freq <= 0 || spec.Channels <= 0 {
+		return errors.New("platform: bad audio spec")
+	}
+	h.AudioSpec = spec
+	return nil
+}
+
+func (h *Headless) QueueAudio(data []byte) error {
+	if h.AudioSpec.Freq == 0 {
+		return errors.New("platform: audio not open")
+	}
+	h.Audio = append(h.Audio, data...)
+	h.queued += len(data)
+	return nil
+}
+
+func (h *Headless) ClearAudio() {
+	h.Audio = h.Audio[:len(h.Audio)-h.queued]
+	h.queued = 0
+}
+
+func (h *Headless) QueuedAudio() int {
+	return h.queued
+}
+
+func (h *Headless) Now() time.Time {
+	return h.now
+}
+
+// Sleep does nothing, the clock only moves when Poll is called
+func (h *Headless) Sleep(d time.Duration) {}
+
+func (h *Headless) Close() {
+	h.closed = true
+}
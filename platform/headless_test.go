@@ -0,0 +1,138 @@
+package platform
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+func TestHeadlessDraws(t *testing.T) {
+	h := NewHeadless(32, 16)
+	h.Record = true
+	h.Hold(2, &input.Snapshot{})
+
+	red, white := palette.Color{R: 255}, palette.Color{R: 255, G: 255, B: 255}
+	fb := framebuffer.New(32, 16)
+	fb.Fill(red)
+	font.Default().Draw(fb, "hi", 1, 1, 1, font.Left, white)
+
+	s := &sprite.Sprite{Pixels: make([]byte, 2*2*4), W: 2, H: 2, Pitch: 8, Premultiplied: true}
+	for i := range s.Pixels {
+		s.Pixels[i] = 255
+	}
+	tex, err := h.NewTexture(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for h.Poll() {
+		h.DrawFramebuffer(fb)
+		h.Draw(tex, image.Rectangle{}, image.Rect(24, 8, 28, 12))
+		h.Present()
+	}
+	if len(h.Frames) != 2 {
+		t.Fatalf("recorded %d frames, want 2", len(h.Frames))
+	}
+	frame := h.Frames[1]
+	if frame.Pixel(0, 0) != red {
+		t.Errorf("background is %v", frame.Pixel(0, 0))
+	}
+	if frame.Pixel(1, 1) != white {
+		t.Error("text wasn't drawn")
+	}
+	// the texture is scaled with bilinear filtering, so only the middle is
+	// solid
+	for _, p := range [][2]int{{25, 9}, {26, 10}} {
+		if frame.Pixel(p[0], p[1]) != white {
+			t.Errorf("texture is missing %v", p)
+		}
+	}
+	if frame.Pixel(28, 12) != red {
+		t.Error("texture was drawn past its rectangle")
+	}
+	if got := h.Now().Sub(time.Unix(0, 0)); got != 2*h.FrameTime {
+		t.Errorf("clock moved %v in two frames", got)
+	}
+}
+
+func TestHeadlessAudio(t *testing.T) {
+	h := NewHeadless(1, 1)
+	if err := h.QueueAudio([]byte{0}); err == nil {
+		t.Error("queued audio before opening it")
+	}
+	if err := h.OpenAudio(AudioSpec{Freq: 100, Channels: 1}); err != nil {
+		t.Fatal(err)
+	}
+	h.FrameTime = time.Second / 10
+	h.Hold(1, &input.Snapshot{})
+	// a tenth of a second at 100 samples a second plays 10 samples, 20 bytes
+	h.QueueAudio(make([]byte, 50))
+	h.Poll()
+	if h.QueuedAudio() != 30 {
+		t.Errorf("%d bytes queued after a frame, want 30", h.QueuedAudio())
+	}
+	h.ClearAudio()
+	if len(h.Audio) != 20 {
+		t.Errorf("%d bytes heard, want 20", len(h.Audio))
+	}
+}
+
+// TestHeadlessDrawClipsSource draws parts of a texture that hang off it,
+// which only draws the part on it, where it would have gone
+func TestHeadlessDrawClipsSource(t *testing.T) {
+	// a 4 by 2 texture with every pixel a different colour, none black
+	s := &sprite.Sprite{Pixels: make([]byte, 4*2*4), W: 4, H: 2, Pitch: 16, Premultiplied: true}
+	colour := func(x, y int) palette.Color {
+		return palette.Color{R: byte(40 + x*50), G: byte(40 + y*100), B: 255}
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := colour(x, y)
+			copy(s.Pixels[y*s.Pitch+x*4:], []byte{c.R, c.G, c.B, 255})
+		}
+	}
+	var black palette.Color
+
+	tests := []struct {
+		src, dst image.Rectangle
+		// want is the colours along the top row of dst, black where
+		// nothing is drawn
+		want []palette.Color
+	}{
+		// off the right, not wrapping onto the next row
+		{image.Rect(2, 0, 6, 1), image.Rect(10, 5, 14, 6), []palette.Color{colour(2, 0), colour(3, 0), black, black}},
+		// off the left and the top
+		{image.Rect(-1, 0, 2, 2), image.Rect(10, 4, 13, 6), []palette.Color{black, colour(0, 0), colour(1, 0)}},
+		{image.Rect(-2, -1, 1, 1), image.Rect(10, 5, 13, 7), []palette.Color{black, black, black}},
+		// wholly off the texture
+		{image.Rect(4, 0, 8, 2), image.Rect(10, 5, 14, 7), []palette.Color{black, black, black, black}},
+		{image.Rect(-1<<30, 1<<30, -1<<30+2, 1<<30+2), image.Rect(10, 5, 12, 7), []palette.Color{black, black}},
+	}
+	for _, tt := range tests {
+		h := NewHeadless(32, 16)
+		tex, err := h.NewTexture(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.Draw(tex, tt.src, tt.dst)
+		for i, want := range tt.want {
+			x, y := tt.dst.Min.X+i, tt.dst.Min.Y
+			if got := h.Screen.Pixel(x, y); got != want {
+				t.Errorf("drawing %v to %v left %v at %d,%d, want %v", tt.src, tt.dst, got, x, y, want)
+			}
+		}
+		for y := 0; y < h.Screen.H; y++ {
+			for x := 0; x < h.Screen.W; x++ {
+				if !image.Pt(x, y).In(tt.dst) && h.Screen.Pixel(x, y) != black {
+					t.Errorf("drawing %v to %v drew outside it at %d,%d", tt.src, tt.dst, x, y)
+				}
+			}
+		}
+	}
+}
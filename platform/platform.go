@@ -0,0 +1,64 @@
+// Window, input, audio and clock behind one interface so games can run
+// with sdl or headless
+package platform
+
+import (
+	"image"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// AudioSpec describes queued audio, always signed 16 bit little endian
+// samples with the channels interleaved
+type AudioSpec struct {
+	Freq     int
+	Channels int
+}
+
+// Texture is a sprite uploaded for Draw
+type Texture interface {
+	Size() (w, h int)
+	Destroy()
+}
+
+// Platform is everything a game needs from the machine it runs on. It also
+// satisfies loop.Clock.
+type Platform interface {
+	// Size is the window size in pixels
+	Size() (w, h int)
+	// Poll handles waiting window events and reads the input devices. It
+	// returns false once the window has been closed.
+	Poll() bool
+	// Input is the device state read by the last Poll
+	Input() *input.Snapshot
+	// SetFullscreen fills the screen with the window or puts it back.
+	// Size follows the change.
+	SetFullscreen(on bool) error
+	Fullscreen() bool
+
+	// NewTexture uploads a sprite
+	NewTexture(s *sprite.Sprite) (Texture, error)
+	// Draw copies the src part of a texture, all of it when src is empty,
+	// scaled to fill dst
+	Draw(tex Texture, src, dst image.Rectangle)
+	// DrawFramebuffer copies a framebuffer over the whole window
+	DrawFramebuffer(fb *framebuffer.Framebuffer)
+	// ReadPixels reads back what has been drawn since the last Present
+	ReadPixels() (*framebuffer.Framebuffer, error)
+	// Present shows everything drawn since the last Present
+	Present()
+
+	OpenAudio(spec AudioSpec) error
+	QueueAudio(data []byte) error
+	ClearAudio()
+	// QueuedAudio is how many bytes are queued and not yet played
+	QueuedAudio() int
+
+	Now() time.Time
+	Sleep(d time.Duration)
+
+	Close()
+}
@@ -0,0 +1,244 @@
+// Runs games in an sdl window
+package sdlplatform
+
+import (
+	"errors"
+	"image"
+	"time"
+	"unsafe"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/input/sdlinput"
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/sprite"
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Platform is a window with a renderer, the attached controllers and an
+// audio device once OpenAudio is called
+type Platform struct {
+	Window   *sdl.Window
+	Renderer *sdl.Renderer
+
+	screen      *sdl.Texture
+	screenW     int
+	screenH     int
+	controllers []*sdl.GameController
+	input       *input.Snapshot
+	audioID     sdl.AudioDeviceID
+}
+
+var _ platform.Platform = (*Platform)(nil)
+
+// New starts sdl and opens a w by h window
+func New(title string, w, h int) (*Platform, error) {
+	err := sdl.Init(sdl.INIT_EVERYTHING)
+	if err != nil {
+		return nil, err
+	}
+	p := &Platform{}
+	p.Window, err = sdl.CreateWindow(title, sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		int32(w), int32(h), sdl.WINDOW_SHOWN)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	sdl.SetHint(sdl.HINT_RENDER_SCALE_QUALITY, "1")
+	p.Renderer, err = sdl.CreateRenderer(p.Window, -1, sdl.RENDERER_ACCELERATED)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	p.controllers = sdlinput.OpenControllers()
+	p.input = sdlinput.Snapshot(p.controllers)
+	return p, nil
+}
+
+func (p *Platform) Size() (int, int) {
+	w, h := p.Window.GetSize()
+	return int(w), int(h)
+}
+
+// SetResizable lets the player resize the window
+func (p *Platform) SetResizable(on bool) {
+	p.Window.SetResizable(on)
+}
+
+// Resize sets the size of the window, as the player dragging its edge would
+func (p *Platform) Resize(w, h int) {
+	p.Window.SetSize(int32(w), int32(h))
+}
+
+// SetFullscreen uses the desktop's resolution rather than changing it
+func (p *Platform) SetFullscreen(on bool) error {
+	var flags uint32
+	if on {
+		flags = sdl.WINDOW_FULLSCREEN_DESKTOP
+	}
+	return p.Window.SetFullscreen(flags)
+}
+
+func (p *Platform) Fullscreen() bool {
+	return p.Window.GetFlags()&sdl.WINDOW_FULLSCREEN_DESKTOP != 0
+}
+
+func (p *Platform) Poll() bool {
+	for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
+		switch event.(type) {
+		case *sdl.QuitEvent:
+			return false
+		}
+	}
+	p.input = sdlinput.Snapshot(p.controllers)
+	return true
+}
+
+func (p *Platform) Input() *input.Snapshot {
+	return p.input
+}
+
+type texture struct {
+	*sdl.Texture
+	w, h int
+}
+
+func (t texture) Size() (int, int) {
+	return t.w, t.h
+}
+
+func (t texture) Destroy() {
+	t.Texture.Destroy()
+}
+
+func (p *Platform) NewTexture(s *sprite.Sprite) (platform.Texture, error) {
+	tex, err := p.Renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STATIC, int32(s.W), int32(s.H))
+	if err != nil {
+		return nil, err
+	}
+	err = tex.Update(nil, s.Pixels, s.Pitch)
+	if err == nil {
+		var blendMode sdl.BlendMode = sdl.BLENDMODE_BLEND
+		if s.Premultiplied {
+			blendMode = sdl.ComposeCustomBlendMode(
+				sdl.BLENDFACTOR_ONE, sdl.BLENDFACTOR_ONE_MINUS_SRC_ALPHA, sdl.BLENDOPERATION_ADD,
+				sdl.BLENDFACTOR_ONE, sdl.BLENDFACTOR_ONE_MINUS_SRC_ALPHA, sdl.BLENDOPERATION_ADD)
+		}
+		err = tex.SetBlendMode(blendMode)
+	}
+	if err != nil {
+		tex.Destroy()
+		return nil, err
+	}
+	return texture{tex, s.W, s.H}, nil
+}
+
+func toRect(r image.Rectangle) *sdl.Rect {
+	if r.Empty() {
+		return nil
+	}
+	return &sdl.Rect{X: int32(r.Min.X), Y: int32(r.Min.Y), W: int32(r.Dx()), H: int32(r.Dy())}
+}
+
+func (p *Platform) Draw(tex platform.Texture, src, dst image.Rectangle) {
+	p.Renderer.Copy(tex.(texture).Texture, toRect(src), toRect(dst))
+}
+
+func (p *Platform) DrawFramebuffer(fb *framebuffer.Framebuffer) {
+	if p.screen == nil || p.screenW != fb.W || p.screenH != fb.H {
+		if p.screen != nil {
+			p.screen.Destroy()
+		}
+		var err error
+		p.screen, err = p.Renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(fb.W), int32(fb.H))
+		if err != nil {
+			p.screen = nil
+			return
+		}
+		p.screenW, p.screenH = fb.W, fb.H
+	}
+	p.screen.Update(nil, fb.Pixels, fb.Stride)
+	p.Renderer.Copy(p.screen, nil, nil)
+}
+
+func (p *Platform) ReadPixels() (*framebuffer.Framebuffer, error) {
+	w, h, err := p.Renderer.GetOutputSize()
+	if err != nil {
+		return nil, err
+	}
+	fb := framebuffer.New(int(w), int(h))
+	err = p.Renderer.ReadPixels(nil, sdl.PIXELFORMAT_ABGR8888, unsafe.Pointer(&fb.Pixels[0]), fb.Stride)
+	if err != nil {
+		return nil, err
+	}
+	// the window has no alpha of its own
+	for i := 3; i < len(fb.Pixels); i += 4 {
+		fb.Pixels[i] = 255
+	}
+	return fb, nil
+}
+
+func (p *Platform) Present() {
+	p.Renderer.Present()
+}
+
+func (p *Platform) OpenAudio(spec platform.AudioSpec) error {
+	if p.audioID != 0 {
+		sdl.CloseAudioDevice(p.audioID)
+		p.audioID = 0
+	}
+	want := &sdl.AudioSpec{Freq: int32(spec.Freq), Format: sdl.AUDIO_S16LSB, Channels: uint8(spec.Channels), Samples: 1024}
+	id, err := sdl.OpenAudioDevice("", false, want, nil, 0)
+	if err != nil {
+		return err
+	}
+	p.audioID = id
+	sdl.PauseAudioDevice(id, false)
+	return nil
+}
+
+func (p *Platform) QueueAudio(data []byte) error {
+	if p.audioID == 0 {
+		return errors.New("sdlplatform: audio not open")
+	}
+	return sdl.QueueAudio(p.audioID, data)
+}
+
+func (p *Platform) ClearAudio() {
+	if p.audioID != 0 {
+		sdl.ClearQueuedAudio(p.audioID)
+	}
+}
+
+func (p *Platform) QueuedAudio() int {
+	if p.audioID == 0 {
+		return 0
+	}
+	return int(sdl.GetQueuedAudioSize(p.audioID))
+}
+
+func (p *Platform) Now() time.Time {
+	return time.Now()
+}
+
+func (p *Platform) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// Close frees everything New made and shuts sdl down
+func (p *Platform) Close() {
+	if p.audioID != 0 {
+		sdl.CloseAudioDevice(p.audioID)
+	}
+	sdlinput.CloseControllers(p.controllers)
+	if p.screen != nil {
+		p.screen.Destroy()
+	}
+	if p.Renderer != nil {
+		p.Renderer.Destroy()
+	}
+	if p.Window != nil {
+		p.Window.Destroy()
+	}
+	sdl.Quit()
+}
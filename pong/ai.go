@@ -0,0 +1,160 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+type difficulty int
+
+const (
+	easy difficulty = iota
+	normal
+	hard
+)
+
+var difficultyNames = []string{"easy", "normal", "hard"}
+
+func parseDifficulty(s string) (difficulty, bool) {
+	for i, name := range difficultyNames {
+		if name == s {
+			return difficulty(i), true
+		}
+	}
+	return normal, false
+}
+
+// how well the computer plays
+type aiSettings struct {
+	// reaction is how long in seconds it takes to notice the ball change
+	// direction
+	reaction float32
+	// guess is the spread in pixels of where it thinks the ball will
+	// arrive, for a ball the whole width of the screen away
+	guess float32
+	// speed is a fraction of the paddle's speed
+	speed float32
+	// aim is how far from the middle of the paddle it tries to meet the
+	// ball, as a fraction of half the paddle. Off centre hits go back at
+	// an angle, and it angles them away from the other player.
+	aim float32
+}
+
+var aiLevels = []aiSettings{
+	easy:   {reaction: 0.35, guess: 160, speed: 0.7, aim: 0.2},
+	normal: {reaction: 0.25, guess: 110, speed: 0.8, aim: 0.5},
+	hard:   {reaction: 0.1, guess: 30, speed: 1, aim: 0.8},
+}
+
+// ai moves a paddle towards where it expects the ball to arrive. It is only
+// as quick as the paddle and misjudges, so it can be beaten.
+type ai struct {
+	aiSettings
+	rng *rand.Rand
+
+	target  float32
+	wait    float32
+	towards bool
+	// lastX is where the ball was, a ball that jumps back against its
+	// direction has been served again
+	lastX float32
+}
+
+func newAI(level difficulty, seed int64) *ai {
+	return &ai{aiSettings: aiLevels[level], rng: rand.New(rand.NewSource(seed)), target: getCenter().y}
+}
+
+// update moves paddle after ball, opponent is the other player's paddle
+func (ai *ai) update(paddle, opponent *paddle, ball *ball, elaspedTime float32) {
+	// after the ball turns or is served there is a moment before the ai
+	// plans again, carrying on towards the old target meanwhile
+	towards := (paddle.x-ball.x)*ball.xv > 0
+	served := (ball.x-ai.lastX)*ball.xv < 0
+	if towards != ai.towards || served {
+		ai.towards = towards
+		ai.wait = ai.reaction
+	}
+	ai.lastX = ball.x
+	if ai.wait > 0 {
+		ai.wait -= elaspedTime
+		if ai.wait <= 0 {
+			ai.plan(paddle, opponent, ball)
+		}
+	}
+
+	step := paddle.speed * ai.speed * elaspedTime
+	// reversed controls leave the computer fumbling at half speed
+	if paddle.reversed {
+		step /= 2
+	}
+	from := paddle.y
+	if d := ai.target - paddle.y; d > step {
+		paddle.y += step
+	} else if d < -step {
+		paddle.y -= step
+	} else {
+		paddle.y = ai.target
+	}
+	paddle.yv = (paddle.y - from) / elaspedTime
+}
+
+// plan picks where to go, the predicted intercept give or take a guess
+// while the ball is coming and back to the middle when it is going away.
+// It meets the ball off centre to send it towards the far side of the
+// court from the opponent.
+func (ai *ai) plan(paddle, opponent *paddle, ball *ball) {
+	if !ai.towards {
+		ai.target = getCenter().y
+		return
+	}
+	x := paddle.x - paddle.w/2 - ball.radius
+	if paddle.x < ball.x {
+		x = paddle.x + paddle.w/2 + ball.radius
+	}
+	y := predictY(ball, x)
+	far := float32(math.Abs(float64(x-ball.x))) / float32(winWidth)
+	y += float32(ai.rng.NormFloat64()) * ai.guess * far
+
+	// hitting below the middle of the paddle sends the ball down
+	down := float32(1)
+	if opponent.y > getCenter().y {
+		down = -1
+	}
+	want := y - down*ai.aim*paddle.h/2
+	// when there isn't time to get there it settles for the nearest
+	// part of the paddle that still meets the ball
+	reach := paddle.speed * ai.speed * (x - ball.x) / ball.xv
+	if abs(want-paddle.y) > reach {
+		edge := 0.8 * paddle.h / 2
+		want = clamp(y-edge, y+edge, paddle.y+clamp(-reach, reach, want-paddle.y))
+	}
+	ai.target = clamp(paddle.h/2, float32(winHeight)-paddle.h/2, want)
+}
+
+// predictY follows the ball, curving with its spin and bouncing off the top
+// and bottom, to where it will be when it reaches x. It steps the ball the
+// same way the game does, so it is exact for a ball nothing else touches.
+func predictY(b *ball, x float32) float32 {
+	if b.xv == 0 || (x-b.x)*b.xv <= 0 {
+		return b.y
+	}
+	sim := *b
+	for {
+		from := sim.pos
+		sim.fly(1.0 / tickRate)
+		if (x-sim.x)*sim.xv <= 0 {
+			t := (x - from.x) / (sim.x - from.x)
+			return from.y + (sim.y-from.y)*t
+		}
+	}
+}
+
+func clamp(min, max, v float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+const tick float32 = 1.0 / tickRate
+
+// flyTo steps a copy of b the way the game does until it reaches x, with no
+// paddles in the way
+func flyTo(b ball, x float32) float32 {
+	for {
+		from := b.pos
+		b.fly(tick)
+		if (x-b.x)*b.xv <= 0 {
+			t := (x - from.x) / (b.x - from.x)
+			return from.y + (b.y-from.y)*t
+		}
+	}
+}
+
+func TestPredictY(t *testing.T) {
+	b := ball{pos: pos{400, 300}, radius: 20, xv: 400, yv: 0}
+	if y := predictY(&b, 700); y != 300 {
+		t.Errorf("straight across arrives at %v, want 300", y)
+	}
+	if y := predictY(&b, 100); y != 300 {
+		t.Errorf("a ball going away arrives at %v, want where it is", y)
+	}
+
+	// 300 across at 45 degrees, bouncing off the bottom at 580
+	b.yv = 400
+	if y := predictY(&b, 700); math.Abs(float64(y-560)) > 1 {
+		t.Errorf("a bounce off the bottom arrives at %v, want 560", y)
+	}
+
+	b.spin = -900
+	y := predictY(&b, 700)
+	if want := flyTo(b, 700); math.Abs(float64(y-want)) > 0.01 {
+		t.Errorf("a spinning ball is predicted at %v, it arrives at %v", y, want)
+	}
+	if math.Abs(float64(y-560)) < 50 {
+		t.Errorf("spin made no difference to the prediction, %v", y)
+	}
+}
+
+func TestAim(t *testing.T) {
+	c := getCenter()
+	for _, level := range []difficulty{easy, normal, hard} {
+		computer := newAI(level, 1)
+		computer.guess = 0
+		p := paddle{pos: pos{750, c.y}, w: 20, h: paddleHeight, speed: 300}
+		b := ball{pos: c, radius: 20, xv: 400}
+
+		// the ball turning towards it makes it plan once it has reacted
+		opponent := paddle{pos: pos{50, 100}}
+		computer.update(&p, &opponent, &b, computer.reaction)
+		// the opponent is near the top, so it plays the ball downwards by
+		// meeting it with the top of the paddle
+		want := c.y - aiLevels[level].aim*paddleHeight/2
+		if math.Abs(float64(computer.target-want)) > 0.01 {
+			t.Errorf("%s aims at %v for the opponent at the top, want %v", difficultyNames[level], computer.target, want)
+		}
+
+		opponent.y = 500
+		computer.towards = false
+		computer.update(&p, &opponent, &b, computer.reaction)
+		want = c.y + aiLevels[level].aim*paddleHeight/2
+		if math.Abs(float64(computer.target-want)) > 0.01 {
+			t.Errorf("%s aims at %v for the opponent at the bottom, want %v", difficultyNames[level], computer.target, want)
+		}
+	}
+}
+
+// playMatch has the computer at level play first to five against a normal
+// computer on the left, and reports whether it won. Matches are stepped at
+// the game's tick rate without a window.
+func playMatch(level difficulty, seed int64) bool {
+	g := newGame(level, 5)
+	g.newMatch()
+	g.computer = newAI(level, seed)
+	left := newAI(normal, seed+1)
+	var wait float32
+	// an hour of play is far longer than any match
+	for i := 0; i < 3600*tickRate; i++ {
+		switch g.state {
+		case serve:
+			// the paddles move while the server takes a second
+			wait += tick
+			if wait >= 1 {
+				g.state, wait = play, 0
+			}
+		case point:
+			g.wait -= tick
+			if g.wait <= 0 {
+				g.state = serve
+			}
+		}
+		switch g.state {
+		case serve, play:
+			left.update(&g.player1, &g.player2, g.threat(&g.player1), tick)
+			g.computer.update(&g.player2, &g.player1, g.threat(&g.player2), tick)
+		case gameOver:
+			return g.winner() == 2
+		}
+		if g.state == play {
+			g.moveBalls(tick)
+		}
+	}
+	return false
+}
+
+func TestDifficultyWinRates(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays hundreds of matches")
+	}
+	const matches = 60
+	// how often each level should beat the normal computer
+	bands := []struct {
+		level    difficulty
+		min, max float64
+	}{
+		{easy, 0, 0.35},
+		{normal, 0.3, 0.7},
+		{hard, 0.65, 1},
+	}
+	for _, band := range bands {
+		wins := 0
+		for seed := int64(0); seed < matches; seed++ {
+			if playMatch(band.level, seed*2) {
+				wins++
+			}
+		}
+		rate := float64(wins) / matches
+		t.Logf("%s won %d of %d", difficultyNames[band.level], wins, matches)
+		if rate < band.min || rate > band.max {
+			t.Errorf("%s beat normal %.0f%% of the time, want %.0f%% to %.0f%%", difficultyNames[band.level], rate*100, band.min*100, band.max*100)
+		}
+	}
+}
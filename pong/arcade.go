@@ -0,0 +1,233 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// Arcade rules add power-ups that appear mid-court. The ball that runs
+// into one gives it to whoever hit that ball last.
+
+// -- enum
+type powerKind int
+
+const (
+	// bigPaddle makes the collector's paddle longer
+	bigPaddle powerKind = iota
+	// slowBall slows every ball down
+	slowBall
+	// multiBall splits the ball into three
+	multiBall
+	// stickyPaddle holds the ball on the collector's paddle a moment
+	// before it goes, so they can aim it
+	stickyPaddle
+	// reverseControls turns the other player's controls upside down
+	reverseControls
+	numPowerKinds
+)
+
+// -- end enum
+
+var powerNames = []string{"big", "slow", "multi", "sticky", "reverse"}
+
+var powerColors = []palette.Color{
+	{R: 80, G: 255, B: 80},
+	{R: 80, G: 160, B: 255},
+	{R: 255, G: 230, B: 60},
+	{R: 255, G: 150, B: 40},
+	{R: 220, G: 80, B: 255},
+}
+
+const (
+	// maxBalls and maxPowerUps are fixed so a game can be copied whole,
+	// which rollback needs
+	maxBalls    = 8
+	maxPowerUps = 3
+
+	paddleHeight float32 = 100
+	powerRadius  float32 = 18
+	// spawnTime is the seconds between power-ups
+	spawnTime float32 = 4
+	// effectTime is how long a timed power-up lasts, in seconds
+	effectTime float32 = 8
+	bigScale   float32 = 1.6
+	slowScale  float32 = 0.6
+	// stickTime is how long a sticky paddle holds the ball
+	stickTime float32 = 0.6
+	// splitAngle is how far apart in radians multi-ball sends the balls
+	splitAngle = 0.35
+)
+
+type powerUp struct {
+	pos
+	kind powerKind
+}
+
+// effects are the seconds left on a player's power-ups
+type effects struct {
+	big, sticky, reversed float32
+}
+
+// random is a small generator kept in the game by value, so a copy of the
+// game carries on with the same numbers as the original
+func (g *game) random() float32 {
+	// xorshift32, the state can't be zero
+	if g.seed == 0 {
+		g.seed = 1
+	}
+	g.seed ^= g.seed << 13
+	g.seed ^= g.seed >> 17
+	g.seed ^= g.seed << 5
+	return float32(g.seed>>8) / (1 << 24)
+}
+
+// updateArcade spawns power-ups, collects the ones balls run into and
+// counts down the effects
+func (g *game) updateArcade(elaspedTime float32) {
+	g.spawn -= elaspedTime
+	if g.spawn <= 0 {
+		g.spawn = spawnTime
+		if g.numPowerUps < maxPowerUps {
+			center := getCenter()
+			g.powerUps[g.numPowerUps] = powerUp{
+				pos:  pos{center.x + (g.random()*2-1)*150, powerRadius + g.random()*(float32(winHeight)-2*powerRadius)},
+				kind: powerKind(g.random() * float32(numPowerKinds)),
+			}
+			g.numPowerUps++
+		}
+	}
+
+	for i := 0; i < g.numPowerUps; i++ {
+		p := g.powerUps[i]
+		for j := 0; j < g.numBalls; j++ {
+			b := &g.balls[j]
+			reach := float64(b.radius + powerRadius)
+			if math.Hypot(float64(b.x-p.x), float64(b.y-p.y)) < reach {
+				g.collect(p.kind, b)
+				g.numPowerUps--
+				g.powerUps[i] = g.powerUps[g.numPowerUps]
+				i--
+				break
+			}
+		}
+	}
+
+	g.slow = countDown(g.slow, elaspedTime)
+	for i := range g.effects {
+		e := &g.effects[i]
+		e.big = countDown(e.big, elaspedTime)
+		e.sticky = countDown(e.sticky, elaspedTime)
+		e.reversed = countDown(e.reversed, elaspedTime)
+	}
+	g.applyEffects()
+}
+
+func countDown(t, elaspedTime float32) float32 {
+	if t -= elaspedTime; t < 0 {
+		return 0
+	}
+	return t
+}
+
+// collect gives a power-up to whoever last hit b, the player it's heading
+// away from
+func (g *game) collect(kind powerKind, b *ball) {
+	player := 0
+	if b.xv < 0 {
+		player = 1
+	}
+	switch kind {
+	case bigPaddle:
+		g.effects[player].big = effectTime
+	case slowBall:
+		g.slow = effectTime
+	case multiBall:
+		g.split(b)
+	case stickyPaddle:
+		g.effects[player].sticky = effectTime
+	case reverseControls:
+		g.effects[1-player].reversed = effectTime
+	}
+}
+
+// split adds two balls going either side of b
+func (g *game) split(b *ball) {
+	for _, turn := range []float64{-splitAngle, splitAngle} {
+		if g.numBalls == maxBalls {
+			return
+		}
+		sin, cos := math.Sincos(turn)
+		nb := *b
+		nb.xv = b.xv*float32(cos) - b.yv*float32(sin)
+		nb.yv = b.xv*float32(sin) + b.yv*float32(cos)
+		g.balls[g.numBalls] = nb
+		g.numBalls++
+	}
+}
+
+// applyEffects sets the paddles to match the effects on them
+func (g *game) applyEffects() {
+	for i, p := range []*paddle{&g.player1, &g.player2} {
+		p.h = paddleHeight
+		if g.effects[i].big > 0 {
+			p.h *= bigScale
+		}
+		p.reversed = g.effects[i].reversed > 0
+	}
+}
+
+// clearArcade takes every power-up off the court and ends every effect
+func (g *game) clearArcade() {
+	g.numPowerUps = 0
+	g.spawn = spawnTime
+	g.slow = 0
+	g.effects = [2]effects{}
+	g.applyEffects()
+}
+
+// stick holds b on the paddle it just bounced off if that paddle is sticky
+func (g *game) stick(b *ball) {
+	player, p := 0, &g.player1
+	if b.xv < 0 {
+		player, p = 1, &g.player2
+	}
+	if g.effects[player].sticky > 0 {
+		b.stuck = stickTime
+		b.stuckY = b.y - p.y
+	}
+}
+
+// hold keeps a stuck ball on its paddle's face until it lets go
+func (g *game) hold(b *ball, elaspedTime float32) {
+	p, face := &g.player1, g.player1.x+g.player1.w/2+b.radius
+	if b.xv < 0 {
+		p, face = &g.player2, g.player2.x-g.player2.w/2-b.radius
+	}
+	b.pos = pos{face, clamp(b.radius, float32(winHeight)-b.radius, p.y+b.stuckY)}
+	b.stuck = countDown(b.stuck, elaspedTime)
+}
+
+// drawArcade draws the power-ups on the court and the effects each player
+// has under their score
+func (g *game) drawArcade(fb *framebuffer.Framebuffer, f field) {
+	for _, p := range g.powerUps[:g.numPowerUps] {
+		x, y := f.point(p.pos)
+		fb.FillCircle(x, y, f.size(powerRadius), powerColors[p.kind])
+		f.text(fb, powerNames[p.kind][:1], pos{p.x, p.y - 5}, 2, palette.Color{})
+	}
+	for i, p := range []*paddle{&g.player1, &g.player2} {
+		e := g.effects[i]
+		timers := [numPowerKinds]float32{bigPaddle: e.big, slowBall: g.slow, stickyPaddle: e.sticky, reverseControls: e.reversed}
+		var lines string
+		for k, t := range timers {
+			if t > 0 {
+				lines += powerNames[k] + " " + strconv.Itoa(int(math.Ceil(float64(t)))) + "\n"
+			}
+		}
+		numX := flerp(p.x, getCenter().x, 0.2)
+		f.text(fb, lines, pos{numX, 70}, 2, palette.Color{R: 255, G: 255, B: 255})
+	}
+}
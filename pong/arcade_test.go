@@ -0,0 +1,229 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+// arcadeGame is a two player arcade match in play from a fixed seed, the
+// ball in the middle going straight right
+func arcadeGame() *game {
+	g := newGame(normal, 10)
+	g.mode = twoPlayerKeyboard
+	g.arcade = true
+	g.seed = 12345
+	g.newMatch()
+	g.state = play
+	b := &g.balls[0]
+	b.pos, b.xv, b.yv = getCenter(), serveSpeed, 0
+	return g
+}
+
+// steps runs n updates with keys held
+func steps(g *game, n int, keys ...input.Key) {
+	inputs := newInputs()
+	for i := 0; i < n; i++ {
+		inputs.Update(input.NewSnapshot(keys...))
+		g.update(inputs, tick)
+		inputs.Tick()
+	}
+}
+
+// inFront puts a power-up just ahead of the first ball
+func inFront(g *game, kind powerKind) {
+	b := g.balls[0]
+	g.powerUps[0] = powerUp{pos{b.x + 10, b.y}, kind}
+	g.numPowerUps = 1
+}
+
+func TestSpawn(t *testing.T) {
+	g := arcadeGame()
+	// keep the ball out of the way on a sticky paddle
+	g.effects[0].sticky = 100
+	g.balls[0].stuck = 100
+	seed := g.seed
+
+	spawnAt := int(math.Ceil(float64(spawnTime * tickRate)))
+	steps(g, spawnAt-1)
+	if g.numPowerUps != 0 {
+		t.Fatalf("%d power-ups before %v seconds", g.numPowerUps, spawnTime)
+	}
+	steps(g, 2)
+	if g.numPowerUps != 1 {
+		t.Fatalf("%d power-ups after %v seconds, want 1", g.numPowerUps, spawnTime)
+	}
+	p := g.powerUps[0]
+	if p.kind < 0 || p.kind >= numPowerKinds || abs(p.x-getCenter().x) > 150 || p.y < powerRadius || p.y > float32(winHeight)-powerRadius {
+		t.Errorf("spawned %+v", p)
+	}
+
+	// the same seed spawns the same power-up
+	again := arcadeGame()
+	again.effects[0].sticky, again.balls[0].stuck = 100, 100
+	again.seed = seed
+	steps(again, spawnAt+1)
+	if again.numPowerUps != 1 || again.powerUps[0] != p {
+		t.Errorf("the same seed spawned %+v, want %+v", again.powerUps[:again.numPowerUps], p)
+	}
+
+	// no more than maxPowerUps wait on the court
+	steps(g, 10*spawnAt)
+	if g.numPowerUps != maxPowerUps {
+		t.Errorf("%d power-ups on the court, want %d", g.numPowerUps, maxPowerUps)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		kind powerKind
+		// left is whether the ball is heading left, so player 2 hit it last
+		left  bool
+		check func(g *game) bool
+	}{
+		{bigPaddle, false, func(g *game) bool {
+			return g.player1.h == paddleHeight*bigScale && g.player2.h == paddleHeight
+		}},
+		{bigPaddle, true, func(g *game) bool {
+			return g.player2.h == paddleHeight*bigScale && g.player1.h == paddleHeight
+		}},
+		{slowBall, false, func(g *game) bool { return g.slow > 0 }},
+		{multiBall, false, func(g *game) bool { return g.numBalls == 3 }},
+		{stickyPaddle, true, func(g *game) bool { return g.effects[1].sticky > 0 && g.effects[0].sticky == 0 }},
+		{reverseControls, false, func(g *game) bool { return g.player2.reversed && !g.player1.reversed }},
+	}
+	for _, tt := range tests {
+		g := arcadeGame()
+		if tt.left {
+			g.balls[0].xv = -serveSpeed
+		}
+		inFront(g, tt.kind)
+		if tt.left {
+			g.powerUps[0].x -= 20
+		}
+		steps(g, 1)
+		if g.numPowerUps != 0 {
+			t.Errorf("%s wasn't collected", powerNames[tt.kind])
+			continue
+		}
+		if !tt.check(g) {
+			t.Errorf("%s heading left %v had the wrong effect", powerNames[tt.kind], tt.left)
+		}
+	}
+}
+
+func TestEffectsWearOff(t *testing.T) {
+	g := arcadeGame()
+	inFront(g, bigPaddle)
+	steps(g, 1)
+	// keep the ball in play while the effect runs out
+	g.effects[0].sticky, g.balls[0].stuck = 100, 100
+	steps(g, int(effectTime*tickRate)+1)
+	if g.player1.h != paddleHeight || g.effects[0].big != 0 {
+		t.Errorf("big paddle still %v high after %v seconds", g.player1.h, effectTime)
+	}
+}
+
+func TestSlowBall(t *testing.T) {
+	g := arcadeGame()
+	inFront(g, slowBall)
+	steps(g, 1)
+	x := g.balls[0].x
+	steps(g, 1)
+	if got, want := g.balls[0].x-x, serveSpeed*slowScale*tick; !near(got, want) {
+		t.Errorf("a slowed ball went %v in an update, want %v", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	g := arcadeGame()
+	inFront(g, multiBall)
+	steps(g, 1)
+	if g.numBalls != 3 {
+		t.Fatalf("%d balls after multi-ball, want 3", g.numBalls)
+	}
+	for i, want := range []float64{0, -splitAngle, splitAngle} {
+		b := g.balls[i]
+		angle := math.Atan2(float64(b.yv), float64(b.xv))
+		if math.Abs(angle-want) > 1e-4 || !near(float32(math.Hypot(float64(b.xv), float64(b.yv))), serveSpeed) {
+			t.Errorf("ball %d goes at %v rad, want %v at the same speed", i, angle, want)
+		}
+	}
+
+	// a split never goes past maxBalls
+	g.numBalls = maxBalls - 1
+	g.split(&g.balls[0])
+	if g.numBalls != maxBalls {
+		t.Errorf("split to %d balls, want %d", g.numBalls, maxBalls)
+	}
+}
+
+func TestSticky(t *testing.T) {
+	g := arcadeGame()
+	g.effects[1].sticky = effectTime
+	// a ball about to hit the middle of player 2's paddle
+	g.player2.y = 300
+	b := &g.balls[0]
+	b.pos = pos{g.player2.x - g.player2.w/2 - b.radius - 1, 300}
+	steps(g, 1)
+	if b.stuck <= 0 || b.xv >= 0 {
+		t.Fatalf("the ball didn't stick, stuck %v going %v", b.stuck, b.xv)
+	}
+	// it rides the paddle up while it's held
+	held := int(stickTime*tickRate) - 2
+	steps(g, held, input.KeyUp)
+	face := g.player2.x - g.player2.w/2 - b.radius
+	if b.stuck <= 0 || b.x != face || !near(b.y, g.player2.y) {
+		t.Errorf("after %d updates the held ball is at %v, the paddle at %v", held, b.pos, g.player2.pos)
+	}
+	steps(g, 10)
+	if b.stuck != 0 || b.x >= face {
+		t.Errorf("the ball wasn't let go, stuck %v at %v", b.stuck, b.pos)
+	}
+}
+
+func TestReverse(t *testing.T) {
+	g := arcadeGame()
+	g.effects[1].reversed = effectTime
+	// effects reach the paddles at the end of an update, as collecting
+	// one does
+	steps(g, 1)
+	y1, y2 := g.player1.y, g.player2.y
+	steps(g, 1, input.KeyUp, input.KeyW)
+	if g.player2.y <= y2 {
+		t.Errorf("player 2 pressed up with reversed controls and went from %v to %v", y2, g.player2.y)
+	}
+	if g.player1.y >= y1 {
+		t.Errorf("player 1's controls were reversed too")
+	}
+}
+
+// TestMultiBallScoring scores with balls still in play, which only ends the
+// rally once the last ball is out
+func TestMultiBallScoring(t *testing.T) {
+	g := arcadeGame()
+	g.numBalls = 3
+	// two balls leaving on the right, one in the middle
+	g.balls[1] = g.balls[0]
+	g.balls[1].pos = pos{float32(winWidth) - 1, 500}
+	g.balls[2] = g.balls[1]
+	g.balls[2].y = 550
+	g.player2.y = 100
+	steps(g, 1)
+	if g.numBalls != 1 || g.state != play {
+		t.Fatalf("%d balls in state %d after two scored, want 1 in play", g.numBalls, g.state)
+	}
+	if g.player1.score != 2 || g.player2.score != 0 {
+		t.Errorf("the score is %d-%d, want 2-0", g.player1.score, g.player2.score)
+	}
+	if g.balls[0].x >= float32(winWidth) {
+		t.Error("the ball left in play is one that scored")
+	}
+
+	g.balls[0].pos = pos{float32(winWidth) - 1, 500}
+	steps(g, 1)
+	if g.numBalls != 1 || g.state != point || g.player1.score != 3 {
+		t.Errorf("the last ball out left %d balls in state %d with player 1 on %d", g.numBalls, g.state, g.player1.score)
+	}
+}
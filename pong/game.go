@@ -0,0 +1,447 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// -- enum
+type gameState int
+
+const (
+	title gameState = iota
+	serve
+	play
+	paused
+	point
+	gameOver
+	// waiting is for a network player to join
+	waiting
+	// statistics shows the stats from the title
+	statistics
+	// standings shows how a tournament stands between matches
+	standings
+)
+
+// -- end enum
+
+// pointTime is how long in seconds the score shows before the next serve
+const pointTime float32 = 1
+
+// game is a match and the menus around it. It only needs input, so it can
+// be played by a script without a window.
+type game struct {
+	state gameState
+	mode  playMode
+	level difficulty
+	// target is the score that wins the match
+	target int
+	// arcade plays with power-ups
+	arcade bool
+
+	player1, player2 paddle
+	// balls holds numBalls balls, there is always at least one
+	balls    [maxBalls]ball
+	numBalls int
+	// hitBall is the ball that made the last hit update returned
+	hitBall  int
+	computer *ai
+	// stats are shown on the statistics screen, nil when not kept
+	stats *stats
+	// tournament is the tournament being played, if there is one, and
+	// names the players in the match
+	tournament *tournament
+	names      [2]string
+	// wait counts down the pause after a point
+	wait float32
+	// view is where the field is in the window, to find the pointer on it
+	view field
+
+	powerUps    [maxPowerUps]powerUp
+	numPowerUps int
+	// spawn counts down to the next power-up
+	spawn   float32
+	slow    float32
+	effects [2]effects
+	seed    uint32
+
+	// remote is the network player's input in online mode. In peerToPeer
+	// mode left and remote are the input for each paddle.
+	remote remoteInput
+	left   remoteInput
+}
+
+// buttons a network player sends as bits
+const (
+	remoteStart byte = 1 << iota
+	remotePause
+)
+
+// remoteInput is the latest input from a network player
+type remoteInput struct {
+	axis    float32
+	buttons byte
+	prev    byte
+}
+
+func (r *remoteInput) pressed(button byte) bool {
+	return r.buttons&button != 0 && r.prev&button == 0
+}
+
+func newGame(level difficulty, target int) *game {
+	white := palette.Color{R: 255, G: 255, B: 255}
+	return &game{
+		level:    level,
+		target:   target,
+		player1:  paddle{pos{50, 100}, 20, paddleHeight, 300, 0, 0, white, keyControl, false},
+		player2:  paddle{pos{float32(winWidth) - 50, 100}, 20, paddleHeight, 300, 0, 0, white, keyControl, false},
+		balls:    [maxBalls]ball{{pos{300, 300}, 20, serveSpeed, serveSpeed, 0, white, 0, 0}},
+		numBalls: 1,
+		computer: newAI(level, rand.Int63()),
+		view:     newField(winWidth, winHeight),
+		seed:     rand.Uint32(),
+	}
+}
+
+// newInputs binds the default controls. One player can use anything, two
+// players get half the keyboard or a controller each.
+func newInputs() *input.Map {
+	inputs := input.NewMap()
+	inputs.Bind("paddle_up", input.BindKey(input.KeyUp), input.BindKey(input.KeyW), input.BindAxis(input.AxisLeftY, -1, input.AnyPad), input.BindButton(input.ButtonDPadUp, input.AnyPad))
+	inputs.Bind("paddle_down", input.BindKey(input.KeyDown), input.BindKey(input.KeyS), input.BindAxis(input.AxisLeftY, 1, input.AnyPad), input.BindButton(input.ButtonDPadDown, input.AnyPad))
+	inputs.Bind("menu_left", input.BindKey(input.KeyLeft), input.BindKey(input.KeyA), input.BindButton(input.ButtonDPadLeft, input.AnyPad))
+	inputs.Bind("menu_right", input.BindKey(input.KeyRight), input.BindKey(input.KeyD), input.BindButton(input.ButtonDPadRight, input.AnyPad))
+	inputs.Bind("p1_up", input.BindKey(input.KeyW))
+	inputs.Bind("p1_down", input.BindKey(input.KeyS))
+	inputs.Bind("p2_up", input.BindKey(input.KeyUp))
+	inputs.Bind("p2_down", input.BindKey(input.KeyDown))
+	for pad := 0; pad < 2; pad++ {
+		name := "pad" + strconv.Itoa(pad+1)
+		inputs.Bind(name+"_up", input.BindAxis(input.AxisLeftY, -1, pad), input.BindButton(input.ButtonDPadUp, pad))
+		inputs.Bind(name+"_down", input.BindAxis(input.AxisLeftY, 1, pad), input.BindButton(input.ButtonDPadDown, pad))
+	}
+	inputs.Bind("start", input.BindKey(input.KeySpace), input.BindButton(input.ButtonStart, input.AnyPad))
+	inputs.Bind("pause", input.BindKey(input.KeyP), input.BindKey(input.KeyEscape), input.BindButton(input.ButtonStart, input.AnyPad))
+	inputs.Bind("back", input.BindKey(input.KeyQ), input.BindButton(input.ButtonBack, input.AnyPad))
+	inputs.Bind("fullscreen", input.BindKey(input.KeyF11))
+	inputs.Bind("theme", input.BindKey(input.KeyT))
+	inputs.Bind("rules", input.BindKey(input.KeyR))
+	inputs.Bind("stats", input.BindKey(input.KeyTab))
+	inputs.Bind("p1_control", input.BindKey(input.KeyC))
+	inputs.Bind("p2_control", input.BindKey(input.KeyV))
+	return inputs
+}
+
+// pressed reports an action from either the local or the network player
+func (g *game) pressed(inputs *input.Map, action string) bool {
+	button := remoteStart
+	if action == "pause" {
+		button = remotePause
+	}
+	if g.mode == peerToPeer {
+		return g.left.pressed(button) || g.remote.pressed(button)
+	}
+	return inputs.Pressed(action) || g.mode == online && g.remote.pressed(button)
+}
+
+func (g *game) networked() bool {
+	return g.mode == online || g.mode == peerToPeer
+}
+
+// update moves the game on by elaspedTime and returns what the ball hit
+func (g *game) update(inputs *input.Map, elaspedTime float32) hit {
+	h := g.step(inputs, elaspedTime)
+	g.remote.prev = g.remote.buttons
+	g.left.prev = g.left.buttons
+	return h
+}
+
+func (g *game) step(inputs *input.Map, elaspedTime float32) hit {
+	// an online game has no menu to go back to
+	back := !g.networked() && inputs.Pressed("back")
+	switch g.state {
+	case title:
+		g.updateTitle(inputs)
+	case serve:
+		g.movePaddles(inputs, elaspedTime)
+		if back {
+			g.state = g.home()
+		} else if g.pressed(inputs, "start") {
+			g.state = play
+		}
+	case play:
+		if g.pressed(inputs, "pause") {
+			g.state = paused
+			return hitNothing
+		}
+		g.movePaddles(inputs, elaspedTime)
+		return g.moveBalls(elaspedTime)
+	case paused:
+		if back {
+			g.state = g.home()
+		} else if g.pressed(inputs, "pause") || g.pressed(inputs, "start") {
+			g.state = play
+		}
+	case point:
+		g.wait -= elaspedTime
+		if g.wait <= 0 {
+			g.state = serve
+		}
+	case statistics:
+		if back || inputs.Pressed("start") || inputs.Pressed("stats") {
+			g.state = title
+		}
+	case standings:
+		switch {
+		case back || inputs.Pressed("start") && g.tournament.champion() != noPlayer:
+			// the bracket is saved, so leaving only puts the tournament
+			// aside until it's resumed
+			g.tournament = nil
+			g.state = title
+		case inputs.Pressed("start"):
+			g.startNext()
+		case inputs.Pressed("menu_left") || inputs.Pressed("menu_right"):
+			switch g.mode {
+			case twoPlayerKeyboard:
+				g.mode = twoPlayerPads
+			case twoPlayerPads:
+				g.mode = twoPlayerKeyboard
+			}
+		}
+	case gameOver:
+		if g.pressed(inputs, "start") {
+			if g.networked() {
+				g.newMatch()
+			} else {
+				g.state = g.home()
+			}
+		}
+	}
+	return hitNothing
+}
+
+// home is where the game goes between matches, the title or a
+// tournament's standings
+func (g *game) home() gameState {
+	if g.tournament != nil {
+		return standings
+	}
+	return title
+}
+
+func (g *game) updateTitle(inputs *input.Map) {
+	n := playMode(len(modeNames))
+	if inputs.Pressed("paddle_up") {
+		g.mode = (g.mode + n - 1) % n
+	}
+	if inputs.Pressed("paddle_down") {
+		g.mode = (g.mode + 1) % n
+	}
+	if g.mode == onePlayer && inputs.Pressed("menu_left") && g.level > easy {
+		g.level--
+	}
+	if g.mode == onePlayer && inputs.Pressed("menu_right") && g.level < hard {
+		g.level++
+	}
+	controls := control(len(controlNames))
+	if inputs.Pressed("p1_control") {
+		g.player1.control = (g.player1.control + 1) % controls
+	}
+	if g.mode != onePlayer && inputs.Pressed("p2_control") {
+		g.player2.control = (g.player2.control + 1) % controls
+	}
+	if inputs.Pressed("rules") {
+		g.arcade = !g.arcade
+	}
+	if inputs.Pressed("stats") && g.stats != nil {
+		g.state = statistics
+		return
+	}
+	if inputs.Pressed("start") {
+		g.newMatch()
+	}
+}
+
+// newMatch clears the scores ready to serve
+func (g *game) newMatch() {
+	g.player1.score = 0
+	g.player2.score = 0
+	g.numBalls = 1
+	g.balls[0].serve(1)
+	// the match's numbers come from the game's own generator, so both
+	// sides of a rollback game agree on them
+	g.random()
+	g.computer = newAI(g.level, int64(g.seed))
+	g.clearArcade()
+	g.state = serve
+}
+
+// moveBalls moves every ball on, dropping any that score while others are
+// still in play. It returns the most important thing a ball hit.
+func (g *game) moveBalls(elaspedTime float32) hit {
+	if g.arcade {
+		g.updateArcade(elaspedTime)
+	}
+	if g.slow > 0 {
+		elaspedTime *= slowScale
+	}
+	h := hitNothing
+	for i := 0; i < g.numBalls; i++ {
+		b := &g.balls[i]
+		if b.stuck > 0 {
+			g.hold(b, elaspedTime)
+			continue
+		}
+		bh := b.update(&g.player1, &g.player2, elaspedTime)
+		if bh == hitPaddle {
+			g.stick(b)
+		}
+		if bh > h {
+			h, g.hitBall = bh, i
+		}
+		if bh == hitGoal && g.numBalls > 1 {
+			g.numBalls--
+			g.balls[i] = g.balls[g.numBalls]
+			i--
+			g.hitBall = 0
+		} else if bh == hitGoal {
+			g.state = point
+			g.wait = pointTime
+			g.clearArcade()
+		}
+	}
+	if g.winner() != 0 {
+		g.state = gameOver
+	}
+	return h
+}
+
+// threat is the ball the computer should watch, the first to reach its
+// paddle of those coming towards it
+func (g *game) threat(p *paddle) *ball {
+	best, soonest := &g.balls[0], float32(math.Inf(1))
+	for i := 0; i < g.numBalls; i++ {
+		b := &g.balls[i]
+		if (p.x-b.x)*b.xv <= 0 {
+			continue
+		}
+		if t := (p.x - b.x) / b.xv; t < soonest {
+			best, soonest = b, t
+		}
+	}
+	return best
+}
+
+func (g *game) movePaddles(inputs *input.Map, elaspedTime float32) {
+	if g.mode == peerToPeer {
+		g.player1.move(g.left.axis, elaspedTime)
+		g.player2.move(g.remote.axis, elaspedTime)
+		return
+	}
+	left, right := g.mode.controls()
+	g.player1.update(inputs, left, g.view, elaspedTime)
+	if g.mode == online {
+		g.player2.move(g.remote.axis, elaspedTime)
+	} else if right[0] == "" {
+		g.computer.update(&g.player2, &g.player1, g.threat(&g.player2), elaspedTime)
+	} else {
+		g.player2.update(inputs, right, g.view, elaspedTime)
+	}
+}
+
+// winner is 1 or 2 once a player reaches the target score, otherwise 0
+func (g *game) winner() int {
+	if g.player1.score >= g.target {
+		return 1
+	}
+	if g.player2.score >= g.target {
+		return 2
+	}
+	return 0
+}
+
+// draw shows the game over a background the size of the field, scaled to
+// fit the window with black bars either side, in theme t
+func (g *game) draw(fb, background *framebuffer.Framebuffer, f field, t *theme) {
+	if f.w < fb.W || f.h < fb.H {
+		fb.Clear()
+	}
+	fb.Blit(background, f.x, f.y)
+	g.player1.draw(fb, f, t)
+	g.player2.draw(fb, f, t)
+	switch g.state {
+	case serve, play, paused, point:
+		for i := 0; i < g.numBalls; i++ {
+			g.balls[i].draw(fb, f, t)
+		}
+	}
+	if g.arcade {
+		g.drawArcade(fb, f)
+	}
+	g.drawText(fb, f)
+}
+
+// drawText draws the words for the current state over the play field
+func (g *game) drawText(fb *framebuffer.Framebuffer, f field) {
+	white := palette.Color{R: 255, G: 255, B: 255}
+	center := getCenter()
+	at := func(y float32) pos {
+		return pos{center.x, y}
+	}
+	switch g.state {
+	case title:
+		f.text(fb, "PONG", at(130), 16, white)
+		for i, name := range modeNames {
+			if playMode(i) == onePlayer {
+				name += ": " + difficultyNames[g.level]
+			}
+			if playMode(i) == g.mode {
+				name = "> " + name + " <"
+			}
+			f.text(fb, name, at(center.y+float32((i-1)*40)), 5, white)
+		}
+		f.text(fb, "first to "+strconv.Itoa(g.target), at(float32(winHeight-100)), 4, white)
+		rules := "r: classic rules"
+		if g.arcade {
+			rules = "r: arcade rules, with power-ups"
+		}
+		f.text(fb, rules, at(float32(winHeight-70)), 2, white)
+		controls := "c: player 1 uses " + controlNames[g.player1.control]
+		if g.mode != onePlayer {
+			controls += ", v: player 2 uses " + controlNames[g.player2.control]
+		}
+		f.text(fb, controls, at(float32(winHeight-50)), 2, white)
+		if g.stats != nil {
+			f.text(fb, "tab: statistics", at(float32(winHeight-30)), 2, white)
+		}
+	case serve:
+		if g.tournament != nil {
+			f.text(fb, g.names[0]+" vs "+g.names[1], at(float32(winHeight-130)), 4, white)
+		}
+		f.text(fb, "press space to serve", at(float32(winHeight-80)), 4, white)
+	case paused:
+		f.text(fb, "paused", at(center.y-40), 10, white)
+		f.text(fb, "p to play, q to quit", at(center.y+40), 4, white)
+	case waiting:
+		f.text(fb, "waiting for a player", center, 6, white)
+	case statistics:
+		g.drawStats(fb, f)
+	case standings:
+		g.drawStandings(fb, f)
+	case gameOver:
+		winner := "player " + strconv.Itoa(g.winner()) + " wins"
+		if g.winner() == 2 && g.mode == onePlayer {
+			winner = "computer wins"
+		}
+		if g.tournament != nil {
+			winner = g.names[g.winner()-1] + " wins"
+		}
+		f.text(fb, winner, at(center.y-40), 8, white)
+		f.text(fb, "press space", at(center.y+40), 4, white)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+// transition is a change of state and the update it happened on
+type transition struct {
+	from, to gameState
+	update   int
+}
+
+// stepper plays a game an update at a time, drawing every update into a
+// framebuffer the size of the field
+type stepper struct {
+	g           *game
+	inputs      *input.Map
+	fb, back    *framebuffer.Framebuffer
+	theme       *theme
+	updates     int
+	transitions []transition
+}
+
+func newStepper(g *game) *stepper {
+	return &stepper{
+		g:      g,
+		inputs: newInputs(),
+		fb:     framebuffer.New(winWidth, winHeight),
+		back:   framebuffer.New(winWidth, winHeight),
+		theme:  classicTheme(),
+	}
+}
+
+// hold runs n updates with keys held down
+func (s *stepper) hold(n int, keys ...input.Key) {
+	for i := 0; i < n; i++ {
+		s.inputs.Update(input.NewSnapshot(keys...))
+		from := s.g.state
+		s.g.update(s.inputs, tick)
+		s.inputs.Tick()
+		s.g.draw(s.fb, s.back, newField(winWidth, winHeight), s.theme)
+		s.updates++
+		if s.g.state != from {
+			s.transitions = append(s.transitions, transition{from, s.g.state, s.updates})
+		}
+	}
+}
+
+// press taps a key for one update and lets go for the next
+func (s *stepper) press(k input.Key) {
+	s.hold(1, k)
+	s.hold(1)
+}
+
+// score puts the ball just past the computer's paddle and plays on until
+// it goes out
+func (s *stepper) score() {
+	b := &s.g.balls[0]
+	b.pos, b.xv, b.yv = pos{float32(winWidth) - 1, 300}, serveSpeed, 0
+	s.g.player2.y = 100
+	s.hold(1)
+}
+
+func TestStateOrder(t *testing.T) {
+	s := newStepper(newGame(normal, 2))
+	s.hold(10)
+	s.press(input.KeySpace)
+	s.hold(10)
+	s.press(input.KeySpace)
+	s.press(input.KeyP)
+	s.hold(30)
+	s.press(input.KeyP)
+	s.score()
+	s.hold(int(pointTime*tickRate) + 5)
+	s.press(input.KeySpace)
+	s.score()
+	s.hold(10)
+	s.press(input.KeySpace)
+
+	// the score shows for pointTime, counted down a float32 tick at a
+	// time. 120 ticks of 1/120 leave a sliver of the second over, so the
+	// serve comes on the 121st update after the point.
+	want := []transition{
+		{title, serve, 11},
+		{serve, play, 23},
+		{play, paused, 25},
+		{paused, play, 57},
+		{play, point, 59},
+		{point, serve, 180},
+		{serve, play, 185},
+		{play, gameOver, 187},
+		{gameOver, title, 198},
+	}
+	if len(s.transitions) != len(want) {
+		t.Fatalf("went through %v, want %v", s.transitions, want)
+	}
+	for i := range want {
+		if s.transitions[i] != want[i] {
+			t.Errorf("transition %d is %v, want %v", i, s.transitions[i], want[i])
+		}
+	}
+	if s.g.player1.score != 2 {
+		t.Errorf("player 1 scored %d, want 2", s.g.player1.score)
+	}
+}
+
+func TestPauseHoldsTheBall(t *testing.T) {
+	s := newStepper(newGame(normal, 2))
+	s.press(input.KeySpace)
+	s.press(input.KeySpace)
+	s.hold(5)
+	playing := string(s.fb.Pixels)
+
+	s.press(input.KeyP)
+	ball := s.g.balls[0].pos
+	s.hold(120)
+	if s.g.state != paused || s.g.balls[0].pos != ball {
+		t.Errorf("the ball moved from %v to %v while paused", ball, s.g.balls[0].pos)
+	}
+	if string(s.fb.Pixels) == playing {
+		t.Error("the paused screen looks the same as play")
+	}
+	s.press(input.KeyP)
+	if s.g.state != play || s.g.balls[0].pos == ball {
+		t.Error("play didn't carry on after the pause")
+	}
+}
+
+func TestBackToTitle(t *testing.T) {
+	s := newStepper(newGame(normal, 2))
+	s.press(input.KeySpace)
+	s.press(input.KeyQ)
+	if s.g.state != title {
+		t.Errorf("back while serving went to %d, want the title", s.g.state)
+	}
+
+	s.press(input.KeySpace)
+	s.press(input.KeySpace)
+	s.press(input.KeyP)
+	s.press(input.KeyQ)
+	if s.g.state != title {
+		t.Errorf("back while paused went to %d, want the title", s.g.state)
+	}
+	// there is no leaving a point part way through
+	s.press(input.KeySpace)
+	s.press(input.KeySpace)
+	s.score()
+	s.press(input.KeyQ)
+	if s.g.state != point {
+		t.Errorf("back after a point went to %d", s.g.state)
+	}
+}
+
+// padSnapshot holds one controller's d-pad up or down, pad 1 is the first
+func padSnapshot(pad int, b input.Button) *input.Snapshot {
+	s := input.NewSnapshot()
+	s.Pads = make([]input.Pad, 2)
+	s.Pads[pad-1].Buttons = 1 << uint(b)
+	return s
+}
+
+// TestOwnControls checks each paddle in a two player match moves only
+// for its own player's bindings
+func TestOwnControls(t *testing.T) {
+	keys := func(k input.Key) *input.Snapshot { return input.NewSnapshot(k) }
+	tests := []struct {
+		name string
+		mode playMode
+		in   *input.Snapshot
+		// which way each paddle should go, -1 up, 1 down
+		p1, p2 float32
+	}{
+		{"w", twoPlayerKeyboard, keys(input.KeyW), -1, 0},
+		{"s", twoPlayerKeyboard, keys(input.KeyS), 1, 0},
+		{"up", twoPlayerKeyboard, keys(input.KeyUp), 0, -1},
+		{"down", twoPlayerKeyboard, keys(input.KeyDown), 0, 1},
+		{"pad 1 on the keyboard", twoPlayerKeyboard, padSnapshot(1, input.ButtonDPadUp), 0, 0},
+		{"pad 1 up", twoPlayerPads, padSnapshot(1, input.ButtonDPadUp), -1, 0},
+		{"pad 1 down", twoPlayerPads, padSnapshot(1, input.ButtonDPadDown), 1, 0},
+		{"pad 2 up", twoPlayerPads, padSnapshot(2, input.ButtonDPadUp), 0, -1},
+		{"pad 2 down", twoPlayerPads, padSnapshot(2, input.ButtonDPadDown), 0, 1},
+		{"w with pads", twoPlayerPads, keys(input.KeyW), 0, 0},
+		{"up with pads", twoPlayerPads, keys(input.KeyUp), 0, 0},
+	}
+	for _, tt := range tests {
+		g := newGame(normal, 3)
+		g.mode, g.state = tt.mode, serve
+		inputs := newInputs()
+		y1, y2 := g.player1.y, g.player2.y
+		inputs.Update(tt.in)
+		g.update(inputs, tick)
+		d1, d2 := g.player1.y-y1, g.player2.y-y2
+		if sign(d1) != tt.p1 || sign(d2) != tt.p2 {
+			t.Errorf("%s moved player 1 by %v and player 2 by %v", tt.name, d1, d2)
+		}
+	}
+}
+
+func sign(v float32) float32 {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
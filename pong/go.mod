@@ -2,7 +2,6 @@ module github.com/stephen-mahon/games-with-go/pong
 
 go 1.16
 
-require (
-	github.com/stephen-mahon/games-with-go v0.0.0-20210626031858-b158233d2a06 // indirect
-	github.com/veandco/go-sdl2 v0.4.8
-)
+require github.com/stephen-mahon/games-with-go v0.0.0-00010101000000-000000000000
+
+replace github.com/stephen-mahon/games-with-go => ../
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/netsim"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// Online play over udp, or tcp where udp can't get through. The host runs
+// the game and sends the whole of it every update, the joining player
+// sends their input and draws what the host sent a little in the past, so
+// there are usually snapshots either side of the moment drawn to blend
+// between. Over udp a lost packet is covered by the next one, nothing is
+// resent.
+
+const (
+	msgInput    byte = 'I'
+	msgSnapshot byte = 'S'
+
+	// tickRate is updates a second, snapshots are sent every update
+	tickRate = 120
+	// netTimeout is how long without hearing from the other side before
+	// they count as gone
+	netTimeout = 3 * time.Second
+	// interpDelay is how far behind the host the joining player draws
+	interpDelay = 100 * time.Millisecond
+	// keep about half a second of snapshots to blend between
+	maxSnapshots = 64
+)
+
+// netConfig makes a network worse than it is for trying things over
+// loopback
+type netConfig struct {
+	// network is udp or tcp, empty is udp
+	network         string
+	latency, jitter time.Duration
+	loss            float64
+	// seed picks which packets are lost and delayed, 0 takes one from the
+	// clock
+	seed int64
+}
+
+func listenUDP(addr string, cfg netConfig) (net.PacketConn, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.simulate(conn), nil
+}
+
+// simulate puts the network cfg asks for on top of conn
+func (cfg netConfig) simulate(conn net.PacketConn) net.PacketConn {
+	if cfg.latency > 0 || cfg.jitter > 0 || cfg.loss > 0 {
+		seed := cfg.seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		return netsim.Wrap(conn, cfg.latency, cfg.jitter, cfg.loss, seed)
+	}
+	return conn
+}
+
+type packet struct {
+	data []byte
+	addr net.Addr
+}
+
+// peer reads packets on a goroutine so the game loop never waits on the
+// network
+type peer struct {
+	conn    net.PacketConn
+	packets chan packet
+	// err is why reading stopped, set before packets is closed
+	err    error
+	closed bool
+}
+
+func newPeer(conn net.PacketConn) *peer {
+	p := &peer{conn: conn, packets: make(chan packet, 256)}
+	go p.read()
+	return p
+}
+
+func (p *peer) read() {
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := p.conn.ReadFrom(buf)
+		if err != nil {
+			p.err = err
+			close(p.packets)
+			return
+		}
+		select {
+		case p.packets <- packet{append([]byte(nil), buf[:n]...), addr}:
+		default:
+			// the game has fallen behind, drop it as a network would
+		}
+	}
+}
+
+// poll returns the next packet if one has arrived
+func (p *peer) poll() (packet, bool) {
+	select {
+	case pk, ok := <-p.packets:
+		if !ok {
+			p.closed = true
+		}
+		return pk, ok
+	default:
+		return packet{}, false
+	}
+}
+
+// Err is why the connection stopped reading, once poll has found it has
+func (p *peer) Err() error {
+	if !p.closed {
+		return nil
+	}
+	return p.err
+}
+
+func (p *peer) Close() error {
+	return p.conn.Close()
+}
+
+// snapshot is everything the joining player needs to draw the game
+type snapshot struct {
+	tick           uint32
+	state          gameState
+	p1, p2         float32
+	ball           pos
+	score1, score2 uint8
+	target         uint8
+	// events counts sounds, lastHit is the latest one
+	events  uint8
+	lastHit hit
+}
+
+const snapshotSize = 1 + 4 + 1 + 4*4 + 5
+
+func (s *snapshot) encode(b []byte) []byte {
+	if cap(b) < snapshotSize {
+		b = make([]byte, snapshotSize)
+	}
+	b = b[:snapshotSize]
+	b[0] = msgSnapshot
+	binary.LittleEndian.PutUint32(b[1:], s.tick)
+	b[5] = byte(s.state)
+	for i, f := range []float32{s.p1, s.p2, s.ball.x, s.ball.y} {
+		binary.LittleEndian.PutUint32(b[6+i*4:], math.Float32bits(f))
+	}
+	copy(b[22:], []byte{s.score1, s.score2, s.target, s.events, byte(s.lastHit)})
+	return b
+}
+
+func decodeSnapshot(b []byte) (snapshot, bool) {
+	if len(b) != snapshotSize || b[0] != msgSnapshot {
+		return snapshot{}, false
+	}
+	f := func(i int) float32 {
+		return math.Float32frombits(binary.LittleEndian.Uint32(b[6+i*4:]))
+	}
+	return snapshot{
+		tick:    binary.LittleEndian.Uint32(b[1:]),
+		state:   gameState(b[5]),
+		p1:      f(0),
+		p2:      f(1),
+		ball:    pos{f(2), f(3)},
+		score1:  b[22],
+		score2:  b[23],
+		target:  b[24],
+		events:  b[25],
+		lastHit: hit(b[26]),
+	}, true
+}
+
+const inputSize = 1 + 4 + 4 + 1
+
+func encodeInput(b []byte, seq uint32, axis float32, buttons byte) []byte {
+	if cap(b) < inputSize {
+		b = make([]byte, inputSize)
+	}
+	b = b[:inputSize]
+	b[0] = msgInput
+	binary.LittleEndian.PutUint32(b[1:], seq)
+	binary.LittleEndian.PutUint32(b[5:], math.Float32bits(axis))
+	b[9] = buttons
+	return b
+}
+
+func decodeInput(b []byte) (seq uint32, axis float32, buttons byte, ok bool) {
+	if len(b) != inputSize || b[0] != msgInput {
+		return 0, 0, 0, false
+	}
+	seq = binary.LittleEndian.Uint32(b[1:])
+	axis = math.Float32frombits(binary.LittleEndian.Uint32(b[5:]))
+	if axis != axis || axis < -1 || axis > 1 {
+		return 0, 0, 0, false
+	}
+	return seq, axis, b[9], true
+}
+
+// server is the host's end, it plays the right paddle for the first player
+// to send it input
+type server struct {
+	*peer
+	client    net.Addr
+	lastHeard time.Time
+	lastSeq   uint32
+
+	tick    uint32
+	events  uint8
+	lastHit hit
+	buf     []byte
+}
+
+func host(addr string, cfg netConfig) (*server, error) {
+	if cfg.network == "tcp" {
+		conn, err := listenTCP(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &server{peer: newPeer(cfg.simulate(conn))}, nil
+	}
+	conn, err := listenUDP(addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &server{peer: newPeer(conn)}, nil
+}
+
+// receive takes the latest input from the joined player, and starts or
+// stops the match as players join and leave
+func (s *server) receive(g *game, now time.Time) {
+	for {
+		pk, ok := s.poll()
+		if !ok {
+			break
+		}
+		seq, axis, buttons, ok := decodeInput(pk.data)
+		if !ok {
+			continue
+		}
+		if s.client == nil {
+			s.client = pk.addr
+			s.lastSeq = 0
+			g.remote = remoteInput{}
+			g.newMatch()
+		}
+		if pk.addr.String() != s.client.String() {
+			continue
+		}
+		s.lastHeard = now
+		// packets can arrive out of order, only newer input counts
+		if seq <= s.lastSeq {
+			continue
+		}
+		s.lastSeq = seq
+		g.remote.axis = axis
+		g.remote.buttons = buttons
+	}
+	if s.client != nil && now.Sub(s.lastHeard) > netTimeout {
+		s.client = nil
+		g.remote = remoteInput{}
+		g.state = waiting
+	}
+}
+
+// send gives the joined player the game after an update where the ball
+// hit h
+func (s *server) send(g *game, h hit) error {
+	s.tick++
+	if h != hitNothing {
+		s.events++
+		s.lastHit = h
+	}
+	if s.client == nil {
+		return nil
+	}
+	// there is only ever one ball online, arcade rules with multi-ball
+	// can only be picked from the title and online play never goes there
+	snap := snapshot{
+		tick:    s.tick,
+		state:   g.state,
+		p1:      g.player1.y,
+		p2:      g.player2.y,
+		ball:    g.balls[0].pos,
+		score1:  uint8(g.player1.score),
+		score2:  uint8(g.player2.score),
+		target:  uint8(g.target),
+		events:  s.events,
+		lastHit: s.lastHit,
+	}
+	s.buf = snap.encode(s.buf)
+	_, err := s.conn.WriteTo(s.buf, s.client)
+	return err
+}
+
+// client is the joining player's end
+type client struct {
+	*peer
+	server    net.Addr
+	seq       uint32
+	buf       []byte
+	snaps     []snapshot
+	lastHeard time.Time
+	// renderTime is the host's time being drawn, in seconds
+	renderTime float64
+}
+
+func join(addr string, cfg netConfig) (*client, error) {
+	if cfg.network == "tcp" {
+		conn, err := dialTCP(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &client{peer: newPeer(cfg.simulate(conn)), server: conn.remote()}, nil
+	}
+	server, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := listenUDP(":0", cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &client{peer: newPeer(conn), server: server}, nil
+}
+
+func (c *client) sendInput(axis float32, buttons byte) error {
+	c.seq++
+	c.buf = encodeInput(c.buf, c.seq, axis, buttons)
+	_, err := c.conn.WriteTo(c.buf, c.server)
+	return err
+}
+
+// receive keeps snapshots from the host in tick order
+func (c *client) receive(now time.Time) {
+	for {
+		pk, ok := c.poll()
+		if !ok {
+			return
+		}
+		snap, ok := decodeSnapshot(pk.data)
+		if !ok || pk.addr.String() != c.server.String() {
+			continue
+		}
+		c.lastHeard = now
+		i := len(c.snaps)
+		for i > 0 && c.snaps[i-1].tick >= snap.tick {
+			i--
+		}
+		if i < len(c.snaps) && c.snaps[i].tick == snap.tick {
+			continue
+		}
+		c.snaps = append(c.snaps, snapshot{})
+		copy(c.snaps[i+1:], c.snaps[i:])
+		c.snaps[i] = snap
+		if len(c.snaps) > maxSnapshots {
+			c.snaps = append(c.snaps[:0], c.snaps[len(c.snaps)-maxSnapshots:]...)
+		}
+	}
+}
+
+func (c *client) connected(now time.Time) bool {
+	return len(c.snaps) > 0 && now.Sub(c.lastHeard) < netTimeout
+}
+
+// view moves the drawn time on by elaspedTime and blends the snapshots
+// either side of it. The drawn time is eased towards interpDelay behind
+// the newest snapshot, so the game plays smoothly through jitter.
+func (c *client) view(elaspedTime float32) (snapshot, bool) {
+	if len(c.snaps) == 0 {
+		return snapshot{}, false
+	}
+	newest := c.snaps[len(c.snaps)-1]
+	want := float64(newest.tick)/tickRate - interpDelay.Seconds()
+	c.renderTime += float64(elaspedTime)
+	if math.Abs(c.renderTime-want) > 0.25 {
+		c.renderTime = want
+	} else {
+		c.renderTime += (want - c.renderTime) * 0.05
+	}
+
+	tick := c.renderTime * tickRate
+	if tick <= float64(c.snaps[0].tick) {
+		return c.snaps[0], true
+	}
+	for i := 1; i < len(c.snaps); i++ {
+		a, b := c.snaps[i-1], c.snaps[i]
+		if tick < float64(b.tick) {
+			pct := float32((tick - float64(a.tick)) / float64(b.tick-a.tick))
+			view := b
+			view.p1 = a.p1 + (b.p1-a.p1)*pct
+			view.p2 = a.p2 + (b.p2-a.p2)*pct
+			view.ball = lerpPos(a.ball, b.ball, pct)
+			return view, true
+		}
+	}
+	return newest, true
+}
+
+// runClient plays the right paddle of a game hosted elsewhere until the
+// platform's window closes. It only takes keys and controllers, the paddle
+// is drawn too far in the past to chase a pointer with.
+func runClient(plat platform.Platform, c *client, themeName string) error {
+	inputs := newInputs()
+	err := inputs.LoadFile("controls.txt")
+	if err != nil {
+		return err
+	}
+	err = plat.OpenAudio(platform.AudioSpec{Freq: 48000, Channels: 2})
+	if err != nil {
+		return err
+	}
+	sounds := newSounds(48000)
+	screen, err := newScreen(plat, themeName)
+	if err != nil {
+		return err
+	}
+	defer screen.close()
+
+	g := newGame(normal, 1)
+	g.mode = online
+	g.state = waiting
+	var events uint8
+	var netErr error
+
+	update := func(elaspedTime float32) {
+		screen.update(plat, inputs)
+		now := plat.Now()
+		c.receive(now)
+		err := c.sendInput(inputs.Axis("paddle_up", "paddle_down"), remoteButtons(inputs))
+		if err != nil && netErr == nil {
+			netErr = err
+		}
+
+		view, ok := c.view(elaspedTime)
+		if ok && c.connected(now) {
+			g.state = view.state
+			g.player1.y, g.player2.y = view.p1, view.p2
+			g.balls[0].pos = view.ball
+			g.player1.score, g.player2.score = int(view.score1), int(view.score2)
+			g.target = int(view.target)
+			if view.events != events {
+				events = view.events
+				sounds.play(view.lastHit, &g.balls[0])
+			}
+		} else {
+			g.state = waiting
+		}
+		sounds.mixer.Pump(plat, time.Second/20)
+		inputs.Tick()
+	}
+
+	render := func(alpha float32) {
+		screen.show(plat, g)
+	}
+
+	gameLoop, err := newLoop(plat)
+	if err != nil {
+		return err
+	}
+	gameLoop.Run(pollInputs(plat, inputs), update, render)
+	return netErr
+}
+
+// remoteButtons are the buttons a local input map sends to the host
+func remoteButtons(inputs *input.Map) byte {
+	var buttons byte
+	// a tap between two updates is sent as held for one
+	if inputs.Held("start") || inputs.Pressed("start") {
+		buttons |= remoteStart
+	}
+	if inputs.Held("pause") || inputs.Pressed("pause") {
+		buttons |= remotePause
+	}
+	return buttons
+}
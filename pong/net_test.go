@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotEncoding(t *testing.T) {
+	s := snapshot{tick: 70000, state: play, p1: 120.5, p2: -3, ball: pos{400, 299.25}, score1: 2, score2: 9, target: 11, events: 250, lastHit: hitGoal}
+	got, ok := decodeSnapshot(s.encode(nil))
+	if !ok || got != s {
+		t.Errorf("decoded %+v, want %+v", got, s)
+	}
+	if _, ok := decodeSnapshot(s.encode(nil)[:snapshotSize-1]); ok {
+		t.Error("decoded a short snapshot")
+	}
+
+	seq, axis, buttons, ok := decodeInput(encodeInput(nil, 9, -0.5, remoteStart))
+	if !ok || seq != 9 || axis != -0.5 || buttons != remoteStart {
+		t.Errorf("decoded input %d %v %d", seq, axis, buttons)
+	}
+	if _, _, _, ok := decodeInput(encodeInput(nil, 1, 2, 0)); ok {
+		t.Error("decoded an axis past 1")
+	}
+}
+
+// TestLoopback hosts and joins a game over loopback with simulated latency,
+// jitter and loss on both sides, and plays it for a second in real time
+func TestLoopback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays for a second over the network")
+	}
+	loopback(t, netConfig{network: "udp", latency: 30 * time.Millisecond, jitter: 20 * time.Millisecond, loss: 0.2, seed: 1})
+}
+
+// TestLoopbackTCP plays the same game over tcp, which loses nothing but
+// can still be slow
+func TestLoopbackTCP(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays for a second over the network")
+	}
+	loopback(t, netConfig{network: "tcp", latency: 30 * time.Millisecond, jitter: 20 * time.Millisecond, seed: 1})
+}
+
+func loopback(t *testing.T, cfg netConfig) {
+	srv, err := host("127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	c, err := join(srv.conn.LocalAddr().String(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	g := newGame(normal, 3)
+	g.mode = online
+	g.state = waiting
+	inputs := newInputs()
+	start := time.Now()
+	var seen []snapshot
+	for i := 0; i < tickRate; i++ {
+		// press start every so often, a lost press is pressed again
+		var buttons byte
+		if i%20 < 10 {
+			buttons = remoteStart
+		}
+		err := c.sendInput(1, buttons)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now()
+		srv.receive(g, now)
+		g.update(inputs, tick)
+		err = srv.send(g, hitNothing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c.receive(now)
+		if view, ok := c.view(tick); ok {
+			seen = append(seen, view)
+		}
+		time.Sleep(time.Until(start.Add(time.Duration(i+1) * time.Second / tickRate)))
+	}
+
+	if g.state == waiting {
+		t.Fatal("the host never heard the player join")
+	}
+	if g.state != play || g.remote.axis != 1 {
+		t.Errorf("the host is in state %d with the remote axis at %v, want play and 1", g.state, g.remote.axis)
+	}
+	if !c.connected(time.Now()) {
+		t.Error("the player doesn't count as connected")
+	}
+	// packets can be lost and the rest reordered by jitter, the player
+	// still keeps them in order
+	if n := len(c.snaps); n < maxSnapshots/2 {
+		t.Errorf("the player kept %d snapshots", n)
+	}
+	for i := 1; i < len(c.snaps); i++ {
+		if c.snaps[i].tick <= c.snaps[i-1].tick {
+			t.Fatalf("snapshot %d is tick %d after %d", i, c.snaps[i].tick, c.snaps[i-1].tick)
+		}
+	}
+	// what the player draws is behind the host but never goes backwards
+	if len(seen) == 0 {
+		t.Fatal("the player had nothing to draw")
+	}
+	for i := 1; i < len(seen); i++ {
+		if seen[i].p2 < seen[i-1].p2 {
+			t.Fatalf("the drawn paddle went back up from %v to %v", seen[i-1].p2, seen[i].p2)
+		}
+	}
+	if last := seen[len(seen)-1].p2; last <= 100 || last > g.player2.y {
+		t.Errorf("the player draws their paddle at %v, the host has it at %v", last, g.player2.y)
+	}
+}
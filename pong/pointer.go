@@ -0,0 +1,57 @@
+package main
+
+import "github.com/stephen-mahon/games-with-go/input"
+
+// how a player moves their paddle
+type control int
+
+const (
+	// keyControl is the keys or controller the play mode gives the player
+	keyControl control = iota
+	// mouseControl follows the height of the mouse
+	mouseControl
+	// touchControl follows a finger dragged on the screen. sdl reports a
+	// touch as the mouse with its left button down, so it is also a mouse
+	// dragged with the button held.
+	touchControl
+)
+
+var controlNames = []string{"keys", "mouse", "touch"}
+
+// pointerDeadZone is how close in field pixels a paddle can be to the
+// pointer and stay still, so a shaky hand or finger doesn't twitch it
+const pointerDeadZone float32 = 2
+
+func parseControl(s string) (control, bool) {
+	for i, name := range controlNames {
+		if name == s {
+			return control(i), true
+		}
+	}
+	return keyControl, false
+}
+
+// pointerY is the height on the field a paddle played with c should follow,
+// and false when it shouldn't follow the pointer
+func pointerY(inputs *input.Map, c control, f field) (float32, bool) {
+	m := inputs.Mouse()
+	switch c {
+	case mouseControl:
+	case touchControl:
+		if !m.Down(input.MouseLeft) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return f.unpoint(m.X, m.Y).y, true
+}
+
+// pointerAxis is the axis that takes a paddle at y towards py in one update,
+// going no faster than the paddle's speed. Inside the dead zone it is 0.
+func pointerAxis(y, py, speed, elaspedTime float32) float32 {
+	if speed <= 0 || elaspedTime <= 0 || abs(py-y) <= pointerDeadZone {
+		return 0
+	}
+	return clamp(-1, 1, (py-y)/(speed*elaspedTime))
+}
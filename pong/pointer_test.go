@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+// TestPointerY puts the pointer on the middle of the field's height in
+// windows of different shapes, with and without the button held
+func TestPointerY(t *testing.T) {
+	windows := []struct{ w, h int }{
+		{winWidth, winHeight},
+		// bars either side
+		{2 * winWidth, winHeight},
+		// bars above and below
+		{winWidth / 2, winHeight},
+		{1920, 1080},
+	}
+	for _, win := range windows {
+		f := newField(win.w, win.h)
+		x, y := f.point(pos{400, 450})
+		for _, down := range []bool{false, true} {
+			inputs := newInputs()
+			s := input.NewSnapshot()
+			s.Mouse = input.Mouse{X: x, Y: y}
+			if down {
+				s.Mouse.Buttons = 1 << uint(input.MouseLeft-1)
+			}
+			inputs.Update(s)
+
+			for _, c := range []control{keyControl, mouseControl, touchControl} {
+				py, ok := pointerY(inputs, c, f)
+				follow := c == mouseControl || c == touchControl && down
+				if ok != follow {
+					t.Errorf("%dx%d: %s with the button down %v follows the pointer %v, want %v",
+						win.w, win.h, controlNames[c], down, ok, follow)
+				}
+				// a window pixel is up to 1/scale field pixels
+				if ok && abs(py-450) > 1/f.scale {
+					t.Errorf("%dx%d: %s puts the pointer %v down the field, want 450", win.w, win.h, controlNames[c], py)
+				}
+			}
+		}
+	}
+}
+
+func TestPointerAxis(t *testing.T) {
+	tests := []struct {
+		name     string
+		y, py    float32
+		speed    float32
+		dt, want float32
+	}{
+		{"on the pointer", 300, 300, 300, tick, 0},
+		{"just inside the dead zone", 300, 300 + pointerDeadZone, 300, tick, 0},
+		{"just inside the dead zone above", 300, 300 - pointerDeadZone, 300, tick, 0},
+		// an update's travel is 2.5 pixels at 300 a second
+		{"just past the dead zone", 300, 302.25, 300, tick, 0.9},
+		{"far below", 300, 500, 300, tick, 1},
+		{"far above", 300, 0, 300, tick, -1},
+		{"no speed", 300, 500, 0, tick, 0},
+		{"no time", 300, 500, 300, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := pointerAxis(tt.y, tt.py, tt.speed, tt.dt); !near(got, tt.want) {
+			t.Errorf("%s: axis is %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -5,173 +5,147 @@ package main
 // [X] Frame rate independence
 // [X] ** Score ** -- up to 3
 // [X] Game over state - win/lose
-// [ ] 2 player mode
-// [ ] AI needs to be more imperfect
-// [ ] Handeling resizing of window
-// [ ] Mouse play?
-// [ ] load bitmaps for paddles and balls
-// [ ] change angle of incident of reflection
-// [ ] add paddle.y vel to ball.y vel
+// [X] 2 player mode
+// [X] AI needs to be more imperfect
+// [X] Handeling resizing of window
+// [X] Mouse play?
+// [X] load bitmaps for paddles and balls
+// [X] change angle of incident of reflection
+// [X] add paddle.y vel to ball.y vel
 
 import (
+	"flag"
 	"fmt"
 	"math"
+	"math/rand"
+	"strconv"
 	"time"
 
-	"github.com/stephen-mahon/games-with-go/noise"
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/stephen-mahon/games-with-go/capture"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/loop"
+	"github.com/stephen-mahon/games-with-go/mixer"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/platform/sdlplatform"
+	"github.com/stephen-mahon/games-with-go/replay"
+	"github.com/stephen-mahon/games-with-go/sprite"
+	"github.com/stephen-mahon/games-with-go/synth"
+	"github.com/stephen-mahon/games-with-go/wav"
 )
 
 const winWidth, winHeight int = 800, 600
 
-// -- enum
-type gameState int
+// who moves the paddles
+type playMode int
 
 const (
-	start gameState = iota
-	play
-	//gameover
-	//pause
+	onePlayer playMode = iota
+	twoPlayerKeyboard
+	twoPlayerPads
+	// online has the right paddle played over the network, peerToPeer has
+	// both paddles played from rollback input. They aren't in the menu.
+	online
+	peerToPeer
 )
 
-var state = start
-
-// -- end enum
-
-var scores = [][]byte{
-	{
-		1, 1, 1,
-		1, 0, 1,
-		1, 0, 1,
-		1, 0, 1,
-		1, 1, 1,
-	},
-	{
-		1, 1, 0,
-		0, 1, 0,
-		0, 1, 0,
-		0, 1, 0,
-		1, 1, 1,
-	},
-	{
-		1, 1, 1,
-		0, 0, 1,
-		1, 1, 1,
-		1, 0, 0,
-		1, 1, 1,
-	},
-	{
-		1, 1, 1,
-		0, 0, 1,
-		0, 1, 1,
-		0, 0, 1,
-		1, 1, 1,
-	},
-}
-
-type color struct {
-	r, g, b byte
-}
+var modeNames = []string{"1 player vs computer", "2 players, keyboard", "2 players, controllers"}
 
-type pos struct {
-	x, y float32
+// controls are the up and down actions for each paddle. The computer plays
+// a paddle with no actions.
+func (mode playMode) controls() (left, right [2]string) {
+	switch mode {
+	case twoPlayerKeyboard:
+		return [2]string{"p1_up", "p1_down"}, [2]string{"p2_up", "p2_down"}
+	case twoPlayerPads:
+		return [2]string{"pad1_up", "pad1_down"}, [2]string{"pad2_up", "pad2_down"}
+	}
+	return [2]string{"paddle_up", "paddle_down"}, [2]string{}
 }
 
-type ball struct {
-	pos
-	radius float32
-	xv     float32
-	yv     float32
-	color  color
-}
+// what the ball hit during an update, so the right sound can play
+type hit int
 
-func lerp(b1 byte, b2 byte, pct float32) byte {
-	return byte(float32(b1) + pct*(float32(b2)-float32(b1)))
-}
+const (
+	hitNothing hit = iota
+	hitWall
+	hitPaddle
+	hitGoal
+)
 
-func colorLerp(c1, c2 color, pct float32) color {
-	return color{lerp(c1.r, c2.r, pct), lerp(c1.g, c2.g, pct), lerp(c1.b, c2.b, pct)}
+type sounds struct {
+	mixer               *mixer.Mixer
+	paddle, wall, score *wav.Sound
 }
 
-func getGradient(c1, c2 color) []color {
-	result := make([]color, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		result[i] = colorLerp(c1, c2, pct)
+func newSounds(freq int) *sounds {
+	return &sounds{
+		mixer:  mixer.New(freq),
+		paddle: synth.Generate(synth.Presets["hit"], freq),
+		wall:   synth.Generate(synth.Presets["bounce"], freq),
+		score:  synth.Generate(synth.Presets["score"], freq),
 	}
-	return result
 }
 
-func getDualGradient(c1, c2, c3, c4 color) []color {
-	result := make([]color, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		if pct < 0.5 {
-			result[i] = colorLerp(c1, c2, pct*float32(2))
-		} else {
-			result[i] = colorLerp(c3, c4, pct*float32(1.5)-float32(0.5))
-		}
+// play the sound for a hit, panned to where the ball is
+func (sounds *sounds) play(h hit, ball *ball) {
+	pan := ball.x/float32(winWidth)*2 - 1
+	switch h {
+	case hitWall:
+		sounds.mixer.Play(sounds.wall, 1, pan)
+	case hitPaddle:
+		sounds.mixer.Play(sounds.paddle, 1, pan)
+	case hitGoal:
+		sounds.mixer.Play(sounds.score, 1, 0)
 	}
-	return result
 }
 
-func clamp(min, max, v int) int {
-	if v < min {
-		v = min
-	} else if v > max {
-		v = max
-	}
-	return v
+type pos struct {
+	x, y float32
 }
 
-func rescaleAndDraw(noise []float32, min, max float32, gradient []color, w, h int) []byte {
-	result := make([]byte, w*h*4)
-	scale := 255.0 / (max - min)
-	offset := min * scale
+const (
+	// serveSpeed is xv and yv when the ball is served
+	serveSpeed float32 = 400
+	// every paddle hit speeds the ball up by speedUp, to at most maxSpeed
+	speedUp  float32 = 1.05
+	maxSpeed float32 = 1200
+	// maxAngle is the bounce off the very end of a paddle, in radians from
+	// straight across
+	maxAngle = math.Pi / 4
+	// spinTransfer turns paddle speed into sideways acceleration of the
+	// ball, which dies away at spinDecay a second
+	spinTransfer float32 = 1
+	spinDecay    float32 = 2
+)
 
-	for i := range noise {
-		noise[i] = noise[i]*scale - offset
-		c := gradient[clamp(0, 255, int(noise[i]))]
-		p := i * 4
-		result[p] = c.r
-		result[p+1] = c.g
-		result[p+2] = c.b
-	}
-	return result
+type ball struct {
+	pos
+	radius float32
+	xv     float32
+	yv     float32
+	// spin curves the ball, in pixels a second a second
+	spin  float32
+	color palette.Color
+	// stuck is how much longer a sticky paddle holds the ball, stuckY
+	// where on the paddle it is held
+	stuck, stuckY float32
 }
 
-func drawNum(pos pos, color color, size int, score int, pixels []byte) {
-	startX := int(pos.x) - (size*3)/2
-	startY := int(pos.y) - (size*5)/2
-
-	for i, v := range scores[score] {
-		if v == 1 {
-			for y := startY; y < startY+size; y++ {
-				for x := startX; x < startX+size; x++ {
-					setPixel(x, y, color, pixels)
-				}
-			}
-		}
-		startX += size
-		if (i+1)%3 == 0 {
-			startY += size
-			startX -= size * 3
-		}
+func (ball *ball) draw(fb *framebuffer.Framebuffer, f field, t *theme) {
+	if t.ball != nil {
+		x, y := f.point(pos{ball.x - ball.radius, ball.y - ball.radius})
+		size := f.size(2 * ball.radius)
+		sprite.Draw(fb, t.ball, float32(x), float32(y), stretch(t.ball, size, size))
+		return
 	}
+	x, y := f.point(ball.pos)
+	fb.FillCircle(x, y, f.size(ball.radius), ball.color)
 }
 
-func (ball *ball) draw(pixels []byte) {
-	//pseudo 3d effect
-	//startX := int(ball.x) - ball.radius
-	//startY := int(ball.y) - ball.radius
-	for y := -ball.radius; y < ball.radius; y++ {
-		for x := -ball.radius; x < ball.radius; x++ {
-			if x*x+y*y < ball.radius*ball.radius {
-				setPixel(int(ball.x+x), int(ball.y+y), ball.color, pixels)
-			}
-		}
-
-	}
+func lerpPos(a, b pos, pct float32) pos {
+	return pos{loop.Lerp(a.x, b.x, pct), loop.Lerp(a.y, b.y, pct)}
 }
 
 // function to get center of screen regardless of screen size
@@ -179,39 +153,102 @@ func getCenter() pos {
 	return pos{float32(winWidth) / 2, float32(winHeight) / 2}
 }
 
-func (ball *ball) update(leftPaddle *paddle, rightPaddle *paddle, elaspedTime float32) {
+// fly moves the ball on, curving with its spin and bouncing off the top
+// and bottom, and reports whether it hit a wall
+func (ball *ball) fly(elaspedTime float32) hit {
+	ball.yv += ball.spin * elaspedTime
+	ball.spin *= float32(math.Exp(float64(-spinDecay * elaspedTime)))
 	ball.x += ball.xv * elaspedTime
 	ball.y += ball.yv * elaspedTime
 
-	// handle collisions
-	if ball.y-ball.radius < 0 || ball.y+ball.radius > float32(winHeight) {
-		ball.yv = -ball.yv
+	// handle collisions, mirroring any distance past a wall back in
+	if ball.y-ball.radius < 0 {
+		ball.y = 2*ball.radius - ball.y
+		ball.yv = abs(ball.yv)
+		return hitWall
+	} else if ball.y+ball.radius > float32(winHeight) {
+		ball.y = 2*(float32(winHeight)-ball.radius) - ball.y
+		ball.yv = -abs(ball.yv)
+		return hitWall
+	}
+	return hitNothing
+}
+
+func (ball *ball) update(leftPaddle *paddle, rightPaddle *paddle, elaspedTime float32) hit {
+	from := ball.pos
+	h := ball.fly(elaspedTime)
+
+	// test the whole path from the last update against each paddle's face,
+	// so a fast ball can't jump over a paddle between updates
+	if ball.xv < 0 && ball.sweep(from, leftPaddle, leftPaddle.x+leftPaddle.w/2+ball.radius) {
+		return hitPaddle
+	}
+	if ball.xv > 0 && ball.sweep(from, rightPaddle, rightPaddle.x-rightPaddle.w/2-ball.radius) {
+		return hitPaddle
 	}
 
 	if ball.x < 0 {
 		rightPaddle.score++
-		ball.pos = getCenter()
-		state = start
+		ball.serve(-1)
+		return hitGoal
 	} else if int(ball.x) > winWidth {
 		leftPaddle.score++
-		ball.pos = getCenter()
-		state = start
+		ball.serve(1)
+		return hitGoal
 	}
+	return h
+}
 
-	if ball.x-ball.radius < leftPaddle.x+leftPaddle.w/2 {
-		if ball.y > leftPaddle.y-leftPaddle.h/2 &&
-			ball.y < leftPaddle.y+leftPaddle.h/2 {
-			ball.xv = -ball.xv
-			ball.x = leftPaddle.x + leftPaddle.w/2 + ball.radius
-		}
+// sweep checks whether the ball's centre crossed faceX on its way from
+// from, and bounces it off the paddle if it was in reach when it did
+func (ball *ball) sweep(from pos, paddle *paddle, faceX float32) bool {
+	if (from.x-faceX)*(ball.x-faceX) > 0 || from.x == ball.x {
+		return false
 	}
-	if ball.x+ball.radius > rightPaddle.x-rightPaddle.w/2 {
-		if ball.y > rightPaddle.y-rightPaddle.h/2 &&
-			ball.y < rightPaddle.y+rightPaddle.h/2 {
-			ball.xv = -ball.xv
-			ball.x = rightPaddle.x - rightPaddle.w/2 - ball.radius
-		}
+	t := (from.x - faceX) / (from.x - ball.x)
+	y := from.y + (ball.y-from.y)*t
+	if y <= paddle.y-paddle.h/2 || y >= paddle.y+paddle.h/2 {
+		return false
 	}
+
+	// the further from the middle it hits the steeper it goes
+	offset := clamp(-1, 1, (y-paddle.y)/(paddle.h/2))
+	angle := float64(offset) * maxAngle
+	speed := float32(math.Hypot(float64(ball.xv), float64(ball.yv))) * speedUp
+	if speed > maxSpeed {
+		speed = maxSpeed
+	}
+	dir := float32(1)
+	if ball.xv > 0 {
+		dir = -1
+	}
+	ball.xv = dir * speed * float32(math.Cos(angle))
+	ball.yv = speed * float32(math.Sin(angle))
+	ball.spin = paddle.yv * spinTransfer
+	ball.pos = pos{faceX, y}
+	return true
+}
+
+// serve puts the ball back in the middle at serving speed, heading left
+// for dir -1 and right for 1. It keeps going up or down as it was, so
+// the game needs no random numbers and plays the same from the same input.
+func (ball *ball) serve(dir float32) {
+	ball.pos = getCenter()
+	ball.xv = dir * serveSpeed
+	if ball.yv < 0 {
+		ball.yv = -serveSpeed
+	} else {
+		ball.yv = serveSpeed
+	}
+	ball.spin = 0
+	ball.stuck = 0
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
 }
 
 type paddle struct {
@@ -220,162 +257,291 @@ type paddle struct {
 	h     float32
 	speed float32
 	score int
-	color color
+	// yv is how fast the paddle moved in the last update
+	yv    float32
+	color palette.Color
+	// control is how the player moves it, the computer ignores it
+	control control
+	// reversed swaps up and down
+	reversed bool
 }
 
 func flerp(a float32, b float32, pct float32) float32 {
 	return a + pct*(b-a)
 }
 
-func (paddle *paddle) draw(pixels []byte) {
-	startX := int(paddle.x - paddle.w/2)
-	startY := int(paddle.y - paddle.h/2)
-
-	for y := 0; y < int(paddle.h); y++ {
-		for x := 0; x < int(paddle.w); x++ {
-			setPixel(startX+x, startY+y, paddle.color, pixels)
-		}
+func (paddle *paddle) draw(fb *framebuffer.Framebuffer, f field, t *theme) {
+	startX, startY := f.point(pos{paddle.x - paddle.w/2, paddle.y - paddle.h/2})
+	w, h := f.size(paddle.w), f.size(paddle.h)
+	if t.paddle != nil {
+		sprite.Draw(fb, t.paddle, float32(startX), float32(startY), stretch(t.paddle, w, h))
+	} else {
+		fb.FillRect(startX, startY, w, h, paddle.color)
 	}
 
 	numX := flerp(paddle.x, getCenter().x, 0.2)
-	drawNum(pos{numX, 35}, paddle.color, 10, paddle.score, pixels)
-}
-
-func (paddle *paddle) update(keyState []uint8, controllerAxis int16, elaspedTime float32) {
-	if keyState[sdl.SCANCODE_UP] != 0 {
-		paddle.y -= paddle.speed * elaspedTime
-	}
-	if keyState[sdl.SCANCODE_DOWN] != 0 {
-		paddle.y += paddle.speed * elaspedTime
-	}
-
-	if math.Abs(float64(controllerAxis)) > 1500 {
-		pct := float32(controllerAxis) / 32767.0
-		paddle.y += paddle.speed * pct * elaspedTime
-	}
-}
-
-func (paddle *paddle) aiUpdate(ball *ball, elaspedTime float32) {
-	paddle.y = ball.y
+	fnt, scale := t.scoreFont(10)
+	f.textIn(fb, fnt, strconv.Itoa(paddle.score), pos{numX, 35 - (5*10)/2}, scale, paddle.color)
 }
 
-func clear(pixels []byte) {
-	for i := range pixels {
-		pixels[i] = 0
+// update moves the paddle with the pointer if the player is using it,
+// otherwise with the controls. f is where the field is in the window.
+func (paddle *paddle) update(inputs *input.Map, controls [2]string, f field, elaspedTime float32) {
+	if y, ok := pointerY(inputs, paddle.control, f); ok {
+		paddle.move(pointerAxis(paddle.y, y, paddle.speed, elaspedTime), elaspedTime)
+		return
 	}
+	paddle.move(inputs.Axis(controls[0], controls[1]), elaspedTime)
 }
 
-func setPixel(x, y int, c color, pixels []byte) {
-	index := (y*winWidth + x) * 4
-	if index < len(pixels)-4 && index >= 0 {
-		pixels[index] = c.r
-		pixels[index+1] = c.g
-		pixels[index+2] = c.b
+// move goes up for an axis of -1 and down for 1 at full speed
+func (paddle *paddle) move(axis float32, elaspedTime float32) {
+	if paddle.reversed {
+		axis = -axis
 	}
+	paddle.yv = paddle.speed * axis
+	paddle.y += paddle.yv * elaspedTime
 }
 
 func main() {
+	recordFile := flag.String("record", "", "record the game to a file")
+	replayFile := flag.String("replay", "", "play back a recorded game")
+	framesDir := flag.String("frames", "", "save every frame as a png in this directory")
+	fps := flag.Int("fps", 60, "frame rate for -frames")
+	level := flag.String("difficulty", "normal", "computer player: easy, normal or hard")
+	target := flag.Int("target", 3, "score that wins a match")
+	players := flag.String("players", "", "start a tournament between these players, separated by commas")
+	format := flag.String("format", knockout, "tournament format: knockout or league")
+	bracketFile := flag.String("bracket", "tournament.json", "keep the tournament in this file")
+	resume := flag.Bool("resume", false, "carry on the tournament kept in -bracket")
+	statsFile := flag.String("stats", "stats.json", "keep statistics in this file")
+	themeName := flag.String("theme", "", "start with this theme from the themes directory")
+	ctl := flag.String("control", "keys", "how player 1, or you online, moves: keys, mouse or touch")
+	hostAddr := flag.String("host", "", "host an online game on this address, like :7777")
+	joinAddr := flag.String("join", "", "join an online game at this address")
+	useRollback := flag.Bool("rollback", false, "play online with rollback, both sides need it")
+	var cfg netConfig
+	flag.StringVar(&cfg.network, "net", "udp", "play online over udp, or tcp where udp is blocked")
+	flag.DurationVar(&cfg.latency, "latency", 0, "add this much delay to packets sent, for testing")
+	flag.DurationVar(&cfg.jitter, "jitter", 0, "add up to this much more delay to packets sent")
+	flag.Float64Var(&cfg.loss, "loss", 0, "drop this fraction of packets sent, from 0 to 1")
+	flag.Parse()
+
+	startLevel, ok := parseDifficulty(*level)
+	if !ok {
+		fmt.Println("unknown difficulty", *level)
+		return
+	}
+	startControl, ok := parseControl(*ctl)
+	if !ok {
+		fmt.Println("unknown control", *ctl)
+		return
+	}
+	if *target < 1 || *target > 255 {
+		fmt.Println("the target score must be from 1 to 255")
+		return
+	}
+	if cfg.network != "udp" && cfg.network != "tcp" {
+		fmt.Println("unknown network", cfg.network)
+		return
+	}
+	if cfg.network == "tcp" && *useRollback {
+		// rollback sends each input until the other side has it, which
+		// needs packets that can be lost rather than held up
+		fmt.Println("rollback only plays over udp")
+		return
+	}
+	if cfg.network == "tcp" && cfg.loss > 0 {
+		fmt.Println("tcp resends lost packets, -loss needs udp")
+		return
+	}
 
-	err := sdl.Init(sdl.INIT_EVERYTHING)
+	sdlPlat, err := sdlplatform.New("Pong", winWidth, winHeight)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer sdl.Quit()
-
-	window, err := sdl.CreateWindow("Pong", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(winWidth), int32(winHeight), sdl.WINDOW_SHOWN)
+	sdlPlat.SetResizable(true)
+	capt := capture.New(sdlPlat)
+	if *framesDir != "" {
+		err = capt.Sequence(*framesDir, *fps)
+		if err != nil {
+			capt.Close()
+			fmt.Println(err)
+			return
+		}
+	}
+	plat, seed, err := replay.Open(capt, *recordFile, *replayFile)
 	if err != nil {
+		capt.Close()
 		fmt.Println(err)
 		return
 	}
-	defer window.Destroy()
+	rand.Seed(seed)
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	switch {
+	case *useRollback && (*joinAddr != "" || *hostAddr != ""):
+		var r *rollback
+		if *joinAddr != "" {
+			r, err = joinRollback(*joinAddr, cfg)
+		} else {
+			r, err = hostRollback(*hostAddr, cfg, *target)
+		}
+		if err == nil {
+			err = runRollback(plat, r, startControl, *themeName)
+			r.Close()
+		}
+	case *joinAddr != "":
+		var c *client
+		c, err = join(*joinAddr, cfg)
+		if err == nil {
+			err = runClient(plat, c, *themeName)
+			c.Close()
+		}
+	case *hostAddr != "":
+		var srv *server
+		srv, err = host(*hostAddr, cfg)
+		if err == nil {
+			g := newGame(startLevel, *target)
+			g.player1.control = startControl
+			g.mode = online
+			g.state = waiting
+			err = run(plat, g, srv, *themeName)
+			srv.Close()
+		}
+	default:
+		g := newGame(startLevel, *target)
+		g.player1.control = startControl
+		g.stats, err = loadStats(*statsFile)
+		if err == nil && (*players != "" || *resume) {
+			g.tournament, err = openTournament(*bracketFile, *format, *players, *resume)
+			g.mode = twoPlayerKeyboard
+			g.state = standings
+		}
+		if err == nil {
+			err = run(plat, g, nil, *themeName)
+		}
+	}
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer renderer.Destroy()
-
-	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(winWidth), int32(winHeight))
+	if err := capt.Err(); err != nil {
+		fmt.Println(err)
+	}
+	err = replay.Close(plat)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer tex.Destroy()
+}
 
-	var controllerHandlers []*sdl.GameController
-	for i := 0; i < sdl.NumJoysticks(); i++ {
-		controllerHandlers = append(controllerHandlers, sdl.GameControllerOpen(i))
-		defer controllerHandlers[i].Close()
+// pollInputs polls the platform for the game loop and reads the input of
+// every frame, so a tap on a frame with no update still counts
+func pollInputs(plat platform.Platform, inputs *input.Map) func() bool {
+	return func() bool {
+		if !plat.Poll() {
+			return false
+		}
+		inputs.Update(plat.Input())
+		return true
 	}
+}
 
-	pixels := make([]byte, winWidth*winHeight*4)
-
-	player1 := paddle{pos{50, 100}, 20, 100, 300, 0, color{255, 255, 255}}
-	player2 := paddle{pos{float32(winWidth) - 50, 100}, 20, 100, 300, 0, color{255, 255, 255}}
-	ball := ball{pos{300, 300}, 20, 400, 400, color{255, 255, 255}}
-
-	keyState := sdl.GetKeyboardState()
-
-	noise, min, max := noise.MakeNoise(noise.TURBULENCE, 0.001, 0.2, 2, 3, winWidth, winHeight)
-	gradient := getGradient(color{255, 0, 0}, color{0, 0, 0})
-	noisePixels := rescaleAndDraw(noise, min, max, gradient, winWidth, winHeight)
-
-	var frameStart time.Time
-	var elaspedTime float32
-	var controllerAxis int16
+func newLoop(plat platform.Platform) (*loop.Loop, error) {
+	gameLoop, err := loop.New(tickRate)
+	if err != nil {
+		return nil, err
+	}
+	gameLoop.MaxFPS = 200
+	gameLoop.Clock = plat
+	return gameLoop, nil
+}
 
-	// game loop
-	for {
-		frameStart = time.Now()
+// run plays g until the platform's window closes, starting with the theme
+// named. With a server the right paddle is played over the network.
+func run(plat platform.Platform, g *game, srv *server, themeName string) error {
+	inputs := newInputs()
+	err := inputs.LoadFile("controls.txt")
+	if err != nil {
+		return err
+	}
 
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return
+	err = plat.OpenAudio(platform.AudioSpec{Freq: 48000, Channels: 2})
+	if err != nil {
+		return err
+	}
+	sounds := newSounds(48000)
+	screen, err := newScreen(plat, themeName)
+	if err != nil {
+		return err
+	}
+	defer screen.close()
+
+	prevBalls, prevPlayer1, prevPlayer2 := g.balls, g.player1.pos, g.player2.pos
+	prevNumBalls := g.numBalls
+	var netErr, statsErr, tournamentErr error
+
+	update := func(elaspedTime float32) {
+		prevBalls, prevPlayer1, prevPlayer2 = g.balls, g.player1.pos, g.player2.pos
+		prevNumBalls = g.numBalls
+		screen.update(plat, inputs)
+		if srv != nil {
+			srv.receive(g, plat.Now())
+		}
+		g.view = screen.field
+		prev := g.state
+		h := g.update(inputs, elaspedTime)
+		if g.stats != nil {
+			err := g.stats.observe(g, h, prev)
+			if err != nil && statsErr == nil {
+				statsErr = err
 			}
 		}
-
-		for _, controller := range controllerHandlers {
-			if controller != nil {
-				controllerAxis = controller.Axis(sdl.CONTROLLER_AXIS_LEFTY)
+		if g.tournament != nil {
+			err := g.tournament.observe(g, prev)
+			if err != nil && tournamentErr == nil {
+				tournamentErr = err
 			}
 		}
-		// updating iota for game state
-		if state == play {
-			player1.update(keyState, controllerAxis, elaspedTime)
-			player2.aiUpdate(&ball, elaspedTime)
-			ball.update(&player1, &player2, elaspedTime)
-		} else if state == start {
-			if keyState[sdl.SCANCODE_SPACE] != 0 {
-				if player1.score == 3 || player2.score == 3 {
-					player1.score = 0
-					player2.score = 0
-				}
-				state = play
-
+		if srv != nil {
+			err := srv.send(g, h)
+			if err != nil && netErr == nil {
+				netErr = err
 			}
 		}
+		sounds.play(h, &g.balls[g.hitBall])
+		sounds.mixer.Pump(plat, time.Second/20)
+		inputs.Tick()
+	}
 
-		for i := range noisePixels {
-			pixels[i] = noisePixels[i]
-		}
-		player1.draw(pixels)
-		player2.draw(pixels)
-		ball.draw(pixels)
-
-		tex.Update(nil, pixels, winWidth*4)
-		renderer.Copy(tex, nil, nil)
-		renderer.Present()
-
-		// basic framerate independence setup
-		elaspedTime = float32(time.Since(frameStart).Seconds() * 1000)
-		if elaspedTime < 0.005 {
-			sdl.Delay(5 - uint32(elaspedTime*1000.0))
-			elaspedTime = float32(time.Since(frameStart).Seconds())
+	render := func(alpha float32) {
+		// draw everything part way between the last two updates
+		view := *g
+		view.player1.pos = lerpPos(prevPlayer1, g.player1.pos, alpha)
+		view.player2.pos = lerpPos(prevPlayer2, g.player2.pos, alpha)
+		// balls come and go in arcade play, only blend when they haven't
+		if prevNumBalls == g.numBalls {
+			for i := 0; i < g.numBalls; i++ {
+				view.balls[i].pos = lerpPos(prevBalls[i].pos, g.balls[i].pos, alpha)
+			}
 		}
+		screen.show(plat, &view)
 	}
 
+	// game loop
+	gameLoop, err := newLoop(plat)
+	if err != nil {
+		return err
+	}
+	gameLoop.Run(pollInputs(plat, inputs), update, render)
+	if g.stats != nil && statsErr == nil {
+		// keep the records set in an unfinished match
+		statsErr = g.stats.save()
+	}
+	if tournamentErr != nil {
+		return tournamentErr
+	}
+	if netErr != nil {
+		return netErr
+	}
+	return statsErr
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"math"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/golden"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// TestGolden renders the title screen headless and compares it with
+// testdata/start.png
+func TestGolden(t *testing.T) {
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	h.Script(input.NewSnapshot())
+	err := run(h, newGame(normal, 3), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = golden.Check("testdata/start.png", h.Frames[0], golden.DefaultTolerance, *update)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestHeadless plays a few hundred frames against the computer without a
+// window
+func TestHeadless(t *testing.T) {
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	idle, start := input.NewSnapshot(), input.NewSnapshot(input.KeySpace)
+	// start a match, serve, then move about. Keys are held a few frames
+	// as the loop doesn't update on every frame.
+	for i := 0; i < 2; i++ {
+		h.Hold(5, idle)
+		h.Hold(5, start)
+	}
+	h.Hold(60, input.NewSnapshot(input.KeyUp))
+	h.Hold(60, input.NewSnapshot(input.KeyDown))
+	h.Hold(280, idle)
+
+	g := newGame(normal, 3)
+	err := run(h, g, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Frames) != 420 {
+		t.Fatalf("presented %d frames, want 420", len(h.Frames))
+	}
+	if g.state == title {
+		t.Fatal("the match didn't start")
+	}
+	// a point ends with the next serve waiting for the space bar
+	if g.player1.score+g.player2.score == 0 && g.state != play {
+		t.Errorf("no point was played, state is %d", g.state)
+	}
+	if string(h.Frames[10].Pixels) == string(h.Frames[len(h.Frames)-1].Pixels) {
+		t.Error("the screen didn't change during play")
+	}
+	if len(h.Audio) == 0 {
+		t.Error("nothing was heard")
+	}
+}
+
+// rightPaddle is a paddle 100 high in the middle of the right side, its
+// face 740 across
+func rightPaddle(yv float32) paddle {
+	return paddle{pos: pos{750, 300}, w: 20, h: 100, speed: 300, yv: yv}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 0.01
+}
+
+func TestDeflection(t *testing.T) {
+	tests := []struct {
+		name string
+		// where the ball meets the face, from -1 at the top of the paddle
+		// to 1 at the bottom
+		offset float32
+		// how fast the paddle was moving, and how fast the ball came in
+		paddleYV, xv float32
+		// what it should leave with
+		angle, speed, spin float32
+	}{
+		{"middle", 0, 0, 400, 0, 420, 0},
+		{"half way down", 0.5, 0, 400, maxAngle / 2, 420, 0},
+		{"half way up", -0.5, 0, 400, -maxAngle / 2, 420, 0},
+		{"near the bottom", 0.98, 0, 400, 0.98 * maxAngle, 420, 0},
+		{"paddle going down", 0, 300, 400, 0, 420, 300 * spinTransfer},
+		{"paddle going up", 0.5, -300, 400, maxAngle / 2, 420, -300 * spinTransfer},
+		{"at the speed cap", 0, 0, 1190, 0, maxSpeed, 0},
+		{"already at the cap", 0, 0, maxSpeed, 0, maxSpeed, 0},
+	}
+	for _, tt := range tests {
+		left, right := paddle{pos: pos{-1000, 300}, h: 100}, rightPaddle(tt.paddleYV)
+		y := 300 + tt.offset*50
+		b := ball{pos: pos{700, y}, radius: 20, xv: tt.xv}
+		// far enough to pass the face in one update
+		if h := b.update(&left, &right, 60/tt.xv); h != hitPaddle {
+			t.Errorf("%s: the ball hit %d, want the paddle", tt.name, h)
+			continue
+		}
+		if b.pos != (pos{720, y}) {
+			t.Errorf("%s: the ball left from %v, want the face at %v", tt.name, b.pos, pos{720, y})
+		}
+		angle := float32(math.Atan2(float64(b.yv), float64(-b.xv)))
+		speed := float32(math.Hypot(float64(b.xv), float64(b.yv)))
+		if b.xv >= 0 || !near(angle, tt.angle) || !near(speed, tt.speed) || !near(b.spin, tt.spin) {
+			t.Errorf("%s: the ball left at %v rad, speed %v, spin %v, want %v rad, speed %v, spin %v",
+				tt.name, angle, speed, b.spin, tt.angle, tt.speed, tt.spin)
+		}
+	}
+}
+
+// TestFastBall moves a ball at full speed in updates long enough to carry
+// it past a paddle in one go
+func TestFastBall(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y float32
+		dt   float32
+		want hit
+	}{
+		// from 650 to 770, past the face at 720 and the paddle's back
+		{"a tenth of a second", 650, 300, 0.1, hitPaddle},
+		// from 200 to 800, the whole paddle and out
+		{"half a second", 200, 300, 0.5, hitPaddle},
+		{"off the end of the paddle", 700, 300 + 50 + 1, 0.1, hitGoal},
+	}
+	for _, tt := range tests {
+		left, right := paddle{pos: pos{-1000, 300}, h: 100}, rightPaddle(0)
+		b := ball{pos: pos{tt.x, tt.y}, radius: 20, xv: maxSpeed}
+		h := b.update(&left, &right, tt.dt)
+		if h != tt.want {
+			t.Errorf("%s: the ball hit %d, want %d", tt.name, h, tt.want)
+		}
+		if h == hitPaddle && (b.x != 720 || b.xv >= 0) {
+			t.Errorf("%s: the ball went through the paddle to %v", tt.name, b.x)
+		}
+	}
+}
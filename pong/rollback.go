@@ -0,0 +1,329 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// Rollback online play in the style of GGPO https://www.ggpo.net. Both
+// players run the whole game from both players' input. Each sends its own
+// input for every frame the other hasn't acknowledged yet, and guesses the
+// other's input stays as it last was until the real input arrives. When a
+// guess turns out wrong the game goes back to the state saved before that
+// frame and plays forward again with the right input.
+
+const (
+	msgRollback byte = 'R'
+
+	// rollbackFrames is how many frames back a late input can be put
+	// right. A player that far behind makes the other wait.
+	rollbackFrames = 32
+	// inputDelay holds local input back a few frames, so that short
+	// delays need no rollback at all
+	inputDelay = 2
+	// historyFrames is how much input each side keeps, enough to resend
+	// everything the other side might still be missing
+	historyFrames = 256
+	// maxSendFrames is the most input sent in one packet. Everything the
+	// other side hasn't acknowledged is sent, so a side whose input has
+	// gone this far unacknowledged waits for the acknowledgements.
+	maxSendFrames = 128
+	// a packet is the type, the sender's frame, how much input it has
+	// from us, the first frame of input in the packet, its advantage and
+	// target score, then 5 bytes of input a frame
+	rollbackHeader = 15
+)
+
+// frameInput is one player's input for one frame
+type frameInput struct {
+	axis    float32
+	buttons byte
+}
+
+type rollback struct {
+	*peer
+	// other is nil until the other player is heard from
+	other net.Addr
+	// side 0 plays the left paddle and hosts, 1 plays the right
+	side int
+
+	game  game
+	frame uint32
+	// saved is the game before each of the last rollbackFrames frames,
+	// and inputs what each played with, the other side's maybe guessed
+	saved  [rollbackFrames]game
+	inputs [rollbackFrames][2]frameInput
+
+	local, remote [historyFrames]frameInput
+	// input is known for frames before localFrames and remoteFrames
+	localFrames, remoteFrames uint32
+	// otherAck is how much of our input the other side has
+	otherAck uint32
+	// advantage is how many frames ahead of the other side this side
+	// seems on average, otherAdvantage how far ahead the other side seems
+	// to itself. Both include the time packets take, which cancels out.
+	advantage, otherAdvantage float32
+	sinceWait                 int
+	// guessFrom is the earliest frame played with a wrong guess
+	guessFrom  uint32
+	mispredict bool
+	lastHeard  time.Time
+	noInput    *input.Map
+	buf        []byte
+
+	// rollbacks counts how often the game went back, for tuning
+	rollbacks int
+}
+
+func newRollback(conn net.PacketConn, other net.Addr, side, target int) *rollback {
+	r := &rollback{peer: newPeer(conn), other: other, side: side, noInput: input.NewMap()}
+	r.game = *newGame(normal, target)
+	r.game.mode = peerToPeer
+	// both sides must draw the same random numbers
+	r.game.seed = 1
+	r.game.newMatch()
+	// nobody has input for the first frames, they play as no input
+	r.localFrames, r.remoteFrames = inputDelay, inputDelay
+	return r
+}
+
+// hostRollback waits on addr for a player to join, the host plays the left
+// paddle and decides the target score
+func hostRollback(addr string, cfg netConfig, target int) (*rollback, error) {
+	conn, err := listenUDP(addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newRollback(conn, nil, 0, target), nil
+}
+
+// joinRollback plays the right paddle of a game hosted at addr
+func joinRollback(addr string, cfg netConfig) (*rollback, error) {
+	other, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := listenUDP(":0", cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newRollback(conn, other, 1, 1), nil
+}
+
+// update plays the next frame with local input, unless this side has got
+// too far ahead of the other. It returns what the ball hit in that frame.
+func (r *rollback) update(local frameInput, now time.Time) (h hit, played bool) {
+	r.receive(now)
+	if r.mispredict {
+		r.replay()
+	}
+
+	wait := int(r.frame)-int(r.remoteFrames) >= rollbackFrames-1
+	if r.frame+inputDelay+1-r.otherAck > maxSendFrames {
+		wait = true
+	}
+	// skip a frame now and then to let a slower other side catch up
+	r.sinceWait++
+	if (r.advantage-r.otherAdvantage)/2 >= 1 && r.sinceWait >= 8 {
+		wait = true
+	}
+	if wait {
+		r.sinceWait = 0
+	}
+	if !wait {
+		r.local[(r.frame+inputDelay)%historyFrames] = local
+		r.localFrames = r.frame + inputDelay + 1
+		h = r.simulate()
+	}
+	r.send()
+	return h, !wait
+}
+
+// simulate saves the game and plays one frame
+func (r *rollback) simulate() hit {
+	f := r.frame
+	in := &r.inputs[f%rollbackFrames]
+	in[r.side] = r.local[f%historyFrames]
+	if f < r.remoteFrames {
+		in[1-r.side] = r.remote[f%historyFrames]
+	} else {
+		// guess the other player is still doing what they last did
+		in[1-r.side] = r.remote[(r.remoteFrames-1)%historyFrames]
+	}
+	r.saved[f%rollbackFrames] = r.game
+
+	r.game.left.axis, r.game.left.buttons = in[0].axis, in[0].buttons
+	r.game.remote.axis, r.game.remote.buttons = in[1].axis, in[1].buttons
+	r.frame++
+	return r.game.update(r.noInput, 1.0/tickRate)
+}
+
+// replay goes back to before the first wrong guess and plays forward again
+func (r *rollback) replay() {
+	to := r.frame
+	r.game = r.saved[r.guessFrom%rollbackFrames]
+	r.frame = r.guessFrom
+	for r.frame < to {
+		r.simulate()
+	}
+	r.mispredict = false
+	r.rollbacks++
+}
+
+// receive takes in the other side's input, noting any guesses it shows
+// were wrong
+func (r *rollback) receive(now time.Time) {
+	for {
+		pk, ok := r.poll()
+		if !ok {
+			return
+		}
+		if len(pk.data) < rollbackHeader || pk.data[0] != msgRollback || (len(pk.data)-rollbackHeader)%5 != 0 {
+			continue
+		}
+		if r.other == nil {
+			r.other = pk.addr
+		}
+		if pk.addr.String() != r.other.String() {
+			continue
+		}
+		r.lastHeard = now
+
+		b := pk.data
+		otherFrame := binary.LittleEndian.Uint32(b[1:])
+		ack := binary.LittleEndian.Uint32(b[5:])
+		first := binary.LittleEndian.Uint32(b[9:])
+		r.otherAdvantage = float32(int8(b[13]))
+		if r.side == 1 && int(b[14]) != r.game.target {
+			r.setTarget(int(b[14]))
+		}
+		// average out jitter
+		r.advantage += (float32(int(r.frame)-int(otherFrame)) - r.advantage) / 8
+		// the other side can't have more input than we've sent
+		if ack > r.otherAck && ack <= r.localFrames {
+			r.otherAck = ack
+		}
+
+		for i := 0; rollbackHeader+i*5 < len(b); i++ {
+			f := first + uint32(i)
+			if f != r.remoteFrames {
+				continue
+			}
+			p := b[rollbackHeader+i*5:]
+			in := frameInput{math.Float32frombits(binary.LittleEndian.Uint32(p)), p[4]}
+			if in.axis != in.axis || in.axis < -1 || in.axis > 1 {
+				break
+			}
+			r.remote[f%historyFrames] = in
+			r.remoteFrames++
+			if f < r.frame && r.inputs[f%rollbackFrames][1-r.side] != in {
+				if !r.mispredict || f < r.guessFrom {
+					r.guessFrom = f
+				}
+				r.mispredict = true
+			}
+		}
+	}
+}
+
+// setTarget takes the host's target score, in the saved games too so a
+// rollback can't undo it
+func (r *rollback) setTarget(target int) {
+	r.game.target = target
+	for i := range r.saved {
+		r.saved[i].target = target
+	}
+}
+
+// send gives the other side all the input it hasn't acknowledged
+func (r *rollback) send() {
+	if r.other == nil {
+		return
+	}
+	first := r.otherAck
+	advantage := int(math.Round(float64(r.advantage)))
+	if advantage > 127 {
+		advantage = 127
+	} else if advantage < -128 {
+		advantage = -128
+	}
+
+	b := append(r.buf[:0], make([]byte, rollbackHeader)...)
+	b[0] = msgRollback
+	binary.LittleEndian.PutUint32(b[1:], r.frame)
+	binary.LittleEndian.PutUint32(b[5:], r.remoteFrames)
+	binary.LittleEndian.PutUint32(b[9:], first)
+	b[13] = byte(int8(advantage))
+	b[14] = byte(r.game.target)
+	for f := first; f < r.localFrames; f++ {
+		in := r.local[f%historyFrames]
+		var p [5]byte
+		binary.LittleEndian.PutUint32(p[:], math.Float32bits(in.axis))
+		p[4] = in.buttons
+		b = append(b, p[:]...)
+	}
+	r.buf = b
+	// a failed send is the same as a lost packet, the input goes again
+	// next frame
+	r.conn.WriteTo(b, r.other)
+}
+
+func (r *rollback) connected(now time.Time) bool {
+	return r.other != nil && !r.lastHeard.IsZero() && now.Sub(r.lastHeard) < netTimeout
+}
+
+// runRollback plays a rollback game until the platform's window closes,
+// moving the local paddle with c
+func runRollback(plat platform.Platform, r *rollback, c control, themeName string) error {
+	inputs := newInputs()
+	err := inputs.LoadFile("controls.txt")
+	if err != nil {
+		return err
+	}
+	err = plat.OpenAudio(platform.AudioSpec{Freq: 48000, Channels: 2})
+	if err != nil {
+		return err
+	}
+	sounds := newSounds(48000)
+	screen, err := newScreen(plat, themeName)
+	if err != nil {
+		return err
+	}
+	defer screen.close()
+
+	update := func(elaspedTime float32) {
+		screen.update(plat, inputs)
+		local := frameInput{inputs.Axis("paddle_up", "paddle_down"), remoteButtons(inputs)}
+		own := &r.game.player1
+		if r.side == 1 {
+			own = &r.game.player2
+		}
+		if y, ok := pointerY(inputs, c, screen.field); ok {
+			local.axis = pointerAxis(own.y, y, own.speed, elaspedTime)
+		}
+		h, _ := r.update(local, plat.Now())
+		sounds.play(h, &r.game.balls[r.game.hitBall])
+		sounds.mixer.Pump(plat, time.Second/20)
+		inputs.Tick()
+	}
+
+	render := func(alpha float32) {
+		view := r.game
+		if !r.connected(plat.Now()) {
+			view.state = waiting
+		}
+		screen.show(plat, &view)
+	}
+
+	gameLoop, err := newLoop(plat)
+	if err != nil {
+		return err
+	}
+	gameLoop.Run(pollInputs(plat, inputs), update, render)
+	return r.Err()
+}
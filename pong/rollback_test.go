@@ -0,0 +1,150 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/netsim"
+)
+
+// confirmed is what must match on both sides once a frame's input is known
+type confirmed struct {
+	state            gameState
+	player1, player2 paddle
+	balls            [maxBalls]ball
+	numBalls         int
+}
+
+// rollbackPeer is one side of a rollback game in a test, with the game as
+// it was before every frame both players' input is known for
+type rollbackPeer struct {
+	*rollback
+	confirmed map[uint32]confirmed
+	next      uint32
+}
+
+// update plays a frame and notes the frames that are now confirmed. Saved
+// games before then are never replayed again.
+func (p *rollbackPeer) update(in frameInput) {
+	p.rollback.update(in, time.Now())
+	known := p.frame
+	if p.remoteFrames < known {
+		known = p.remoteFrames
+	}
+	for ; p.next < known; p.next++ {
+		g := p.saved[p.next%rollbackFrames]
+		p.confirmed[p.next] = confirmed{g.state, g.player1, g.player2, g.balls, g.numBalls}
+	}
+}
+
+func newRollbackPair(t *testing.T, cfg netConfig) (host, joined *rollbackPeer) {
+	h, err := hostRollback("127.0.0.1:0", cfg, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, err := joinRollback(h.conn.LocalAddr().String(), cfg)
+	if err != nil {
+		h.Close()
+		t.Fatal(err)
+	}
+	return &rollbackPeer{rollback: h, confirmed: map[uint32]confirmed{}}, &rollbackPeer{rollback: j, confirmed: map[uint32]confirmed{}}
+}
+
+// playBoth runs both sides for n updates in real time, each with its own
+// input for the update number. before is called ahead of each update.
+func playBoth(host, joined *rollbackPeer, n int, before func(i int)) {
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		if before != nil {
+			before(i)
+		}
+		f := float64(i)
+		var buttons byte
+		// serve again every few seconds
+		if i%240 < 5 {
+			buttons = remoteStart
+		}
+		host.update(frameInput{float32(math.Sin(f / 20)), buttons})
+		joined.update(frameInput{float32(math.Cos(f / 13)), 0})
+		time.Sleep(time.Until(start.Add(time.Duration(i+1) * time.Second / tickRate)))
+	}
+}
+
+// checkConverged fails unless both sides played at least min frames and
+// agree on every frame both have confirmed
+func checkConverged(t *testing.T, host, joined *rollbackPeer, min uint32) {
+	t.Helper()
+	n := host.next
+	if joined.next < n {
+		n = joined.next
+	}
+	if n < min {
+		t.Fatalf("only %d frames were confirmed on both sides, want %d", n, min)
+	}
+	for f := uint32(0); f < n; f++ {
+		if host.confirmed[f] != joined.confirmed[f] {
+			t.Fatalf("the sides differ at frame %d:\n%+v\n%+v", f, host.confirmed[f], joined.confirmed[f])
+		}
+	}
+	if host.game.player1.score+host.game.player2.score == 0 && host.game.state != play {
+		t.Errorf("the match never got going, it is in state %d", host.game.state)
+	}
+}
+
+func TestRollbackConverges(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays for a few seconds over the network")
+	}
+	host, joined := newRollbackPair(t, netConfig{latency: 40 * time.Millisecond, jitter: 30 * time.Millisecond, loss: 0.1, seed: 1})
+	defer host.Close()
+	defer joined.Close()
+
+	playBoth(host, joined, 3*tickRate, nil)
+	checkConverged(t, host, joined, 2*tickRate)
+	if host.rollbacks == 0 && joined.rollbacks == 0 {
+		t.Error("neither side rolled back, the guesses can't all have been right")
+	}
+	if host.game.target != 5 || joined.game.target != 5 {
+		t.Errorf("the targets are %d and %d, want the host's 5", host.game.target, joined.game.target)
+	}
+}
+
+// TestRollbackOutage drops everything the host sends for a second. Its
+// unacknowledged input piles up, and all of it has to reach the other side
+// once the network comes back.
+func TestRollbackOutage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("plays for a few seconds over the network")
+	}
+	host, joined := newRollbackPair(t, netConfig{latency: 20 * time.Millisecond, seed: 1})
+	defer host.Close()
+	defer joined.Close()
+
+	sim := host.conn.(*netsim.Conn)
+	playBoth(host, joined, 4*tickRate, func(i int) {
+		switch i {
+		case tickRate / 2:
+			sim.Loss = 1
+		case 3 * tickRate / 2:
+			sim.Loss = 0
+		}
+	})
+	checkConverged(t, host, joined, 3*tickRate)
+	if err := host.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRollbackClosed(t *testing.T) {
+	host, joined := newRollbackPair(t, netConfig{})
+	defer joined.Close()
+	host.Close()
+	for i := 0; i < 100 && host.Err() == nil; i++ {
+		time.Sleep(time.Millisecond)
+		host.update(frameInput{})
+	}
+	if host.Err() == nil {
+		t.Error("a closed connection wasn't reported")
+	}
+}
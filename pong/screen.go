@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// field maps the play field, winWidth by winHeight whatever the window,
+// into the window as big as it fits with black bars either side
+type field struct {
+	scale float32
+	// x, y is the field's top left in the window, w, h its size there
+	x, y, w, h int
+}
+
+func newField(windowW, windowH int) field {
+	scale := float32(windowW) / float32(winWidth)
+	if s := float32(windowH) / float32(winHeight); s < scale {
+		scale = s
+	}
+	w, h := int(float32(winWidth)*scale+0.5), int(float32(winHeight)*scale+0.5)
+	return field{scale, (windowW - w) / 2, (windowH - h) / 2, w, h}
+}
+
+// point is where a place on the field is in the window
+func (f field) point(p pos) (int, int) {
+	return f.x + int(p.x*f.scale), f.y + int(p.y*f.scale)
+}
+
+// size is a length on the field in window pixels
+func (f field) size(l float32) int {
+	return int(l*f.scale + 0.5)
+}
+
+// unpoint is the place on the field under a window pixel
+func (f field) unpoint(x, y int) pos {
+	return pos{float32(x-f.x) / f.scale, float32(y-f.y) / f.scale}
+}
+
+// text draws in the classic font at a place on the field, see textIn
+func (f field) text(fb *framebuffer.Framebuffer, s string, p pos, scale int, c palette.Color) {
+	f.textIn(fb, font.Default(), s, p, scale, c)
+}
+
+// textIn draws at a place on the field with the font scaled to match,
+// never smaller than a pixel a dot
+func (f field) textIn(fb *framebuffer.Framebuffer, fnt *font.Font, s string, p pos, scale int, c palette.Color) {
+	x, y := f.point(p)
+	scale = f.size(float32(scale))
+	if scale < 1 {
+		scale = 1
+	}
+	fnt.Draw(fb, s, x, y, scale, font.Center, c)
+}
+
+// screen is the frame drawn into, sized to the window, and the theme's
+// background behind the field. When the window changes size or the theme
+// changes the background is made again by a worker goroutine, the old one
+// carries on until it is ready.
+type screen struct {
+	fb         *framebuffer.Framebuffer
+	field      field
+	themes     []*theme
+	theme      int
+	background *framebuffer.Framebuffer
+	// requests holds the newest background wanted, one the worker hasn't
+	// started on yet is replaced. Made backgrounds come back on made with
+	// the generation they were asked for in, only the latest is kept.
+	requests chan backgroundRequest
+	made     chan backgroundRequest
+	gen      int
+	// made is the size and theme of the newest background asked for
+	madeW, madeH int
+	madeTheme    int
+}
+
+type backgroundRequest struct {
+	gen   int
+	theme *theme
+	field field
+	bg    *framebuffer.Framebuffer
+}
+
+// newScreen fits the window and loads the themes, starting with the one
+// named, making the first background straight away
+func newScreen(plat platform.Platform, themeName string) (*screen, error) {
+	themes, err := loadThemes(themeDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &screen{themes: themes, theme: -1}
+	for i, t := range themes {
+		if t.name == themeName || themeName == "" && i == 0 {
+			s.theme = i
+		}
+	}
+	if s.theme < 0 {
+		return nil, fmt.Errorf("no theme %q in %s", themeName, themeDir)
+	}
+	w, h := plat.Size()
+	s.fb, s.field = framebuffer.New(w, h), newField(w, h)
+	s.background = s.themes[s.theme].background(s.field.w, s.field.h, s.field.scale)
+	s.madeW, s.madeH, s.madeTheme = s.field.w, s.field.h, s.theme
+	return s, nil
+}
+
+// update switches theme and goes in and out of fullscreen on the theme and
+// fullscreen actions
+func (s *screen) update(plat platform.Platform, inputs *input.Map) {
+	if inputs.Pressed("fullscreen") {
+		// if it fails the window stays as it was, which is fine
+		plat.SetFullscreen(!plat.Fullscreen())
+	}
+	if inputs.Pressed("theme") {
+		s.theme = (s.theme + 1) % len(s.themes)
+	}
+}
+
+// fit follows the window's size and the theme
+func (s *screen) fit(plat platform.Platform) {
+	select {
+	case req := <-s.made:
+		if req.gen == s.gen {
+			s.background = req.bg
+		}
+	default:
+	}
+
+	w, h := plat.Size()
+	if w > 0 && h > 0 && (w != s.fb.W || h != s.fb.H) {
+		s.fb = framebuffer.New(w, h)
+		s.field = newField(w, h)
+	}
+	if s.field.w == s.madeW && s.field.h == s.madeH && s.theme == s.madeTheme {
+		return
+	}
+	if s.requests == nil {
+		s.requests = make(chan backgroundRequest, 1)
+		s.made = make(chan backgroundRequest, 1)
+		go makeBackgrounds(s.requests, s.made)
+	}
+	s.gen++
+	s.madeW, s.madeH, s.madeTheme = s.field.w, s.field.h, s.theme
+	// only this goroutine sends, so once emptied there is room
+	select {
+	case <-s.requests:
+	default:
+	}
+	s.requests <- backgroundRequest{gen: s.gen, theme: s.themes[s.theme], field: s.field}
+}
+
+// makeBackgrounds makes backgrounds until requests is closed. A background
+// nobody has taken yet is replaced by the next.
+func makeBackgrounds(requests <-chan backgroundRequest, made chan backgroundRequest) {
+	for req := range requests {
+		req.bg = req.theme.background(req.field.w, req.field.h, req.field.scale)
+		select {
+		case <-made:
+		default:
+		}
+		made <- req
+	}
+}
+
+// close stops the background worker
+func (s *screen) close() {
+	if s.requests != nil {
+		close(s.requests)
+		s.requests = nil
+	}
+}
+
+// show draws g to the window
+func (s *screen) show(plat platform.Platform, g *game) {
+	s.fit(plat)
+	g.draw(s.fb, s.background, s.field, s.themes[s.theme])
+	plat.DrawFramebuffer(s.fb)
+	plat.Present()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+func TestNewField(t *testing.T) {
+	// twice as wide as the field needs, bars left and right
+	f := newField(2*winWidth, winHeight)
+	if f.scale != 1 || f.x != winWidth/2 || f.y != 0 || f.w != winWidth || f.h != winHeight {
+		t.Errorf("wide window fits the field as %+v", f)
+	}
+	f = newField(winWidth/2, winHeight)
+	if f.scale != 0.5 || f.x != 0 || f.y != winHeight/4 {
+		t.Errorf("tall window fits the field as %+v", f)
+	}
+	if p := f.unpoint(f.point(pos{100, 200})); p != (pos{100, 200}) {
+		t.Errorf("a point on the field came back as %v", p)
+	}
+}
+
+// workers counts the goroutines making backgrounds
+func workers() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return bytes.Count(buf, []byte("pong.makeBackgrounds("))
+}
+
+// TestResizeStorm drags the window through many sizes between frames. One
+// worker makes the backgrounds, and the last size is the one that sticks.
+func TestResizeStorm(t *testing.T) {
+	h := platform.NewHeadless(winWidth, winHeight)
+	s, err := newScreen(h, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+
+	for i := 0; i < 50; i++ {
+		h.Resize(400+i*10, 300+i*5)
+		s.fit(h)
+	}
+	if n := workers(); n != 1 {
+		t.Errorf("resizing started %d workers, want 1", n)
+	}
+
+	want := newField(890, 545)
+	deadline := time.Now().Add(10 * time.Second)
+	for s.background.W != want.w || s.background.H != want.h {
+		if time.Now().After(deadline) {
+			t.Fatalf("the background is %d by %d, want %d by %d", s.background.W, s.background.H, want.w, want.h)
+		}
+		time.Sleep(time.Millisecond)
+		s.fit(h)
+	}
+	if s.fb.W != 890 || s.fb.H != 545 || s.field != want {
+		t.Errorf("the screen is %d by %d with field %+v", s.fb.W, s.fb.H, s.field)
+	}
+
+	// a new theme is a new background at the same size
+	old := s.background
+	s.theme = (s.theme + 1) % len(s.themes)
+	for s.background == old {
+		if time.Now().After(deadline) {
+			t.Fatal("the background wasn't made again for the new theme")
+		}
+		time.Sleep(time.Millisecond)
+		s.fit(h)
+	}
+}
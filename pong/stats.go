@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// stats are records kept between games in a json file
+type stats struct {
+	Matches int `json:"matches"`
+	// a rally is how many times the paddles hit the ball in one point
+	LongestRally int     `json:"longest_rally"`
+	Rallies      int     `json:"rallies"`
+	RallyHits    int     `json:"rally_hits"`
+	FastestBall  float32 `json:"fastest_ball"`
+	// streaks are matches won in a row against the computer
+	WinStreak     int `json:"win_streak"`
+	LongestStreak int `json:"longest_streak"`
+	// VsComputer is keyed by difficulty name
+	VsComputer map[string]record `json:"vs_computer"`
+
+	path  string
+	rally int
+}
+
+type record struct {
+	Wins   int `json:"wins"`
+	Losses int `json:"losses"`
+}
+
+// loadStats reads the stats kept in path, a missing file is a fresh start
+func loadStats(path string) (*stats, error) {
+	s := &stats{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+func (s *stats) save() error {
+	return saveJSON(s.path, s)
+}
+
+// saveJSON writes v to a new file and renames it over path, so a crash
+// part way through leaves the old file as it was
+func saveJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file readable only by us, the file it
+	// replaces was made like any other
+	err = f.Chmod(0644)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		// the data has to be on disk before the rename is
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// observe records what happened in an update of g that returned h, where
+// g was in the state prev before it. It saves when a match ends.
+func (s *stats) observe(g *game, h hit, prev gameState) error {
+	if g.networked() {
+		return nil
+	}
+	if prev == play {
+		for _, b := range g.balls[:g.numBalls] {
+			speed := float32(math.Hypot(float64(b.xv), float64(b.yv)))
+			if speed > s.FastestBall {
+				s.FastestBall = speed
+			}
+		}
+	}
+	switch {
+	case h == hitPaddle:
+		s.rally++
+	case h == hitGoal && g.state != play:
+		// the rally only ends when the last ball in play scores
+		s.Rallies++
+		s.RallyHits += s.rally
+		if s.rally > s.LongestRally {
+			s.LongestRally = s.rally
+		}
+		s.rally = 0
+	}
+	if g.state == g.home() {
+		// a match given up loses its rally, whether it goes back to the
+		// title or a tournament's standings
+		s.rally = 0
+	}
+
+	if g.state != gameOver || prev == gameOver {
+		return nil
+	}
+	s.Matches++
+	if g.mode == onePlayer {
+		if s.VsComputer == nil {
+			s.VsComputer = make(map[string]record)
+		}
+		r := s.VsComputer[difficultyNames[g.level]]
+		if g.winner() == 1 {
+			r.Wins++
+			s.WinStreak++
+			if s.WinStreak > s.LongestStreak {
+				s.LongestStreak = s.WinStreak
+			}
+		} else {
+			r.Losses++
+			s.WinStreak = 0
+		}
+		s.VsComputer[difficultyNames[g.level]] = r
+	}
+	return s.save()
+}
+
+// averageRally is the mean number of hits a point
+func (s *stats) averageRally() float32 {
+	if s.Rallies == 0 {
+		return 0
+	}
+	return float32(s.RallyHits) / float32(s.Rallies)
+}
+
+// drawStats shows the stats screen
+func (g *game) drawStats(fb *framebuffer.Framebuffer, f field) {
+	white := palette.Color{R: 255, G: 255, B: 255}
+	s := g.stats
+	x := getCenter().x
+	f.text(fb, "statistics", pos{x, 60}, 8, white)
+	lines := []string{
+		"matches played: " + strconv.Itoa(s.Matches),
+		"longest rally: " + strconv.Itoa(s.LongestRally) + " hits",
+		fmt.Sprintf("average rally: %.1f hits", s.averageRally()),
+		"fastest ball: " + strconv.Itoa(int(s.FastestBall)) + " pixels a second",
+		"longest win streak: " + strconv.Itoa(s.LongestStreak) + ", now " + strconv.Itoa(s.WinStreak),
+		"",
+		"against the computer",
+	}
+	for _, name := range difficultyNames {
+		r := s.VsComputer[name]
+		lines = append(lines, name+": won "+strconv.Itoa(r.Wins)+", lost "+strconv.Itoa(r.Losses))
+	}
+	for i, line := range lines {
+		f.text(fb, line, pos{x, 160 + float32(i*36)}, 3, white)
+	}
+	f.text(fb, "press space", pos{x, float32(winHeight - 50)}, 3, white)
+}
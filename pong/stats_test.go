@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+func TestStatsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.json")
+	s, err := loadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, &stats{path: path}) {
+		t.Fatalf("a missing file loaded %+v", s)
+	}
+
+	s.Matches = 3
+	s.LongestRally, s.Rallies, s.RallyHits = 12, 20, 97
+	s.FastestBall = 1234.5
+	s.WinStreak, s.LongestStreak = 1, 2
+	s.VsComputer = map[string]record{"easy": {2, 0}, "hard": {0, 1}}
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("saved with mode %v, want %v", info.Mode().Perm(), os.FileMode(0644))
+	}
+	loaded, err := loadStats(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("loaded %+v, saved %+v", loaded, s)
+	}
+
+	// saving again replaces the file and leaves nothing behind
+	s.Matches++
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*"))
+	if len(files) != 1 {
+		t.Errorf("left %q", files)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadStats(path); err == nil {
+		t.Error("loaded a broken file")
+	}
+}
+
+// observeSteps runs n updates of g with keys held, as the game loop does
+func observeSteps(t *testing.T, g *game, s *stats, n int, keys ...input.Key) {
+	t.Helper()
+	inputs := newInputs()
+	for i := 0; i < n; i++ {
+		inputs.Update(input.NewSnapshot(keys...))
+		prev := g.state
+		h := g.update(inputs, tick)
+		inputs.Tick()
+		if err := s.observe(g, h, prev); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRallies counts the hits in a multi-ball rally, which only ends when
+// the last ball is out
+func TestRallies(t *testing.T) {
+	s := &stats{path: filepath.Join(t.TempDir(), "stats.json")}
+	g := arcadeGame()
+	// player 2 hits the ball back
+	g.player2.y = 300
+	b := &g.balls[0]
+	b.pos = pos{g.player2.x - g.player2.w/2 - b.radius - 1, 300}
+	observeSteps(t, g, s, 1)
+	if s.rally != 1 || b.xv >= 0 {
+		t.Fatalf("the hit made a rally of %d with the ball going %v", s.rally, b.xv)
+	}
+
+	// two more balls score on the right while the first is in play
+	g.numBalls = 3
+	g.balls[1] = *b
+	g.balls[1].pos, g.balls[1].xv = pos{float32(winWidth) - 1, 500}, serveSpeed
+	g.balls[2] = g.balls[1]
+	g.balls[2].y = 550
+	observeSteps(t, g, s, 1)
+	if g.numBalls != 1 || s.Rallies != 0 || s.rally != 1 {
+		t.Fatalf("%d balls left, %d rallies, this one at %d hits, want 1, 0 and 1", g.numBalls, s.Rallies, s.rally)
+	}
+
+	// the last ball out ends the rally
+	g.player1.y = 100
+	b.pos = pos{1, 500}
+	observeSteps(t, g, s, 1)
+	if g.state != point {
+		t.Fatalf("the last ball out left the game in state %d", g.state)
+	}
+	if s.Rallies != 1 || s.RallyHits != 1 || s.LongestRally != 1 || s.rally != 0 {
+		t.Errorf("after one rally of 1 hit: %d rallies, %d hits, longest %d, %d counting", s.Rallies, s.RallyHits, s.LongestRally, s.rally)
+	}
+	if s.FastestBall < serveSpeed {
+		t.Errorf("fastest ball %v, want at least %v", s.FastestBall, serveSpeed)
+	}
+
+	// a point with no hits is a rally too
+	g.state = play
+	g.balls[0].pos, g.balls[0].xv, g.balls[0].yv = pos{1, 500}, -serveSpeed, 0
+	observeSteps(t, g, s, 1)
+	if s.Rallies != 2 || s.RallyHits != 1 || s.averageRally() != 0.5 {
+		t.Errorf("after two rallies: %d rallies, %d hits, average %v", s.Rallies, s.RallyHits, s.averageRally())
+	}
+}
+
+// TestAbandonedRally gives up a match part way through a rally, which
+// mustn't carry its hits into the next match
+func TestAbandonedRally(t *testing.T) {
+	dir := t.TempDir()
+	tr, err := newTournament(filepath.Join(dir, "tournament.json"), knockout, []string{"ann", "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		tournament *tournament
+		home       gameState
+	}{
+		{nil, title},
+		{tr, standings},
+	} {
+		s := &stats{path: filepath.Join(dir, "stats.json")}
+		g := arcadeGame()
+		g.tournament = tt.tournament
+		g.player2.y = 300
+		b := &g.balls[0]
+		b.pos = pos{g.player2.x - g.player2.w/2 - b.radius - 1, 300}
+		observeSteps(t, g, s, 1)
+		observeSteps(t, g, s, 1, input.KeyP)
+		observeSteps(t, g, s, 1, input.KeyQ)
+		if g.state != tt.home || s.rally != 0 {
+			t.Errorf("giving up went to state %d with a rally of %d carried, want state %d and 0", g.state, s.rally, tt.home)
+		}
+		if s.Rallies != 0 || s.RallyHits != 0 {
+			t.Errorf("giving up counted %d rallies of %d hits", s.Rallies, s.RallyHits)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Online play over tcp, for networks that let tcp through but not udp. A
+// stream has no packets, so each message goes with its length in front
+// and the host and player see the same packets they would over udp. tcp
+// resends what's lost, holding up every snapshot behind it, so udp plays
+// better where it works.
+
+var errDeadline = errors.New("deadlines aren't supported over tcp")
+
+// streamConn is a packet connection over tcp. The host's takes streams
+// from any number of players, the joining player's has the one stream to
+// the host.
+type streamConn struct {
+	ln      net.Listener
+	local   net.Addr
+	packets chan packet
+
+	mu      sync.Mutex
+	streams map[string]net.Conn
+	// err is why reading stopped, set before done is closed
+	err  error
+	done chan struct{}
+	once sync.Once
+}
+
+func newStreamConn(ln net.Listener, local net.Addr) *streamConn {
+	return &streamConn{ln: ln, local: local, packets: make(chan packet, 256), streams: make(map[string]net.Conn), done: make(chan struct{})}
+}
+
+// listenTCP waits for players on addr
+func listenTCP(addr string) (*streamConn, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := newStreamConn(ln, ln.Addr())
+	go c.accept()
+	return c, nil
+}
+
+// dialTCP connects to a host at addr
+func dialTCP(addr string) (*streamConn, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := newStreamConn(nil, conn.LocalAddr())
+	c.add(conn)
+	return c, nil
+}
+
+// remote is the address packets from the host come from, when c was
+// dialed
+func (c *streamConn) remote() net.Addr {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range c.streams {
+		return s.RemoteAddr()
+	}
+	return nil
+}
+
+func (c *streamConn) accept() {
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			c.stop(err)
+			return
+		}
+		c.add(conn)
+	}
+}
+
+func (c *streamConn) add(conn net.Conn) {
+	c.mu.Lock()
+	c.streams[conn.RemoteAddr().String()] = conn
+	c.mu.Unlock()
+	go c.read(conn)
+}
+
+// read passes on each message from conn until it ends. A host carries on
+// without a player who has gone, the joining player stops with the host.
+func (c *streamConn) read(conn net.Conn) {
+	addr := conn.RemoteAddr()
+	var size [2]byte
+	for {
+		_, err := io.ReadFull(conn, size[:])
+		var data []byte
+		if err == nil {
+			data = make([]byte, binary.LittleEndian.Uint16(size[:]))
+			_, err = io.ReadFull(conn, data)
+		}
+		if err != nil {
+			c.drop(conn)
+			if c.ln == nil {
+				c.stop(err)
+			}
+			return
+		}
+		select {
+		case c.packets <- packet{data, addr}:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *streamConn) drop(conn net.Conn) {
+	c.mu.Lock()
+	if c.streams[conn.RemoteAddr().String()] == conn {
+		delete(c.streams, conn.RemoteAddr().String())
+	}
+	c.mu.Unlock()
+	conn.Close()
+}
+
+// stop ends reading with err, the first reason given is kept
+func (c *streamConn) stop(err error) {
+	c.once.Do(func() {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
+
+// ReadFrom waits for the next message, cutting it short if p is, as udp
+// does
+func (c *streamConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case pk := <-c.packets:
+		return copy(p, pk.data), pk.addr, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return 0, nil, c.err
+	}
+}
+
+// WriteTo sends p down the stream to addr. Like a udp packet to a player
+// who has gone, a message with no stream to go down is lost without an
+// error, the other side's timeout notices.
+func (c *streamConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if len(p) > 0xffff {
+		return 0, errors.New("message too long for its length")
+	}
+	msg := make([]byte, 2+len(p))
+	binary.LittleEndian.PutUint16(msg, uint16(len(p)))
+	copy(msg[2:], p)
+
+	// one write at a time, so messages aren't mixed up in a stream
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	conn := c.streams[addr.String()]
+	if conn == nil {
+		return len(p), nil
+	}
+	if _, err := conn.Write(msg); err != nil {
+		delete(c.streams, addr.String())
+		conn.Close()
+	}
+	return len(p), nil
+}
+
+func (c *streamConn) Close() error {
+	c.stop(net.ErrClosed)
+	var err error
+	if c.ln != nil {
+		err = c.ln.Close()
+	}
+	c.mu.Lock()
+	for _, conn := range c.streams {
+		conn.Close()
+	}
+	c.mu.Unlock()
+	return err
+}
+
+func (c *streamConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *streamConn) SetDeadline(t time.Time) error      { return errDeadline }
+func (c *streamConn) SetReadDeadline(t time.Time) error  { return errDeadline }
+func (c *streamConn) SetWriteDeadline(t time.Time) error { return errDeadline }
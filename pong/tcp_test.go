@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// readWithin reads the next message from c, failing after a second
+func readWithin(t *testing.T, c net.PacketConn) ([]byte, net.Addr) {
+	t.Helper()
+	type result struct {
+		data []byte
+		addr net.Addr
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := c.ReadFrom(buf)
+		done <- result{buf[:n], addr, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		return r.data, r.addr
+	case <-time.After(time.Second):
+		t.Fatal("nothing arrived")
+		return nil, nil
+	}
+}
+
+func TestStreamConn(t *testing.T) {
+	host, err := listenTCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Close()
+	var players []*streamConn
+	for i := 0; i < 2; i++ {
+		p, err := dialTCP(host.LocalAddr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer p.Close()
+		players = append(players, p)
+	}
+
+	// messages keep their edges however the stream splits them, and each
+	// comes from its own player
+	long := bytes.Repeat([]byte{7}, 1000)
+	for i, p := range players {
+		if _, err := p.WriteTo([]byte{byte(i)}, p.remote()); err != nil {
+			t.Fatal(err)
+		}
+		p.WriteTo(long, p.remote())
+	}
+	from := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		data, addr := readWithin(t, host)
+		if len(data) == 1 {
+			from[addr.String()] = int(data[0])
+		} else if !bytes.Equal(data, long) {
+			t.Fatalf("a message of %d bytes arrived as %d", len(long), len(data))
+		}
+	}
+	if len(from) != 2 {
+		t.Fatalf("messages came from %v", from)
+	}
+
+	// the host answers each player on their own stream
+	for addr, i := range from {
+		a, _ := net.ResolveTCPAddr("tcp", addr)
+		host.WriteTo([]byte{byte(10 + i)}, a)
+	}
+	for i, p := range players {
+		data, addr := readWithin(t, p)
+		if len(data) != 1 || int(data[0]) != 10+i || addr.String() != p.remote().String() {
+			t.Errorf("player %d heard %v from %v", i, data, addr)
+		}
+	}
+
+	// a player leaving is no error to the host, what is sent to them is
+	// lost, and the host still hears the others
+	gone := players[0].LocalAddr()
+	players[0].Close()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := host.WriteTo([]byte{1}, gone); err != nil {
+		t.Errorf("writing to a player who left: %v", err)
+	}
+	players[1].WriteTo([]byte{2}, players[1].remote())
+	if data, _ := readWithin(t, host); !bytes.Equal(data, []byte{2}) {
+		t.Errorf("after a player left the host heard %v", data)
+	}
+
+	// the host going stops the player reading
+	host.Close()
+	buf := make([]byte, 10)
+	if _, _, err := players[1].ReadFrom(buf); err == nil {
+		t.Error("read from a host that has gone")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/stephen-mahon/games-with-go/font"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/noise"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/sprite"
+)
+
+// themeDir holds a directory for each theme as well as the classic look
+const themeDir = "themes"
+
+// theme is how the game looks. Anything a theme leaves out is drawn as
+// the classic theme draws it.
+type theme struct {
+	name string
+	// paddle and ball are stretched over the shapes they replace
+	paddle, ball *sprite.Sprite
+	// image is stretched over the field, without one the background is
+	// noise coloured by gradient
+	image    *sprite.Sprite
+	noise    noise.NoiseType
+	gradient palette.Gradient
+	// font draws the scores
+	font *font.Font
+}
+
+func classicTheme() *theme {
+	return &theme{
+		name:     "classic",
+		noise:    noise.TURBULENCE,
+		gradient: palette.NewGradient(palette.Color{R: 255}, palette.Color{}),
+	}
+}
+
+var noiseNames = map[string]noise.NoiseType{"fbm": noise.FBM, "turbulence": noise.TURBULENCE}
+
+// loadTheme reads dir/theme.txt. Each line is a part of the game and what
+// to draw it with, files are in dir and // starts a comment.
+//
+//	paddle      paddle.png
+//	ball        ball.png
+//	background  image field.png
+//	background  noise turbulence fire     // a palette preset or gradient file
+//	font        font.png font.txt
+func loadTheme(dir string) (*theme, error) {
+	f, err := os.Open(filepath.Join(dir, "theme.txt"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := classicTheme()
+	t.name = filepath.Base(dir)
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "//"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "paddle" && len(fields) == 2:
+			t.paddle, err = sprite.Load(path(fields[1]))
+		case fields[0] == "ball" && len(fields) == 2:
+			t.ball, err = sprite.Load(path(fields[1]))
+		case fields[0] == "background" && len(fields) == 3 && fields[1] == "image":
+			t.image, err = sprite.Load(path(fields[2]))
+		case fields[0] == "background" && len(fields) == 4 && fields[1] == "noise":
+			n, ok := noiseNames[fields[2]]
+			if !ok {
+				err = fmt.Errorf("unknown noise %q", fields[2])
+				break
+			}
+			t.image, t.noise = nil, n
+			t.gradient, err = palette.Preset(fields[3])
+			if err != nil {
+				t.gradient, err = palette.LoadFile(path(fields[3]))
+			}
+		case fields[0] == "font" && len(fields) == 3:
+			t.font, err = font.Load(path(fields[1]), path(fields[2]))
+		default:
+			err = fmt.Errorf("can't read %q", text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("theme %s: line %d: %v", t.name, line, err)
+		}
+	}
+	return t, scanner.Err()
+}
+
+// loadThemes reads every theme in dir, after the classic theme. Having no
+// themes directory is fine, there is just the classic theme.
+func loadThemes(dir string) ([]*theme, error) {
+	themes := []*theme{classicTheme()}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return themes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t, err := loadTheme(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		themes = append(themes, t)
+	}
+	return themes, nil
+}
+
+// background renders the theme's background for a field w by h pixels.
+// Noise is sampled in field units so it looks the same at any size.
+func (t *theme) background(w, h int, scale float32) *framebuffer.Framebuffer {
+	background := framebuffer.New(w, h)
+	if t.image != nil {
+		sprite.Draw(background, t.image, 0, 0, stretch(t.image, w, h))
+		return background
+	}
+	noise, min, max := noise.MakeNoise(t.noise, 0.001/scale, 0.2, 2, 3, w, h)
+	t.gradient.Draw(noise, min, max, background.Pixels)
+	return background
+}
+
+// stretch is the options to draw s w by h pixels
+func stretch(s *sprite.Sprite, w, h int) sprite.Options {
+	return sprite.Options{ScaleX: float32(w) / float32(s.W), ScaleY: float32(h) / float32(s.H), Filter: sprite.Bilinear}
+}
+
+// scoreFont is the font for the scores and the scale that makes it as
+// tall as the classic font would be at scale
+func (t *theme) scoreFont(scale int) (*font.Font, int) {
+	classic := font.Default()
+	if t.font == nil || t.font.LineHeight <= 0 {
+		return classic, scale
+	}
+	scale = scale * classic.LineHeight / t.font.LineHeight
+	if scale < 1 {
+		scale = 1
+	}
+	return t.font, scale
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/font"
+)
+
+func TestShippedThemes(t *testing.T) {
+	themes, err := loadThemes(themeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, th := range themes {
+		names = append(names, th.name)
+	}
+	if got := strings.Join(names, " "); got != "classic neon ocean" {
+		t.Errorf("loaded themes %s", got)
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, text string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// gradient files use # for colours, so // is the comment everywhere
+	write("gradient.txt", "0 #102030 // dark\n1 #ffffff\n")
+	write("theme.txt", "// a test theme\nbackground noise fbm gradient.txt   // from the file\n")
+	th, err := loadTheme(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.name != filepath.Base(dir) || th.image != nil {
+		t.Errorf("loaded %+v", th)
+	}
+	if c := th.gradient.At(0); c.R != 0x10 || c.G != 0x20 || c.B != 0x30 {
+		t.Errorf("the gradient starts at %v", c)
+	}
+
+	write("theme.txt", "paddle missing.png\n")
+	if _, err := loadTheme(dir); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("a missing sprite gave %v", err)
+	}
+	write("theme.txt", "# not a comment\n")
+	if _, err := loadTheme(dir); err == nil {
+		t.Error("a line starting with # loaded")
+	}
+
+	// a font with no glyphs has no line height to scale the score by
+	var sheet bytes.Buffer
+	if err := png.Encode(&sheet, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	write("font.png", sheet.String())
+	write("font.txt", "")
+	write("theme.txt", "font font.png font.txt\n")
+	if _, err := loadTheme(dir); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("an empty font gave %v", err)
+	}
+}
+
+func TestScoreFont(t *testing.T) {
+	th := classicTheme()
+	if f, scale := th.scoreFont(10); f != font.Default() || scale != 10 {
+		t.Errorf("the classic theme scores at %d", scale)
+	}
+	// a font made without LoadMetrics can still have no line height
+	th.font = &font.Font{}
+	if f, scale := th.scoreFont(10); f != font.Default() || scale != 10 {
+		t.Errorf("a font with no line height scores at %d", scale)
+	}
+	th.font = &font.Font{LineHeight: font.Default().LineHeight * 2}
+	if f, scale := th.scoreFont(10); f != th.font || scale != 5 {
+		t.Errorf("a font twice as tall scores at %d, want 5", scale)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// A tournament is a list of matches between named players, played one
+// after another on the same machine. It is saved after every match so it
+// can carry on after the game is closed.
+
+const (
+	// knockout is single elimination, the winners of each round meet in
+	// the next until one is left
+	knockout = "knockout"
+	// league has everyone play everyone once
+	league = "league"
+)
+
+// noPlayer is the other side of a bye, and the winner of a match not yet
+// played
+const noPlayer = -1
+
+type tournament struct {
+	Format  string   `json:"format"`
+	Players []string `json:"players"`
+	// Matches are in the order they are played. A knockout gets the next
+	// round's matches when a round is over.
+	Matches []match `json:"matches"`
+
+	path string
+}
+
+type match struct {
+	Round int `json:"round"`
+	// P1 and P2 index Players, P2 is noPlayer for a bye
+	P1     int `json:"p1"`
+	P2     int `json:"p2"`
+	Winner int `json:"winner"`
+	Score1 int `json:"score1"`
+	Score2 int `json:"score2"`
+}
+
+// newTournament draws up the matches for players in format, saved to path
+func newTournament(path, format string, players []string) (*tournament, error) {
+	if len(players) < 2 {
+		return nil, errors.New("a tournament needs at least two players")
+	}
+	t := &tournament{Format: format, Players: players, path: path}
+	switch format {
+	case knockout:
+		// byes fill the first round up to a power of two, the first
+		// players named get them
+		size := 1
+		for size < len(players) {
+			size *= 2
+		}
+		for i := 0; i < size/2; i++ {
+			t.add(1, i, size-1-i)
+		}
+	case league:
+		t.roundRobin()
+	default:
+		return nil, fmt.Errorf("unknown tournament format %q", format)
+	}
+	return t, nil
+}
+
+// loadTournament carries on the tournament saved in path
+func loadTournament(path string) (*tournament, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &tournament{path: path}
+	err = json.Unmarshal(data, t)
+	if err == nil {
+		err = t.check()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// check makes sure a loaded tournament only names players it has, and
+// that every result is a win for someone playing in the match
+func (t *tournament) check() error {
+	if t.Format != knockout && t.Format != league {
+		return fmt.Errorf("unknown tournament format %q", t.Format)
+	}
+	if len(t.Players) < 2 || len(t.Matches) == 0 {
+		return errors.New("not enough players or matches")
+	}
+	valid := func(p int) bool {
+		return p == noPlayer || p >= 0 && p < len(t.Players)
+	}
+	for _, m := range t.Matches {
+		if m.P1 < 0 || !valid(m.P1) || !valid(m.P2) || !valid(m.Winner) {
+			return fmt.Errorf("match in round %d has a player that isn't in the tournament", m.Round)
+		}
+		if m.P2 == noPlayer && m.Winner != m.P1 {
+			return fmt.Errorf("bye in round %d doesn't put %s through", m.Round, t.Players[m.P1])
+		}
+		if m.Winner != noPlayer && m.Winner != m.P1 && m.Winner != m.P2 {
+			return fmt.Errorf("match in round %d is won by %s, who didn't play in it", m.Round, t.Players[m.Winner])
+		}
+	}
+	return nil
+}
+
+// openTournament carries on the tournament in path, or starts a new one
+// between the comma separated players. A new tournament won't replace one
+// already in path.
+func openTournament(path, format, players string, resume bool) (*tournament, error) {
+	if resume {
+		return loadTournament(path)
+	}
+	_, err := os.Stat(path)
+	if err == nil {
+		return nil, fmt.Errorf("%s already has a tournament, carry it on with -resume or remove it", path)
+	}
+	var names []string
+	for _, name := range strings.Split(players, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	t, err := newTournament(path, format, names)
+	if err != nil {
+		return nil, err
+	}
+	return t, t.save()
+}
+
+func (t *tournament) save() error {
+	return saveJSON(t.path, t)
+}
+
+// add puts on a match, a player numbered past the last is a bye and the
+// other player goes through
+func (t *tournament) add(round, p1, p2 int) {
+	m := match{Round: round, P1: p1, P2: p2, Winner: noPlayer}
+	if p2 >= len(t.Players) {
+		m.P2, m.Winner = noPlayer, p1
+	}
+	t.Matches = append(t.Matches, m)
+}
+
+// roundRobin pairs everyone with everyone by the circle method, one player
+// stays put while the rest turn round them a place each round
+func (t *tournament) roundRobin() {
+	n := len(t.Players)
+	if n%2 == 1 {
+		// the odd one out each round sits it out
+		n++
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	for round := 1; round < n; round++ {
+		for i := 0; i < n/2; i++ {
+			p1, p2 := order[i], order[n-1-i]
+			if p1 < len(t.Players) && p2 < len(t.Players) {
+				t.Matches = append(t.Matches, match{Round: round, P1: p1, P2: p2, Winner: noPlayer})
+			}
+		}
+		last := order[n-1]
+		copy(order[2:], order[1:n-1])
+		order[1] = last
+	}
+}
+
+// next is the index of the next match to play, -1 once the tournament is
+// over
+func (t *tournament) next() int {
+	for i, m := range t.Matches {
+		if m.Winner == noPlayer {
+			return i
+		}
+	}
+	return -1
+}
+
+// record gives the next match its result and draws up the next knockout
+// round when it finishes one
+func (t *tournament) record(score1, score2 int) {
+	i := t.next()
+	if i < 0 {
+		return
+	}
+	m := &t.Matches[i]
+	m.Score1, m.Score2 = score1, score2
+	m.Winner = m.P1
+	if score2 > score1 {
+		m.Winner = m.P2
+	}
+	if t.Format != knockout || t.next() >= 0 {
+		return
+	}
+	round := t.Matches[len(t.Matches)-1].Round
+	var winners []int
+	for _, m := range t.Matches {
+		if m.Round == round {
+			winners = append(winners, m.Winner)
+		}
+	}
+	for j := 0; j+1 < len(winners); j += 2 {
+		t.Matches = append(t.Matches, match{Round: round + 1, P1: winners[j], P2: winners[j+1], Winner: noPlayer})
+	}
+}
+
+// champion is the winner of the tournament, noPlayer until it is over
+func (t *tournament) champion() int {
+	if t.next() >= 0 {
+		return noPlayer
+	}
+	if t.Format == knockout {
+		return t.Matches[len(t.Matches)-1].Winner
+	}
+	return t.table()[0].player
+}
+
+type standing struct {
+	player, won, lost, pointsFor, pointsAgainst int
+}
+
+// table is the players by matches won, then by points won less points lost
+func (t *tournament) table() []standing {
+	table := make([]standing, len(t.Players))
+	for i := range table {
+		table[i].player = i
+	}
+	for _, m := range t.Matches {
+		if m.Winner == noPlayer || m.P2 == noPlayer {
+			continue
+		}
+		a, b := &table[m.P1], &table[m.P2]
+		a.pointsFor += m.Score1
+		a.pointsAgainst += m.Score2
+		b.pointsFor += m.Score2
+		b.pointsAgainst += m.Score1
+		if m.Winner == m.P1 {
+			a.won++
+			b.lost++
+		} else {
+			b.won++
+			a.lost++
+		}
+	}
+	sort.SliceStable(table, func(i, j int) bool {
+		if table[i].won != table[j].won {
+			return table[i].won > table[j].won
+		}
+		return table[i].pointsFor-table[i].pointsAgainst > table[j].pointsFor-table[j].pointsAgainst
+	})
+	return table
+}
+
+// observe records the match when g's match ends, g having been in the
+// state prev before its last update
+func (t *tournament) observe(g *game, prev gameState) error {
+	if g.state != gameOver || prev == gameOver {
+		return nil
+	}
+	t.record(g.player1.score, g.player2.score)
+	return t.save()
+}
+
+// startNext sets g up to play the tournament's next match
+func (g *game) startNext() {
+	i := g.tournament.next()
+	if i < 0 {
+		return
+	}
+	m := g.tournament.Matches[i]
+	g.names = [2]string{g.tournament.Players[m.P1], g.tournament.Players[m.P2]}
+	g.newMatch()
+}
+
+// drawStandings shows the league table or the knockout's results so far,
+// and who plays next
+func (g *game) drawStandings(fb *framebuffer.Framebuffer, f field) {
+	white := palette.Color{R: 255, G: 255, B: 255}
+	t := g.tournament
+	x := getCenter().x
+	f.text(fb, t.Format, pos{x, 40}, 8, white)
+
+	var lines []string
+	if t.Format == league {
+		for i, s := range t.table() {
+			lines = append(lines, strconv.Itoa(i+1)+". "+t.Players[s.player]+
+				fmt.Sprintf("  won %d lost %d  points %d-%d", s.won, s.lost, s.pointsFor, s.pointsAgainst))
+		}
+	} else {
+		for _, m := range t.Matches {
+			switch {
+			case m.P2 == noPlayer:
+				lines = append(lines, fmt.Sprintf("round %d: %s has a bye", m.Round, t.Players[m.P1]))
+			case m.Winner != noPlayer:
+				lines = append(lines, fmt.Sprintf("round %d: %s %d-%d %s", m.Round, t.Players[m.P1], m.Score1, m.Score2, t.Players[m.P2]))
+			}
+		}
+	}
+	// keep the latest lines when there are more than fit
+	const maxLines = 12
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	for i, line := range lines {
+		f.text(fb, line, pos{x, 120 + float32(i*28)}, 2, white)
+	}
+
+	if c := t.champion(); c != noPlayer {
+		f.text(fb, t.Players[c]+" wins the "+t.Format, pos{x, float32(winHeight - 110)}, 5, white)
+		f.text(fb, "press space", pos{x, float32(winHeight - 50)}, 3, white)
+		return
+	}
+	m := t.Matches[t.next()]
+	f.text(fb, "next: "+t.Players[m.P1]+" vs "+t.Players[m.P2], pos{x, float32(winHeight - 120)}, 4, white)
+	controls := "keyboard"
+	if g.mode == twoPlayerPads {
+		controls = "controllers"
+	}
+	f.text(fb, "playing with "+controls+", left and right to change", pos{x, float32(winHeight - 75)}, 2, white)
+	f.text(fb, "press space to play, q to leave", pos{x, float32(winHeight - 50)}, 3, white)
+}
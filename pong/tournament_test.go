@@ -0,0 +1,276 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+func TestKnockout(t *testing.T) {
+	players := []string{"ann", "bob", "cat", "dan", "eve"}
+	tr, err := newTournament("", knockout, players)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// five fill a bracket of eight, the first three named get byes
+	if len(tr.Matches) != 4 {
+		t.Fatalf("first round has %d matches, want 4", len(tr.Matches))
+	}
+	for i, m := range tr.Matches[:3] {
+		if m.P1 != i || m.P2 != noPlayer || m.Winner != i {
+			t.Errorf("match %d is %+v, want a bye for %s", i, m, players[i])
+		}
+	}
+	if m := tr.Matches[3]; m.P1 != 3 || m.P2 != 4 || tr.next() != 3 {
+		t.Errorf("the only first round match is %+v", m)
+	}
+
+	// eve beats dan, then the second round is the bye winners and eve
+	tr.record(1, 3)
+	if len(tr.Matches) != 6 {
+		t.Fatalf("%d matches after the first round, want 6", len(tr.Matches))
+	}
+	if a, b := tr.Matches[4], tr.Matches[5]; a.Round != 2 || a.P1 != 0 || a.P2 != 1 || b.P1 != 2 || b.P2 != 4 {
+		t.Errorf("second round is %+v and %+v", a, b)
+	}
+	if tr.champion() != noPlayer {
+		t.Error("a champion before the final")
+	}
+	tr.record(3, 0)
+	tr.record(2, 3)
+	if m := tr.Matches[len(tr.Matches)-1]; len(tr.Matches) != 7 || m.Round != 3 || m.P1 != 0 || m.P2 != 4 {
+		t.Fatalf("the final is %+v", m)
+	}
+	tr.record(0, 3)
+	if c := tr.champion(); c != 4 {
+		t.Errorf("champion is %d, want eve", c)
+	}
+	if tr.next() != -1 {
+		t.Error("a match to play after the final")
+	}
+	// recording after the end changes nothing
+	tr.record(3, 0)
+	if len(tr.Matches) != 7 || tr.champion() != 4 {
+		t.Error("a result after the final was recorded")
+	}
+}
+
+func TestLeague(t *testing.T) {
+	for n := 2; n <= 7; n++ {
+		players := make([]string, n)
+		for i := range players {
+			players[i] = string(rune('a' + i))
+		}
+		tr, err := newTournament("", league, players)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tr.Matches) != n*(n-1)/2 {
+			t.Errorf("%d players play %d matches, want %d", n, len(tr.Matches), n*(n-1)/2)
+		}
+		met := map[[2]int]bool{}
+		played := map[[2]int]bool{}
+		for _, m := range tr.Matches {
+			pair := [2]int{m.P1, m.P2}
+			if m.P1 > m.P2 {
+				pair = [2]int{m.P2, m.P1}
+			}
+			if m.P1 == m.P2 || met[pair] {
+				t.Errorf("%d players: %s and %s meet again", n, players[m.P1], players[m.P2])
+			}
+			met[pair] = true
+			for _, p := range pair {
+				if played[[2]int{m.Round, p}] {
+					t.Errorf("%d players: %s plays twice in round %d", n, players[p], m.Round)
+				}
+				played[[2]int{m.Round, p}] = true
+			}
+		}
+	}
+}
+
+func TestLeagueTable(t *testing.T) {
+	tr, err := newTournament("", league, []string{"ann", "bob", "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every match goes to the player named first
+	for i := range tr.Matches {
+		m := tr.Matches[i]
+		if m.P1 < m.P2 {
+			tr.record(3, i)
+		} else {
+			tr.record(i, 3)
+		}
+	}
+	table := tr.table()
+	want := []standing{
+		{player: 0, won: 2},
+		{player: 1, won: 1, lost: 1},
+		{player: 2, lost: 2},
+	}
+	for i := range want {
+		if table[i].player != want[i].player || table[i].won != want[i].won || table[i].lost != want[i].lost {
+			t.Errorf("place %d is %+v, want %+v", i+1, table[i], want[i])
+		}
+	}
+	if c := tr.champion(); c != 0 {
+		t.Errorf("champion is %d, want ann", c)
+	}
+
+	// level on wins, points decide
+	tr, _ = newTournament("", league, []string{"ann", "bob"})
+	tr.Players = append(tr.Players, "cat")
+	tr.Matches = []match{
+		{Round: 1, P1: 0, P2: 1, Winner: noPlayer},
+		{Round: 2, P1: 1, P2: 2, Winner: noPlayer},
+		{Round: 3, P1: 2, P2: 0, Winner: noPlayer},
+	}
+	tr.record(3, 2)
+	tr.record(3, 0)
+	tr.record(3, 0)
+	if table := tr.table(); table[0].player != 1 || table[1].player != 2 || table[2].player != 0 {
+		t.Errorf("table on points is %+v", table)
+	}
+}
+
+func TestTournamentCheck(t *testing.T) {
+	players := []string{"ann", "bob", "cat"}
+	bad := map[string][]match{
+		"player out of range": {{Round: 1, P1: 0, P2: 3, Winner: noPlayer}},
+		"no first player":     {{Round: 1, P1: noPlayer, P2: 1, Winner: 1}},
+		"unplayed bye":        {{Round: 1, P1: 0, P2: noPlayer, Winner: noPlayer}},
+		"bye won by another":  {{Round: 1, P1: 0, P2: noPlayer, Winner: 1}},
+		"won by someone else": {{Round: 1, P1: 0, P2: 1, Winner: 2}},
+		"winner out of range": {{Round: 1, P1: 0, P2: 1, Winner: 5}},
+		"no matches":          {},
+	}
+	for name, matches := range bad {
+		tr := &tournament{Format: knockout, Players: players, Matches: matches}
+		if err := tr.check(); err == nil {
+			t.Errorf("%s passed the check", name)
+		}
+	}
+
+	good := &tournament{Format: knockout, Players: players, Matches: []match{
+		{Round: 1, P1: 0, P2: noPlayer, Winner: 0},
+		{Round: 1, P1: 1, P2: 2, Winner: 2, Score1: 1, Score2: 3},
+		{Round: 2, P1: 0, P2: 2, Winner: noPlayer},
+	}}
+	if err := good.check(); err != nil {
+		t.Error(err)
+	}
+	good.Format = "cup"
+	if err := good.check(); err == nil {
+		t.Error("an unknown format passed the check")
+	}
+}
+
+func TestOpenTournament(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tournament.json")
+	tr, err := openTournament(path, knockout, " ann, bob ,,cat", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tr.Players, " ") != "ann bob cat" {
+		t.Errorf("players are %q", tr.Players)
+	}
+	if _, err := openTournament(path, league, "dan,eve", false); err == nil {
+		t.Error("a new tournament replaced the saved one")
+	}
+
+	tr.record(3, 1)
+	if err := tr.save(); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := openTournament(path, "", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed.Matches) != len(tr.Matches) || resumed.next() != tr.next() {
+		t.Errorf("resumed at match %d of %d, want %d of %d", resumed.next(), len(resumed.Matches), tr.next(), len(tr.Matches))
+	}
+
+	// a hand edited file that doesn't add up is refused
+	data, _ := os.ReadFile(path)
+	data = []byte(strings.Replace(string(data), `"winner": 0`, `"winner": 2`, 1))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openTournament(path, "", "", true); err == nil {
+		t.Error("a bye won by another player loaded")
+	}
+	if _, err := openTournament(path, knockout, "ann", false); err == nil {
+		t.Error("opened a tournament over the saved one")
+	}
+}
+
+// TestStandings plays a two player knockout from its standings screen
+func TestStandings(t *testing.T) {
+	dir := t.TempDir()
+	tr, err := newTournament(filepath.Join(dir, "final.json"), knockout, []string{"ann", "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := newGame(normal, 1)
+	g.tournament, g.mode, g.state = tr, twoPlayerKeyboard, standings
+	s := newStepper(g)
+
+	for _, tt := range []struct {
+		key  input.Key
+		want playMode
+	}{
+		{input.KeyRight, twoPlayerPads},
+		{input.KeyRight, twoPlayerKeyboard},
+		{input.KeyLeft, twoPlayerPads},
+		{input.KeyA, twoPlayerKeyboard},
+	} {
+		s.press(tt.key)
+		if g.mode != tt.want || g.state != standings {
+			t.Fatalf("pressing %d changed to mode %d in state %d, want mode %d", tt.key, g.mode, g.state, tt.want)
+		}
+	}
+
+	s.press(input.KeySpace)
+	if g.state != serve || g.names != [2]string{"ann", "bob"} {
+		t.Fatalf("start went to state %d with %q playing", g.state, g.names)
+	}
+	s.press(input.KeySpace)
+	s.score()
+	if g.state != gameOver {
+		t.Fatalf("the winning point went to state %d", g.state)
+	}
+	// the game loop records the result
+	if err := tr.observe(g, play); err != nil {
+		t.Fatal(err)
+	}
+	s.press(input.KeySpace)
+	if g.state != standings || tr.champion() != 0 {
+		t.Fatalf("after the final went to state %d with champion %d", g.state, tr.champion())
+	}
+	// with the tournament won start goes back to the title, and the
+	// matches played from there are no part of it
+	s.press(input.KeySpace)
+	if g.state != title || g.tournament != nil {
+		t.Fatalf("start after the final went to state %d", g.state)
+	}
+	s.press(input.KeySpace)
+	s.press(input.KeyQ)
+	if g.state != title {
+		t.Errorf("back from a match after the tournament went to %d, want the title", g.state)
+	}
+
+	// back leaves a tournament part way through
+	tr, err = newTournament(filepath.Join(dir, "league.json"), league, []string{"ann", "bob", "cat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.tournament, g.state = tr, standings
+	s.press(input.KeyQ)
+	if g.state != title || g.tournament != nil {
+		t.Errorf("back from the standings went to state %d", g.state)
+	}
+}
@@ -0,0 +1,57 @@
+package replay
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// Open wraps p to record to the file named record or to play back the file
+// named replay, whichever is set. It returns the seed the game should use
+// for its random numbers, the recorded one when replaying and one from the
+// clock otherwise. On error p is left open.
+func Open(p platform.Platform, record, replay string) (platform.Platform, int64, error) {
+	seed := time.Now().UnixNano()
+	switch {
+	case record != "" && replay != "":
+		return nil, 0, errors.New("replay: can't record and replay at once")
+	case record != "":
+		f, err := os.Create(record)
+		if err != nil {
+			return nil, 0, err
+		}
+		r, err := NewRecorder(p, f, seed)
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return r, seed, nil
+	case replay != "":
+		f, err := os.Open(replay)
+		if err != nil {
+			return nil, 0, err
+		}
+		pl, err := NewPlayer(p, f)
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return pl, pl.Seed(), nil
+	}
+	return p, seed, nil
+}
+
+// Close closes a platform from Open and reports anything that went wrong
+// with the recording or replay
+func Close(p platform.Platform) error {
+	p.Close()
+	switch p := p.(type) {
+	case *Recorder:
+		return p.Err()
+	case *Player:
+		return p.Err()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// Player is a platform that plays a recording back. Drawing and audio go
+// to the platform it wraps, input, the clock and the window size come from
+// the recording. Poll returns false when the recording runs out or the
+// window is closed.
+type Player struct {
+	platform.Platform
+
+	in    io.Closer
+	dec   decoder
+	seed  int64
+	w, h  int
+	now   time.Time
+	input *input.Snapshot
+	done  bool
+}
+
+// resizer is a platform whose window can be resized
+type resizer interface {
+	Resize(w, h int)
+}
+
+// NewPlayer reads the header of a recording from r and plays it on p. The
+// window is put back to the recorded fullscreen setting, and to the
+// recorded size if p can resize it. Close closes r if it is an io.Closer.
+func NewPlayer(p platform.Platform, r io.Reader) (*Player, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errFormat
+	}
+	pl := &Player{Platform: p, now: time.Unix(0, 0), input: &input.Snapshot{}}
+	if c, ok := r.(io.Closer); ok {
+		pl.in = c
+	}
+	pl.dec.r = bufio.NewReader(gz)
+	hd, err := readHeader(&pl.dec)
+	if err != nil {
+		return nil, err
+	}
+	pl.seed, pl.w, pl.h = hd.seed, hd.w, hd.h
+	if p.Fullscreen() != hd.fullscreen {
+		err = p.SetFullscreen(hd.fullscreen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r, ok := p.(resizer); ok && !hd.fullscreen {
+		r.Resize(hd.w, hd.h)
+	}
+	return pl, nil
+}
+
+// Seed is the random seed the recorded game used
+func (p *Player) Seed() int64 {
+	return p.seed
+}
+
+// next reads the tag of the next record, which must be want
+func (p *Player) next(want byte) bool {
+	if p.done {
+		return false
+	}
+	tag := p.dec.byte()
+	if p.dec.err == io.EOF {
+		p.done = true
+		return false
+	}
+	if tag != want {
+		p.dec.err = fmt.Errorf("replay: game out of step with the recording, wanted %q got %q", want, tag)
+	}
+	if p.dec.err != nil {
+		p.done = true
+		return false
+	}
+	return true
+}
+
+func (p *Player) Poll() bool {
+	if !p.Platform.Poll() || !p.next(pollTag) {
+		return false
+	}
+	s := p.dec.snapshot()
+	if s == nil {
+		p.done = true
+		return false
+	}
+	p.input = s
+	p.w, p.h = p.dec.size()
+	if p.dec.err != nil {
+		p.done = true
+		return false
+	}
+	return true
+}
+
+// Size is the recorded window size, so the game lays itself out and maps
+// the pointer as it did even if the window couldn't be put back
+func (p *Player) Size() (int, int) {
+	return p.w, p.h
+}
+
+func (p *Player) Input() *input.Snapshot {
+	return p.input
+}
+
+// Now gives back the recorded times. Once the recording runs out the clock
+// stops.
+func (p *Player) Now() time.Time {
+	if p.next(timeTag) {
+		p.now = p.now.Add(time.Duration(p.dec.varint()))
+	}
+	return p.now
+}
+
+// Err reports a recording that is damaged or that the game no longer
+// follows
+func (p *Player) Err() error {
+	if p.dec.err == io.EOF {
+		return nil
+	}
+	return p.dec.err
+}
+
+// Close closes the recording and the wrapped platform
+func (p *Player) Close() {
+	if p.in != nil {
+		p.in.Close()
+	}
+	p.Platform.Close()
+}
@@ -0,0 +1,88 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// Recorder is a platform that writes down every input poll and clock read
+// of the platform it wraps
+type Recorder struct {
+	platform.Platform
+
+	out  io.WriteCloser
+	gz   *gzip.Writer
+	enc  encoder
+	last time.Time
+	read bool
+}
+
+// NewRecorder starts a recording of p to w. The game should seed its
+// random numbers with seed, it is saved so a replay can do the same, as are
+// the window's size and fullscreen setting. Close finishes the recording
+// and closes w if it is an io.Closer.
+func NewRecorder(p platform.Platform, w io.Writer, seed int64) (*Recorder, error) {
+	r := &Recorder{Platform: p, gz: gzip.NewWriter(w)}
+	if c, ok := w.(io.WriteCloser); ok {
+		r.out = c
+	}
+	r.enc.w = bufio.NewWriter(r.gz)
+	width, height := p.Size()
+	writeHeader(&r.enc, header{seed, width, height, p.Fullscreen()})
+	return r, r.enc.err
+}
+
+func (r *Recorder) Poll() bool {
+	if !r.Platform.Poll() {
+		return false
+	}
+	r.enc.byte(pollTag)
+	r.enc.snapshot(r.Platform.Input())
+	r.enc.size(r.Platform.Size())
+	return true
+}
+
+func (r *Recorder) Now() time.Time {
+	now := r.Platform.Now()
+	var dt time.Duration
+	if r.read {
+		dt = now.Sub(r.last)
+	}
+	r.last, r.read = now, true
+	r.enc.byte(timeTag)
+	r.enc.varint(int64(dt))
+	return now
+}
+
+// Input is read back through the recording so the game sees exactly what
+// a replay will
+func (r *Recorder) Input() *input.Snapshot {
+	s := r.enc.prev
+	return &s
+}
+
+// Err reports the first error writing the recording
+func (r *Recorder) Err() error {
+	return r.enc.err
+}
+
+// Close finishes the recording and closes the wrapped platform
+func (r *Recorder) Close() {
+	if r.enc.err == nil {
+		r.enc.err = r.enc.w.Flush()
+	}
+	if err := r.gz.Close(); r.enc.err == nil {
+		r.enc.err = err
+	}
+	if r.out != nil {
+		if err := r.out.Close(); r.enc.err == nil {
+			r.enc.err = err
+		}
+	}
+	r.Platform.Close()
+}
@@ -0,0 +1,247 @@
+// Records a game's input and clock to a file and plays it back exactly
+//
+// A recording is a gzipped stream. It starts with a header holding the
+// random seed and the window's size and fullscreen setting, then has one
+// record for every time the game polled input or read the clock, in the
+// order it did so. A poll record holds the window size too, so a game
+// that lays itself out from it sees the same size when replayed. Inputs
+// are stored as changes from the last poll and times as the nanoseconds
+// since the last read, both as varints, so an idle frame costs a handful
+// of bytes before compression. Given the same seed, inputs and times a
+// game steps exactly as it did.
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/stephen-mahon/games-with-go/input"
+)
+
+const magic = "GWGR"
+const version = 2
+
+// record tags
+const (
+	pollTag byte = 'P'
+	timeTag byte = 'T'
+)
+
+var errFormat = errors.New("replay: not a recording")
+
+type encoder struct {
+	w    *bufio.Writer
+	buf  [binary.MaxVarintLen64]byte
+	prev input.Snapshot
+	err  error
+}
+
+func (e *encoder) byte(b byte) {
+	if e.err == nil {
+		e.err = e.w.WriteByte(b)
+	}
+}
+
+func (e *encoder) uvarint(v uint64) {
+	if e.err == nil {
+		_, e.err = e.w.Write(e.buf[:binary.PutUvarint(e.buf[:], v)])
+	}
+}
+
+func (e *encoder) varint(v int64) {
+	if e.err == nil {
+		_, e.err = e.w.Write(e.buf[:binary.PutVarint(e.buf[:], v)])
+	}
+}
+
+// snapshot writes the keys that changed, then the mouse and pads as
+// differences from the last snapshot
+func (e *encoder) snapshot(s *input.Snapshot) {
+	e.uvarint(uint64(len(s.Keys)))
+	var changed []int
+	for k := range s.Keys {
+		if (s.Keys[k] != 0) != (k < len(e.prev.Keys) && e.prev.Keys[k] != 0) {
+			changed = append(changed, k)
+		}
+	}
+	e.uvarint(uint64(len(changed)))
+	last := 0
+	for _, k := range changed {
+		e.uvarint(uint64(k - last))
+		last = k
+	}
+
+	e.varint(int64(s.Mouse.X - e.prev.Mouse.X))
+	e.varint(int64(s.Mouse.Y - e.prev.Mouse.Y))
+	e.uvarint(uint64(s.Mouse.Buttons))
+
+	e.uvarint(uint64(len(s.Pads)))
+	for i, pad := range s.Pads {
+		var prev input.Pad
+		if i < len(e.prev.Pads) {
+			prev = e.prev.Pads[i]
+		}
+		e.uvarint(uint64(pad.Buttons))
+		for a := range pad.Axes {
+			e.varint(int64(pad.Axes[a]) - int64(prev.Axes[a]))
+		}
+	}
+	e.prev = copySnapshot(s)
+}
+
+type decoder struct {
+	r    *bufio.Reader
+	prev input.Snapshot
+	err  error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	var b byte
+	b, d.err = d.r.ReadByte()
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var v uint64
+	v, d.err = binary.ReadUvarint(d.r)
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	var v int64
+	v, d.err = binary.ReadVarint(d.r)
+	return v
+}
+
+func (d *decoder) snapshot() *input.Snapshot {
+	s := copySnapshot(&d.prev)
+	n := int(d.uvarint())
+	if d.err != nil || n > 1<<16 {
+		d.fail()
+		return nil
+	}
+	keys := make([]uint8, n)
+	copy(keys, s.Keys)
+	s.Keys = keys
+
+	changed := int(d.uvarint())
+	k := 0
+	for i := 0; i < changed && d.err == nil; i++ {
+		k += int(d.uvarint())
+		if k >= len(s.Keys) {
+			d.fail()
+			return nil
+		}
+		s.Keys[k] ^= 1
+	}
+
+	s.Mouse.X += int(d.varint())
+	s.Mouse.Y += int(d.varint())
+	s.Mouse.Buttons = uint32(d.uvarint())
+
+	pads := int(d.uvarint())
+	if d.err != nil || pads > 64 {
+		d.fail()
+		return nil
+	}
+	prevPads := s.Pads
+	s.Pads = make([]input.Pad, pads)
+	copy(s.Pads, prevPads)
+	for i := range s.Pads {
+		s.Pads[i].Buttons = uint32(d.uvarint())
+		for a := range s.Pads[i].Axes {
+			s.Pads[i].Axes[a] += int16(d.varint())
+		}
+	}
+	if d.err != nil {
+		return nil
+	}
+	d.prev = s
+	c := copySnapshot(&s)
+	return &c
+}
+
+func (e *encoder) size(w, h int) {
+	e.uvarint(uint64(w))
+	e.uvarint(uint64(h))
+}
+
+func (d *decoder) size() (w, h int) {
+	w, h = int(d.uvarint()), int(d.uvarint())
+	if w > 1<<16 || h > 1<<16 {
+		d.fail()
+	}
+	return w, h
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = errFormat
+	}
+}
+
+// copySnapshot copies a snapshot so later changes to it don't reach the
+// recording. Held keys are stored as 1.
+func copySnapshot(s *input.Snapshot) input.Snapshot {
+	c := input.Snapshot{Keys: make([]uint8, len(s.Keys)), Mouse: s.Mouse, Pads: make([]input.Pad, len(s.Pads))}
+	for k, v := range s.Keys {
+		if v != 0 {
+			c.Keys[k] = 1
+		}
+	}
+	copy(c.Pads, s.Pads)
+	return c
+}
+
+// header is what a recording starts with
+type header struct {
+	seed       int64
+	w, h       int
+	fullscreen bool
+}
+
+func writeHeader(e *encoder, hd header) {
+	for i := 0; i < len(magic); i++ {
+		e.byte(magic[i])
+	}
+	e.uvarint(version)
+	e.varint(hd.seed)
+	e.size(hd.w, hd.h)
+	var fullscreen byte
+	if hd.fullscreen {
+		fullscreen = 1
+	}
+	e.byte(fullscreen)
+}
+
+func readHeader(d *decoder) (header, error) {
+	var hd header
+	var m [len(magic)]byte
+	if _, err := io.ReadFull(d.r, m[:]); err != nil || string(m[:]) != magic {
+		return hd, errFormat
+	}
+	if d.uvarint() != version {
+		return hd, errors.New("replay: unknown version")
+	}
+	hd.seed = d.varint()
+	hd.w, hd.h = d.size()
+	switch d.byte() {
+	case 0:
+	case 1:
+		hd.fullscreen = true
+	default:
+		d.fail()
+	}
+	return hd, d.err
+}
@@ -0,0 +1,157 @@
+package replay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+// play is a small game. It runs until the input runs out, marking where
+// the pointer is as a fraction of the window, and returns what it added up
+// from the input, clock and window size. resize, when set, is called
+// before each frame.
+func play(p platform.Platform, resize func(frame int)) float64 {
+	var state float64
+	start := p.Now()
+	for frame := 0; ; frame++ {
+		if resize != nil {
+			resize(frame)
+		}
+		if !p.Poll() {
+			return state
+		}
+		s := p.Input()
+		w, h := p.Size()
+		fx, fy := float64(s.Mouse.X)/float64(w), float64(s.Mouse.Y)/float64(h)
+		state += fx + 2*fy + p.Now().Sub(start).Seconds()
+		if s.Keys[input.KeySpace] != 0 {
+			state *= 1.5
+		}
+
+		fb := framebuffer.New(w, h)
+		fb.FillRect(int(fx*64), int(fy*48), 4, 4, palette.Color{R: 255, G: 255, B: 255})
+		p.DrawFramebuffer(fb)
+		p.Present()
+	}
+}
+
+// script holds the pointer moving across a 64 by 48 window with space
+// tapped now and then
+func script(h *platform.Headless) {
+	for i := 0; i < 40; i++ {
+		s := input.NewSnapshot()
+		if i%7 == 0 {
+			s = input.NewSnapshot(input.KeySpace)
+		}
+		s.Mouse = input.Mouse{X: i, Y: 47 - i, Buttons: uint32(i % 2)}
+		h.Script(s)
+	}
+}
+
+// record plays the game on a 64 by 48 window
+func record(t *testing.T, seed int64, resize func(h *platform.Headless, frame int)) (*platform.Headless, float64, []byte) {
+	t.Helper()
+	h := platform.NewHeadless(64, 48)
+	h.Record = true
+	h.FrameTime = 13 * time.Millisecond
+	script(h)
+	var buf bytes.Buffer
+	r, err := NewRecorder(h, &buf, seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hook func(int)
+	if resize != nil {
+		hook = func(frame int) { resize(h, frame) }
+	}
+	state := play(r, hook)
+	r.Close()
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return h, state, buf.Bytes()
+}
+
+func TestRecordReplay(t *testing.T) {
+	rec, want, data := record(t, 42, nil)
+
+	// a fullscreen window of another size, and a different clock
+	h := platform.NewHeadless(100, 30)
+	h.SetFullscreen(true)
+	h.Record = true
+	h.FrameTime = time.Second
+	h.Hold(1000, input.NewSnapshot())
+	p, err := NewPlayer(h, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Seed() != 42 {
+		t.Errorf("replay seed is %d, want 42", p.Seed())
+	}
+	if w, hh := h.Size(); w != 64 || hh != 48 || h.Fullscreen() {
+		t.Errorf("window put back to %dx%d fullscreen %v, want 64x48 in a window", w, hh, h.Fullscreen())
+	}
+
+	got := play(p, nil)
+	p.Close()
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("replay ended in state %v, recording in %v", got, want)
+	}
+	if len(h.Frames) != len(rec.Frames) {
+		t.Fatalf("replay presented %d frames, recording %d", len(h.Frames), len(rec.Frames))
+	}
+	for i := range h.Frames {
+		if string(h.Frames[i].Pixels) != string(rec.Frames[i].Pixels) {
+			t.Fatalf("frame %d differs", i)
+		}
+	}
+}
+
+// TestReplayFollowsResize resizes the window part way through recording,
+// the replay should see the same sizes though its window stays put
+func TestReplayFollowsResize(t *testing.T) {
+	_, want, data := record(t, 1, func(h *platform.Headless, frame int) {
+		if frame == 20 {
+			h.Resize(128, 96)
+		}
+	})
+	h := platform.NewHeadless(64, 48)
+	h.Hold(1000, input.NewSnapshot())
+	p, err := NewPlayer(h, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := play(p, nil); got != want {
+		t.Errorf("replay ended in state %v, recording in %v", got, want)
+	}
+	if err := p.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNotARecording(t *testing.T) {
+	h := platform.NewHeadless(64, 48)
+	if _, err := NewPlayer(h, strings.NewReader("not a recording")); err == nil {
+		t.Error("played a string")
+	}
+	_, _, data := record(t, 1, nil)
+	// cut the recording off part way through a record
+	p, err := NewPlayer(h, bytes.NewReader(data[:len(data)/2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Hold(1000, input.NewSnapshot())
+	play(p, nil)
+	if p.Err() == nil {
+		t.Error("a cut off recording played without an error")
+	}
+}
@@ -1,5 +1,10 @@
-module github.com/stephen-mahon/games-with-go
+module github.com/stephen-mahon/games-with-go/sdl2
 
 go 1.16
 
-require github.com/veandco/go-sdl2 v0.4.8
+require (
+	github.com/stephen-mahon/games-with-go v0.0.0-00010101000000-000000000000
+	github.com/veandco/go-sdl2 v0.4.8
+)
+
+replace github.com/stephen-mahon/games-with-go => ../
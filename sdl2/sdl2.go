@@ -3,25 +3,13 @@ package main
 import (
 	"fmt"
 
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 const winWidth, winHeight int = 800, 600
 
-type color struct {
-	r, g, b byte
-}
-
-func setPixel(x, y int, c color, pixels []byte) {
-	index := (y*winWidth + x) * 4
-	if index < len(pixels)-4 && index >= 0 {
-		pixels[index] = c.r
-		pixels[index+1] = c.g
-		pixels[index+2] = c.b
-	}
-
-}
-
 func main() {
 
 	err := sdl.Init(sdl.INIT_EVERYTHING)
@@ -53,15 +41,15 @@ func main() {
 	}
 	defer tex.Destroy()
 
-	pixels := make([]byte, winWidth*winHeight*4)
+	fb := framebuffer.New(winWidth, winHeight)
 
 	for y := 0; y < winHeight; y++ {
 		for x := 0; x < winWidth; x++ {
-			setPixel(x, y, color{byte(x % 255), byte(y % 255), 0}, pixels)
+			fb.SetPixel(x, y, palette.Color{R: byte(x % 255), G: byte(y % 255)})
 		}
 	}
 
-	tex.Update(nil, pixels, winWidth*4)
+	tex.Update(nil, fb.Pixels, fb.Stride)
 	renderer.Copy(tex, nil, nil)
 	renderer.Present()
 
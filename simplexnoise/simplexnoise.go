@@ -1,69 +1,25 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"math"
 	"runtime"
 	"sync"
 	"time"
 
-	"github.com/veandco/go-sdl2/sdl"
+	"github.com/stephen-mahon/games-with-go/capture"
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/loop"
+	"github.com/stephen-mahon/games-with-go/palette"
+	"github.com/stephen-mahon/games-with-go/platform"
+	"github.com/stephen-mahon/games-with-go/platform/sdlplatform"
+	"github.com/stephen-mahon/games-with-go/replay"
 )
 
 const winWidth, winHeight int = 800, 600
 
-func lerp(b1 byte, b2 byte, pct float32) byte {
-	return byte(float32(b1) + pct*(float32(b2)-float32(b1)))
-}
-
-func colorLerp(c1, c2 color, pct float32) color {
-	return color{lerp(c1.r, c2.r, pct), lerp(c1.g, c2.g, pct), lerp(c1.b, c2.b, pct)}
-}
-
-func getGradient(c1, c2 color) []color {
-	result := make([]color, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		result[i] = colorLerp(c1, c2, pct)
-	}
-	return result
-}
-
-func getDualGradient(c1, c2, c3, c4 color) []color {
-	result := make([]color, 256)
-	for i := range result {
-		pct := float32(i) / float32(255)
-		if pct < 0.5 {
-			result[i] = colorLerp(c1, c2, pct*float32(2))
-		} else {
-			result[i] = colorLerp(c3, c4, pct*float32(1.5)-float32(0.5))
-		}
-	}
-	return result
-}
-
-func clamp(min, max, v int) int {
-	if v < min {
-		v = min
-	} else if v > max {
-		v = max
-	}
-	return v
-}
-
-func rescaleAndDraw(noise []float32, min, max float32, gradient []color, pixels []byte) {
-	scale := 255.0 / (max - min)
-	offset := min * scale
-
-	for i := range noise {
-		noise[i] = noise[i]*scale - offset
-		c := gradient[clamp(0, 255, int(noise[i]))]
-		p := i * 4
-		pixels[p] = c.r
-		pixels[p+1] = c.g
-		pixels[p+2] = c.b
-	}
-}
-
 func turbulence(x, y, frequency, lacunarity, gain float32, octaves int) float32 {
 	var sum float32
 	amplitude := float32(1.0)
@@ -91,136 +47,178 @@ func fbm2(x, y, frequency, lacunarity, gain float32, octaves int) float32 {
 	return sum
 }
 
-func makeNoise(pixels []byte, frequency, lacunarity, gain float32, octaves, w int) {
+func makeNoise(fb *framebuffer.Framebuffer, frequency, lacunarity, gain float32, octaves int) {
 	startTime := time.Now()
-	noise := make([]float32, winWidth*winHeight)
 	fmt.Println("freq:", frequency, "lac:", lacunarity, "gain:", gain, "octaves:", octaves)
-	min := float32(9999.0) //I think there is work around for this in Go
-	max := float32(-9999.0)
+	noise, min, max := turbulenceField(fb.W, fb.H, frequency, lacunarity, gain, octaves, runtime.NumCPU())
+	elsapedTime := time.Since(startTime).Seconds() * 1000.0
+	fmt.Println(elsapedTime)
+	//gradient := palette.NewDualGradient(palette.Color{B: 175}, palette.Color{R: 80, G: 160, B: 244}, palette.Color{R: 12, G: 192, B: 75}, palette.Color{R: 255, G: 255, B: 255})
+	gradient := palette.NewGradient(palette.Color{R: 255}, palette.Color{R: 255, G: 242})
+	gradient.Draw(noise, min, max, fb.Pixels)
+}
 
-	numRoutines := runtime.NumCPU()
+// turbulenceField works out turbulence for every pixel of a w by h field,
+// split between a number of goroutines, and the lowest and highest value.
+// Each goroutine keeps its own min and max, they are merged once all are
+// done, so the result is the same however many there are.
+func turbulenceField(w, h int, frequency, lacunarity, gain float32, octaves, routines int) (noise []float32, min, max float32) {
+	noise = make([]float32, w*h)
+	if len(noise) == 0 {
+		return noise, 0, 0
+	}
+	if routines < 1 {
+		routines = 1
+	}
+	mins := make([]float32, routines)
+	maxs := make([]float32, routines)
 	var wg sync.WaitGroup
-	wg.Add(numRoutines)
-	batchSize := len(noise) / numRoutines
-	for i := 0; i < numRoutines; i++ {
+	wg.Add(routines)
+	for i := 0; i < routines; i++ {
 		go func(i int) {
 			defer wg.Done()
-			start := i * batchSize
-			end := start + batchSize - 1
+			// batches split the field evenly, the last one ends on the last pixel
+			start := i * len(noise) / routines
+			end := (i + 1) * len(noise) / routines
+			min, max := float32(math.Inf(1)), float32(math.Inf(-1))
 			for j := start; j < end; j++ {
 				x := j % w
 				y := (j - x) / w
 				noise[j] = turbulence(float32(x), float32(y), frequency, lacunarity, gain, octaves)
-
-				// Does min max work in a multiple thread routine?
-				// mutex? https://tour.golang.org/concurrency/9
 				if noise[j] < min {
 					min = noise[j]
-				} else if noise[j] > max {
+				}
+				if noise[j] > max {
 					max = noise[j]
 				}
-
 			}
+			mins[i], maxs[i] = min, max
 		}(i)
 	}
 	wg.Wait()
-	elsapedTime := time.Since(startTime).Seconds() * 1000.0
-	fmt.Println(elsapedTime)
-	//gradient := getDualGradient(color{0, 0, 175}, color{80, 160, 244}, color{12, 192, 75}, color{255, 255, 255})
-	gradient := getGradient(color{255, 0, 0}, color{255, 242, 0})
-	rescaleAndDraw(noise, min, max, gradient, pixels)
-}
-
-type color struct {
-	r, g, b byte
-}
-
-func setPixel(x, y int, c color, pixels []byte) {
-	index := (y*winWidth + x) * 4
-	if index < len(pixels)-4 && index >= 0 {
-		pixels[index] = c.r
-		pixels[index+1] = c.g
-		pixels[index+2] = c.b
+	min, max = mins[0], maxs[0]
+	for i := 1; i < routines; i++ {
+		if mins[i] < min {
+			min = mins[i]
+		}
+		if maxs[i] > max {
+			max = maxs[i]
+		}
 	}
-
+	return noise, min, max
 }
 
 func main() {
+	recordFile := flag.String("record", "", "record the session to a file")
+	replayFile := flag.String("replay", "", "play back a recorded session")
+	framesDir := flag.String("frames", "", "save every frame as a png in this directory")
+	fps := flag.Int("fps", 60, "frame rate for -frames")
+	flag.Parse()
 
-	err := sdl.Init(sdl.INIT_EVERYTHING)
+	sdlPlat, err := sdlplatform.New("Testing SDL2", winWidth, winHeight)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer sdl.Quit()
-
-	window, err := sdl.CreateWindow("Testing SDL2", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
-		int32(winWidth), int32(winHeight), sdl.WINDOW_SHOWN)
+	capt := capture.New(sdlPlat)
+	if *framesDir != "" {
+		err = capt.Sequence(*framesDir, *fps)
+		if err != nil {
+			capt.Close()
+			fmt.Println(err)
+			return
+		}
+	}
+	// nothing here is random so the seed goes unused
+	plat, _, err := replay.Open(capt, *recordFile, *replayFile)
 	if err != nil {
+		capt.Close()
 		fmt.Println(err)
 		return
 	}
-	defer window.Destroy()
 
-	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED)
+	err = run(plat)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer renderer.Destroy()
-
-	tex, err := renderer.CreateTexture(sdl.PIXELFORMAT_ABGR8888, sdl.TEXTUREACCESS_STREAMING, int32(winWidth), int32(winHeight))
+	if err := capt.Err(); err != nil {
+		fmt.Println(err)
+	}
+	err = replay.Close(plat)
 	if err != nil {
 		fmt.Println(err)
-		return
 	}
-	defer tex.Destroy()
+}
 
-	pixels := make([]byte, winWidth*winHeight*4)
+// run shows the noise until the platform's window closes
+func run(plat platform.Platform) error {
+	fb := framebuffer.New(winWidth, winHeight)
 	frequency := float32(0.01)
 	gain := float32(0.2)
 	lac := float32(3.0)
 	octaves := 3
 
-	makeNoise(pixels, frequency, lac, gain, octaves, winWidth)
-	keyState := sdl.GetKeyboardState()
+	makeNoise(fb, frequency, lac, gain, octaves)
 
-	// game loop
-	for {
-		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
-			switch event.(type) {
-			case *sdl.QuitEvent:
-				return
-			}
-		}
+	inputs := input.NewMap()
+	inputs.Bind("octaves", input.BindKey(input.KeyO))
+	inputs.Bind("frequency", input.BindKey(input.KeyF))
+	inputs.Bind("gain", input.BindKey(input.KeyG))
+	inputs.Bind("lacunarity", input.BindKey(input.KeyL))
+	inputs.Bind("reverse", input.BindKey(input.KeyLShift), input.BindKey(input.KeyRShift))
+	err := inputs.LoadFile("controls.txt")
+	if err != nil {
+		return err
+	}
 
+	update := func(elaspedTime float32) {
 		mult := 1
-		if keyState[sdl.SCANCODE_LSHIFT] != 0 || keyState[sdl.SCANCODE_RSHIFT] != 0 {
+		if inputs.Held("reverse") {
 			mult = -1
 		}
-		if keyState[sdl.SCANCODE_O] != 0 {
+		if inputs.Held("octaves") {
 			octaves += 1 * mult
-			makeNoise(pixels, frequency, lac, gain, octaves, winWidth)
+			makeNoise(fb, frequency, lac, gain, octaves)
 
 		}
-		if keyState[sdl.SCANCODE_F] != 0 {
+		if inputs.Held("frequency") {
 			frequency += 0.001 * float32(mult)
-			makeNoise(pixels, frequency, lac, gain, octaves, winWidth)
+			makeNoise(fb, frequency, lac, gain, octaves)
 		}
-		if keyState[sdl.SCANCODE_G] != 0 {
+		if inputs.Held("gain") {
 			gain += 0.1 * float32(mult)
-			makeNoise(pixels, frequency, lac, gain, octaves, winWidth)
+			makeNoise(fb, frequency, lac, gain, octaves)
 		}
-		if keyState[sdl.SCANCODE_L] != 0 {
+		if inputs.Held("lacunarity") {
 			lac += 0.1 * float32(mult)
-			makeNoise(pixels, frequency, lac, gain, octaves, winWidth)
+			makeNoise(fb, frequency, lac, gain, octaves)
 		}
+		inputs.Tick()
+	}
 
-		tex.Update(nil, pixels, winWidth*4)
-		renderer.Copy(tex, nil, nil)
-		renderer.Present()
-		sdl.Delay(16)
+	render := func(alpha float32) {
+		plat.DrawFramebuffer(fb)
+		plat.Present()
+	}
+
+	// game loop
+	gameLoop, err := loop.New(60)
+	if err != nil {
+		return err
+	}
+	gameLoop.MaxFPS = 60
+	gameLoop.Clock = plat
+	// read the input every frame, so a tap on a frame with no update
+	// still counts
+	events := func() bool {
+		if !plat.Poll() {
+			return false
+		}
+		inputs.Update(plat.Input())
+		return true
 	}
+	gameLoop.Run(events, update, render)
+	return nil
 }
 
 /* This code ported to Go from Stefan Gustavson's C implementation, his comments follow:
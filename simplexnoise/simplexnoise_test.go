@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/golden"
+	"github.com/stephen-mahon/games-with-go/input"
+	"github.com/stephen-mahon/games-with-go/platform"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden images in testdata")
+
+// TestTurbulenceField checks every pixel is worked out, and the field and
+// its range come out the same however many goroutines share the work
+func TestTurbulenceField(t *testing.T) {
+	// a size no number of goroutines below divides evenly
+	w, h := 37, 11
+	want := make([]float32, w*h)
+	for i := range want {
+		want[i] = turbulence(float32(i%w), float32(i/w), .01, 3, .2, 3)
+	}
+	wantMin, wantMax := want[0], want[0]
+	for _, v := range want {
+		if v < wantMin {
+			wantMin = v
+		}
+		if v > wantMax {
+			wantMax = v
+		}
+	}
+	for _, routines := range []int{1, 2, 3, 7, 64, len(want) + 1} {
+		noise, min, max := turbulenceField(w, h, .01, 3, .2, 3, routines)
+		if min != wantMin || max != wantMax {
+			t.Errorf("%d goroutines found a range of %v to %v, want %v to %v", routines, min, max, wantMin, wantMax)
+		}
+		for i := range want {
+			if noise[i] != want[i] {
+				t.Errorf("%d goroutines got pixel %d as %v, want %v", routines, i, noise[i], want[i])
+				break
+			}
+		}
+	}
+}
+
+// TestGolden renders the default settings headless and compares them
+// with testdata/default.png
+func TestGolden(t *testing.T) {
+	h := platform.NewHeadless(winWidth, winHeight)
+	h.Record = true
+	h.Script(input.NewSnapshot())
+	err := run(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = golden.Check("testdata/default.png", h.Frames[0], golden.DefaultTolerance, *update)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,164 @@
+package sprite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// drawReference is Draw written the obvious way: every pixel in the
+// sprite's bounding box is taken back through the full inverse transform,
+// sampled in floating point and blended with divides. Draw must match it
+// and beat it. It visits the same pixels Draw does, so the benchmark
+// compares the blitters and not the clipping.
+func drawReference(dst *framebuffer.Framebuffer, s *Sprite, x, y float32, opts Options) {
+	scaleX, scaleY := float64(opts.ScaleX), float64(opts.ScaleY)
+	if scaleX == 0 {
+		scaleX = 1
+	}
+	if scaleY == 0 {
+		scaleY = 1
+	}
+	texel := func(tx, ty int) (r, g, b, a float64) {
+		if tx < 0 || ty < 0 || tx >= s.W || ty >= s.H {
+			return 0, 0, 0, 0
+		}
+		i := ty*s.Pitch + tx*4
+		r, g, b, a = float64(s.Pixels[i]), float64(s.Pixels[i+1]), float64(s.Pixels[i+2]), float64(s.Pixels[i+3])
+		if !s.Premultiplied {
+			r, g, b = r*a/255, g*a/255, b*a/255
+		}
+		return r, g, b, a
+	}
+
+	sin, cos := math.Sincos(float64(opts.Angle))
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range [4][2]float64{{0, 0}, {float64(s.W), 0}, {0, float64(s.H)}, {float64(s.W), float64(s.H)}} {
+		px := (c[0] - float64(opts.PivotX)) * scaleX
+		py := (c[1] - float64(opts.PivotY)) * scaleY
+		cx := float64(x) + px*cos - py*sin
+		cy := float64(y) + px*sin + py*cos
+		minX, maxX = math.Min(minX, cx), math.Max(maxX, cx)
+		minY, maxY = math.Min(minY, cy), math.Max(maxY, cy)
+	}
+	x0 := int(math.Max(0, math.Floor(minX)))
+	x1 := int(math.Min(float64(dst.W), math.Ceil(maxX)))
+	y0 := int(math.Max(0, math.Floor(minY)))
+	y1 := int(math.Min(float64(dst.H), math.Ceil(maxY)))
+
+	for dy := y0; dy < y1; dy++ {
+		for dx := x0; dx < x1; dx++ {
+			ox := float64(dx) + 0.5 - float64(x)
+			oy := float64(dy) + 0.5 - float64(y)
+			u := (ox*cos+oy*sin)/scaleX + float64(opts.PivotX)
+			v := (-ox*sin+oy*cos)/scaleY + float64(opts.PivotY)
+			if u < -0.5 || v < -0.5 || u >= float64(s.W)+0.5 || v >= float64(s.H)+0.5 {
+				continue
+			}
+			var r, g, b, a float64
+			if opts.Filter == Bilinear {
+				fu, fv := math.Floor(u-0.5), math.Floor(v-0.5)
+				tx, ty := u-0.5-fu, v-0.5-fv
+				weights := [4]float64{(1 - tx) * (1 - ty), tx * (1 - ty), (1 - tx) * ty, tx * ty}
+				corners := [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}}
+				for i, c := range corners {
+					cr, cg, cb, ca := texel(int(fu)+c[0], int(fv)+c[1])
+					r += cr * weights[i]
+					g += cg * weights[i]
+					b += cb * weights[i]
+					a += ca * weights[i]
+				}
+			} else {
+				r, g, b, a = texel(int(math.Floor(u)), int(math.Floor(v)))
+			}
+			if opts.Tint != nil {
+				r = r * float64(opts.Tint.R) / 255
+				g = g * float64(opts.Tint.G) / 255
+				b = b * float64(opts.Tint.B) / 255
+			}
+			p := dst.Pixels[dy*dst.Stride+dx*4:]
+			inv := 1 - a/255
+			p[0] = byte(math.Round(r + float64(p[0])*inv))
+			p[1] = byte(math.Round(g + float64(p[1])*inv))
+			p[2] = byte(math.Round(b + float64(p[2])*inv))
+			p[3] = byte(math.Round(a + float64(p[3])*inv))
+		}
+	}
+}
+
+// testSprite is a w by h sprite of changing colour and alpha
+func testSprite(w, h int, premultiplied bool) *Sprite {
+	s := &Sprite{Pixels: make([]byte, w*h*4), W: w, H: h, Pitch: w * 4}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*s.Pitch + x*4
+			s.Pixels[i], s.Pixels[i+1], s.Pixels[i+2], s.Pixels[i+3] = byte(x*255/w), byte(y*255/h), 200, byte((x+y)*255/(w+h))
+		}
+	}
+	if premultiplied {
+		return premultiply(s)
+	}
+	return s
+}
+
+var referenceCases = []struct {
+	name string
+	opts Options
+}{
+	{"copy", Options{}},
+	{"scaled", Options{ScaleX: 1.5, ScaleY: 2.25}},
+	{"bilinear", Options{ScaleX: 1.5, ScaleY: 2.25, Filter: Bilinear}},
+	{"rotated", Options{Angle: 0.7, PivotX: 16, PivotY: 16, Filter: Bilinear}},
+	{"tinted", Options{Angle: -2, ScaleX: 0.75, ScaleY: 0.75, Tint: &palette.Color{R: 255, G: 100, B: 30}}},
+}
+
+func TestDrawMatchesReference(t *testing.T) {
+	for _, premultiplied := range []bool{false, true} {
+		s := testSprite(32, 32, premultiplied)
+		for _, tc := range referenceCases {
+			got, want := framebuffer.New(80, 80), framebuffer.New(80, 80)
+			got.Fill(palette.Color{R: 20, G: 90, B: 160})
+			want.Fill(palette.Color{R: 20, G: 90, B: 160})
+			Draw(got, s, 30.25, 20.5, tc.opts)
+			drawReference(want, s, 30.25, 20.5, tc.opts)
+
+			// fixed point rounding and pixel centres on the edge of the
+			// sprite can come out a little differently
+			off := 0
+			for y := 0; y < got.H; y++ {
+				for x := 0; x < got.W; x++ {
+					if !near(got.Pixel(x, y), want.Pixel(x, y), 3) {
+						off++
+					}
+				}
+			}
+			if off > 32 {
+				t.Errorf("%s premultiplied %v: %d pixels differ from the reference", tc.name, premultiplied, off)
+			}
+		}
+	}
+}
+
+func BenchmarkBlit(b *testing.B) {
+	s := testSprite(64, 64, true)
+	fb := framebuffer.New(320, 240)
+	blitters := []struct {
+		name string
+		draw func(*framebuffer.Framebuffer, *Sprite, float32, float32, Options)
+	}{
+		{"naive", drawReference},
+		{"optimised", Draw},
+	}
+	for _, tc := range referenceCases {
+		for _, blit := range blitters {
+			b.Run(tc.name+"/"+blit.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					blit.draw(fb, s, 120, 80, tc.opts)
+				}
+			})
+		}
+	}
+}
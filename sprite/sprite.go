@@ -0,0 +1,285 @@
+// Alpha blended, scaled and rotated sprites for the software renderer
+package sprite
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// Sprite is a w by h block of rgba pixels, Pitch bytes per row. When
+// Premultiplied is set the rgb have already been multiplied by alpha.
+type Sprite struct {
+	Pixels        []byte
+	W, H, Pitch   int
+	Premultiplied bool
+}
+
+// Filter indicates how a scaled or rotated sprite is sampled
+type Filter int
+
+const (
+	Nearest Filter = iota
+	Bilinear
+)
+
+// Options for Draw. The zero value draws the sprite unscaled and unrotated
+// with its top left corner at the draw position.
+type Options struct {
+	// ScaleX and ScaleY of zero are treated as one
+	ScaleX, ScaleY float32
+	// Angle in radians, clockwise on screen, about the pivot
+	Angle float32
+	// PivotX and PivotY are in sprite pixels and land on the draw position
+	PivotX, PivotY float32
+	Filter         Filter
+	// Tint multiplies every pixel when set
+	Tint *palette.Color
+}
+
+// FromImage copies an image into a sprite with either straight or
+// premultiplied alpha
+func FromImage(img image.Image, premultiplied bool) *Sprite {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	s := &Sprite{make([]byte, w*h*4), w, h, w * 4, premultiplied}
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// At().RGBA() is always premultiplied, NRGBA is straight
+			var r, g, b, a uint32
+			if premultiplied {
+				r, g, b, a = img.At(x, y).RGBA()
+			} else {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				r, g, b, a = uint32(c.R)<<8, uint32(c.G)<<8, uint32(c.B)<<8, uint32(c.A)<<8
+			}
+			s.Pixels[i] = byte(r >> 8)
+			s.Pixels[i+1] = byte(g >> 8)
+			s.Pixels[i+2] = byte(b >> 8)
+			s.Pixels[i+3] = byte(a >> 8)
+			i += 4
+		}
+	}
+	return s
+}
+
+// Load decodes a png file into a premultiplied sprite
+func Load(filename string) (*Sprite, error) {
+	infile, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+	img, err := png.Decode(infile)
+	if err != nil {
+		return nil, err
+	}
+	return FromImage(img, true), nil
+}
+
+// pixel returns the premultiplied colour at x, y, transparent off the sprite
+func (s *Sprite) pixel(x, y int) (r, g, b, a int) {
+	if x < 0 || y < 0 || x >= s.W || y >= s.H {
+		return 0, 0, 0, 0
+	}
+	i := y*s.Pitch + x*4
+	r, g, b, a = int(s.Pixels[i]), int(s.Pixels[i+1]), int(s.Pixels[i+2]), int(s.Pixels[i+3])
+	if !s.Premultiplied {
+		r = r * a / 255
+		g = g * a / 255
+		b = b * a / 255
+	}
+	return r, g, b, a
+}
+
+// bilinear samples at u, v where pixel centres sit on whole numbers
+func (s *Sprite) bilinear(u, v float32) (r, g, b, a int) {
+	fu, fv := float32(math.Floor(float64(u))), float32(math.Floor(float64(v)))
+	x0, y0 := int(fu), int(fv)
+	tx := int((u - fu) * 256)
+	ty := int((v - fv) * 256)
+
+	if s.Premultiplied && x0 >= 0 && y0 >= 0 && x0+1 < s.W && y0+1 < s.H {
+		// all four texels are on the sprite, read them directly
+		i00 := y0*s.Pitch + x0*4
+		i01 := i00 + s.Pitch
+		p := s.Pixels
+		return blerp(p[i00], p[i00+4], p[i01], p[i01+4], tx, ty),
+			blerp(p[i00+1], p[i00+5], p[i01+1], p[i01+5], tx, ty),
+			blerp(p[i00+2], p[i00+6], p[i01+2], p[i01+6], tx, ty),
+			blerp(p[i00+3], p[i00+7], p[i01+3], p[i01+7], tx, ty)
+	}
+
+	r00, g00, b00, a00 := s.pixel(x0, y0)
+	r10, g10, b10, a10 := s.pixel(x0+1, y0)
+	r01, g01, b01, a01 := s.pixel(x0, y0+1)
+	r11, g11, b11, a11 := s.pixel(x0+1, y0+1)
+	return blerpInt(r00, r10, r01, r11, tx, ty), blerpInt(g00, g10, g01, g11, tx, ty),
+		blerpInt(b00, b10, b01, b11, tx, ty), blerpInt(a00, a10, a01, a11, tx, ty)
+}
+
+// blerp interpolates four texels with tx and ty in 1/256ths
+func blerp(c00, c10, c01, c11 byte, tx, ty int) int {
+	return blerpInt(int(c00), int(c10), int(c01), int(c11), tx, ty)
+}
+
+func blerpInt(c00, c10, c01, c11, tx, ty int) int {
+	top := c00*256 + (c10-c00)*tx
+	bottom := c01*256 + (c11-c01)*tx
+	return (top*256 + (bottom-top)*ty) >> 16
+}
+
+// https://en.wikipedia.org/wiki/Alpha_compositing
+// With premultiplied colour "over" is just src + dst*(1-srcAlpha)
+func blend(dst []byte, r, g, b, a int) {
+	if a == 0 {
+		return
+	}
+	inv := 255 - a
+	dst[0] = byte(r + div255(int(dst[0])*inv))
+	dst[1] = byte(g + div255(int(dst[1])*inv))
+	dst[2] = byte(b + div255(int(dst[2])*inv))
+	dst[3] = byte(a + div255(int(dst[3])*inv))
+}
+
+// div255 is a rounded v/255 without the divide, exact for 0 to 255*255
+func div255(v int) int {
+	v += 128
+	return (v + v>>8) >> 8
+}
+
+// Draw blends the sprite onto dst so that its pivot lands on x, y
+func Draw(dst *framebuffer.Framebuffer, s *Sprite, x, y float32, opts Options) {
+	scaleX, scaleY := opts.ScaleX, opts.ScaleY
+	if scaleX == 0 {
+		scaleX = 1
+	}
+	if scaleY == 0 {
+		scaleY = 1
+	}
+	if opts.Angle == 0 && opts.Filter == Nearest && opts.Tint == nil && scaleX == 1 && scaleY == 1 {
+		drawUnscaled(dst, s, int(math.Floor(float64(x-opts.PivotX))), int(math.Floor(float64(y-opts.PivotY))))
+		return
+	}
+
+	sin, cos := math.Sincos(float64(opts.Angle))
+	sinA, cosA := float32(sin), float32(cos)
+
+	// screen position of a sprite point sx, sy
+	toScreen := func(sx, sy float32) (float32, float32) {
+		px := (sx - opts.PivotX) * scaleX
+		py := (sy - opts.PivotY) * scaleY
+		return x + px*cosA - py*sinA, y + px*sinA + py*cosA
+	}
+
+	// bounding box of the four corners, clipped to the framebuffer
+	minX, minY := float32(math.MaxFloat32), float32(math.MaxFloat32)
+	maxX, maxY := float32(-math.MaxFloat32), float32(-math.MaxFloat32)
+	for _, c := range [4][2]float32{{0, 0}, {float32(s.W), 0}, {0, float32(s.H)}, {float32(s.W), float32(s.H)}} {
+		cx, cy := toScreen(c[0], c[1])
+		minX = float32(math.Min(float64(minX), float64(cx)))
+		minY = float32(math.Min(float64(minY), float64(cy)))
+		maxX = float32(math.Max(float64(maxX), float64(cx)))
+		maxY = float32(math.Max(float64(maxY), float64(cy)))
+	}
+	x0 := clamp(0, dst.W, int(math.Floor(float64(minX))))
+	x1 := clamp(0, dst.W, int(math.Ceil(float64(maxX))))
+	y0 := clamp(0, dst.H, int(math.Floor(float64(minY))))
+	y1 := clamp(0, dst.H, int(math.Ceil(float64(maxY))))
+	if x0 >= x1 || y0 >= y1 {
+		return
+	}
+
+	// Walking one pixel along a screen row moves a fixed step through the
+	// sprite, so the inverse transform is only worked out once per row
+	duDx, dvDx := cosA/scaleX, -sinA/scaleY
+	duDy, dvDy := sinA/scaleX, cosA/scaleY
+	fx := float32(x0) + 0.5 - x
+	fy := float32(y0) + 0.5 - y
+	rowU := opts.PivotX + fx*duDx + fy*duDy
+	rowV := opts.PivotY + fx*dvDx + fy*dvDy
+
+	tr, tg, tb := 256, 256, 256
+	if opts.Tint != nil {
+		tr, tg, tb = int(opts.Tint.R)+1, int(opts.Tint.G)+1, int(opts.Tint.B)+1
+	}
+	fw, fh := float32(s.W), float32(s.H)
+
+	for sy := y0; sy < y1; sy++ {
+		u, v := rowU, rowV
+		dstIndex := sy*dst.Stride + x0*4
+		for sx := x0; sx < x1; sx++ {
+			if u >= -0.5 && v >= -0.5 && u < fw+0.5 && v < fh+0.5 {
+				var r, g, b, a int
+				if opts.Filter == Bilinear {
+					r, g, b, a = s.bilinear(u-0.5, v-0.5)
+				} else {
+					r, g, b, a = s.pixel(int(math.Floor(float64(u))), int(math.Floor(float64(v))))
+				}
+				if opts.Tint != nil {
+					r = r * tr >> 8
+					g = g * tg >> 8
+					b = b * tb >> 8
+				}
+				blend(dst.Pixels[dstIndex:dstIndex+4], r, g, b, a)
+			}
+			u += duDx
+			v += dvDx
+			dstIndex += 4
+		}
+		rowU += duDy
+		rowV += dvDy
+	}
+}
+
+// drawUnscaled is the common case of a sprite copied pixel for pixel
+func drawUnscaled(dst *framebuffer.Framebuffer, s *Sprite, x, y int) {
+	sx0, sy0 := 0, 0
+	w, h := s.W, s.H
+	if x < 0 {
+		sx0 = -x
+	}
+	if y < 0 {
+		sy0 = -y
+	}
+	if x+w > dst.W {
+		w = dst.W - x
+	}
+	if y+h > dst.H {
+		h = dst.H - y
+	}
+	for sy := sy0; sy < h; sy++ {
+		srcRow := s.Pixels[sy*s.Pitch:]
+		dstRow := dst.Pixels[(y+sy)*dst.Stride:]
+		for sx := sx0; sx < w; sx++ {
+			src := srcRow[sx*4 : sx*4+4]
+			a := int(src[3])
+			if a == 0 {
+				continue
+			}
+			r, g, b := int(src[0]), int(src[1]), int(src[2])
+			if !s.Premultiplied {
+				r = r * a / 255
+				g = g * a / 255
+				b = b * a / 255
+			}
+			i := (x + sx) * 4
+			blend(dstRow[i:i+4], r, g, b, a)
+		}
+	}
+}
+
+func clamp(min, max, v int) int {
+	if v < min {
+		v = min
+	} else if v > max {
+		v = max
+	}
+	return v
+}
@@ -0,0 +1,174 @@
+package sprite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/framebuffer"
+	"github.com/stephen-mahon/games-with-go/palette"
+)
+
+// solid makes a w by h sprite of one straight alpha colour
+func solid(w, h int, c palette.Color, a byte) *Sprite {
+	s := &Sprite{Pixels: make([]byte, w*h*4), W: w, H: h, Pitch: w * 4}
+	for i := 0; i < len(s.Pixels); i += 4 {
+		s.Pixels[i], s.Pixels[i+1], s.Pixels[i+2], s.Pixels[i+3] = c.R, c.G, c.B, a
+	}
+	return s
+}
+
+// premultiply returns a copy of s with premultiplied alpha
+func premultiply(s *Sprite) *Sprite {
+	p := &Sprite{Pixels: make([]byte, len(s.Pixels)), W: s.W, H: s.H, Pitch: s.Pitch, Premultiplied: true}
+	for i := 0; i < len(s.Pixels); i += 4 {
+		a := int(s.Pixels[i+3])
+		for c := 0; c < 3; c++ {
+			p.Pixels[i+c] = byte(int(s.Pixels[i+c]) * a / 255)
+		}
+		p.Pixels[i+3] = byte(a)
+	}
+	return p
+}
+
+func near(a, b palette.Color, tolerance int) bool {
+	d := func(x, y byte) bool {
+		diff := int(x) - int(y)
+		return diff >= -tolerance && diff <= tolerance
+	}
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B)
+}
+
+// covered is how many pixels of fb aren't black
+func covered(fb *framebuffer.Framebuffer) int {
+	n := 0
+	for y := 0; y < fb.H; y++ {
+		for x := 0; x < fb.W; x++ {
+			if fb.Pixel(x, y) != (palette.Color{}) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestBlend(t *testing.T) {
+	blue := palette.Color{B: 255}
+	half := solid(2, 2, palette.Color{R: 255}, 128)
+	for _, s := range []*Sprite{half, premultiply(half)} {
+		fb := framebuffer.New(4, 4)
+		fb.Fill(blue)
+		Draw(fb, s, 1, 1, Options{})
+		if got := fb.Pixel(1, 1); !near(got, palette.Color{R: 128, B: 127}, 1) {
+			t.Errorf("premultiplied %v: half red over blue is %v", s.Premultiplied, got)
+		}
+		if got := fb.Pixel(0, 0); got != blue {
+			t.Errorf("premultiplied %v: drew outside the sprite", s.Premultiplied)
+		}
+	}
+
+	fb := framebuffer.New(2, 2)
+	fb.Fill(blue)
+	Draw(fb, solid(2, 2, palette.Color{R: 255}, 0), 0, 0, Options{})
+	if fb.Pixel(0, 0) != blue {
+		t.Error("a transparent sprite changed the background")
+	}
+}
+
+func TestDrawClips(t *testing.T) {
+	s := solid(4, 4, palette.Color{G: 255}, 255)
+	for _, pos := range [][2]float32{{-2, -2}, {6, 6}, {-2, 6}} {
+		fb := framebuffer.New(8, 8)
+		Draw(fb, s, pos[0], pos[1], Options{})
+		if n := covered(fb); n != 4 {
+			t.Errorf("sprite at %v covered %d pixels, want 4", pos, n)
+		}
+	}
+	fb := framebuffer.New(8, 8)
+	Draw(fb, s, 20, 20, Options{})
+	Draw(fb, s, -20, 2, Options{Angle: 1})
+	if n := covered(fb); n != 0 {
+		t.Errorf("sprites off the buffer covered %d pixels", n)
+	}
+}
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		scaleX, scaleY float32
+		w, h           int
+	}{
+		{2, 2, 8, 8},
+		{1.5, 1.5, 6, 6},
+		{0.5, 1, 2, 4},
+		{3, 0.25, 12, 1},
+	}
+	s := solid(4, 4, palette.Color{G: 255}, 255)
+	for _, filter := range []Filter{Nearest, Bilinear} {
+		for _, tt := range tests {
+			fb := framebuffer.New(16, 16)
+			Draw(fb, s, 0, 0, Options{ScaleX: tt.scaleX, ScaleY: tt.scaleY, Filter: filter})
+			if n := covered(fb); n != tt.w*tt.h {
+				t.Errorf("filter %d scale %v by %v covered %d pixels, want %d", filter, tt.scaleX, tt.scaleY, n, tt.w*tt.h)
+			}
+		}
+	}
+}
+
+func TestNearestKeepsTexels(t *testing.T) {
+	s := solid(2, 1, palette.Color{R: 255}, 255)
+	s.Pixels[4], s.Pixels[6] = 0, 255
+	fb := framebuffer.New(4, 2)
+	Draw(fb, s, 0, 0, Options{ScaleX: 2, ScaleY: 2})
+	for x := 0; x < 4; x++ {
+		want := palette.Color{R: 255}
+		if x >= 2 {
+			want = palette.Color{B: 255}
+		}
+		if got := fb.Pixel(x, 1); got != want {
+			t.Errorf("pixel %d is %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestBilinearBlendsTexels(t *testing.T) {
+	s := solid(2, 1, palette.Color{}, 255)
+	s.Pixels[4], s.Pixels[5], s.Pixels[6] = 200, 200, 200
+	fb := framebuffer.New(8, 4)
+	Draw(fb, s, 0, 0, Options{ScaleX: 4, ScaleY: 4, Filter: Bilinear})
+	// the texel centres land on 2 and 6, beyond them the edges fade out
+	prev := -1
+	for x := 2; x < 6; x++ {
+		v := int(fb.Pixel(x, 2).R)
+		if v < prev {
+			t.Fatalf("pixel %d is %d, darker than the one before", x, v)
+		}
+		prev = v
+	}
+	if v := fb.Pixel(4, 2).R; v < 50 || v > 150 {
+		t.Errorf("the middle is %d, want it between the texels", v)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	// a 4 by 1 bar turned a quarter about its top left corner hangs down
+	// to the left of the pivot
+	s := solid(4, 1, palette.Color{G: 255}, 255)
+	fb := framebuffer.New(8, 8)
+	Draw(fb, s, 4, 2, Options{Angle: math.Pi / 2})
+	for y := 2; y < 6; y++ {
+		if fb.Pixel(3, y) == (palette.Color{}) {
+			t.Errorf("pixel 3, %d wasn't drawn", y)
+		}
+	}
+	if n := covered(fb); n != 4 {
+		t.Errorf("rotated bar covered %d pixels, want 4", n)
+	}
+}
+
+func TestTint(t *testing.T) {
+	s := solid(2, 2, palette.Color{R: 255, G: 255, B: 255}, 255)
+	fb := framebuffer.New(2, 2)
+	Draw(fb, s, 0, 0, Options{Tint: &palette.Color{R: 255, G: 128}})
+	if got := fb.Pixel(0, 0); !near(got, palette.Color{R: 255, G: 128}, 1) {
+		t.Errorf("tinted white is %v", got)
+	}
+}
@@ -0,0 +1,56 @@
+package synth
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/stephen-mahon/games-with-go/wav"
+)
+
+// Presets are ready made sounds by name
+var Presets = map[string]Params{
+	"hit": {
+		Wave: Square, Duty: 0.5,
+		Sustain: 0.03, Decay: 0.08, Punch: 0.5,
+		Freq: 440, Slide: -3,
+	},
+	"bounce": {
+		Wave: Square, Duty: 0.25,
+		Sustain: 0.02, Decay: 0.06,
+		Freq: 220, Slide: -2,
+		Volume: 0.4,
+	},
+	"score": {
+		Wave: Square, Duty: 0.5,
+		Sustain: 0.15, Decay: 0.25, Punch: 0.3,
+		Freq: 523, ArpTime: 0.08, ArpMult: 1.5,
+	},
+	"lose": {
+		Wave:    Sawtooth,
+		Sustain: 0.2, Decay: 0.3,
+		Freq: 330, Slide: -1.5, MinFreq: 60,
+		VibratoDepth: 0.05, VibratoSpeed: 12,
+	},
+	"explosion": {
+		Wave:   Noise,
+		Attack: 0.005, Sustain: 0.1, Decay: 0.5, Punch: 0.6,
+		Freq: 800, Slide: -1.5,
+	},
+}
+
+// ExportPresets renders every preset at rate samples a second and saves it
+// to dir as name.wav, for listening to outside a game or for a sound editor
+func ExportPresets(dir string, rate int) error {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := wav.Save(filepath.Join(dir, name+".wav"), Generate(Presets[name], rate))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
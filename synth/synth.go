@@ -0,0 +1,130 @@
+// Sound effects generated from a handful of parameters, in the spirit of
+// sfxr
+// https://www.drpetter.se/project_sfxr.html
+package synth
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/stephen-mahon/games-with-go/noise"
+	"github.com/stephen-mahon/games-with-go/wav"
+)
+
+// Waveform is the shape of the oscillator
+type Waveform int
+
+const (
+	Square Waveform = iota
+	Sawtooth
+	Sine
+	// Noise is simplex noise run at the oscillator's frequency, so it still
+	// has a pitch and slides like the other waveforms
+	Noise
+)
+
+// Params describe a sound. Times are in seconds and frequencies in Hz.
+type Params struct {
+	Wave Waveform
+	// Duty is the fraction of a square wave spent high, zero is treated as
+	// a half
+	Duty float32
+
+	// the volume ramps up over Attack, holds for Sustain and falls away
+	// over Decay. Punch starts the sustain louder by that fraction.
+	Attack, Sustain, Decay float32
+	Punch                  float32
+
+	Freq float32
+	// Slide changes the frequency by this many octaves a second. The sound
+	// stops early if it slides below MinFreq.
+	Slide   float32
+	MinFreq float32
+	// VibratoDepth is a fraction of the frequency, VibratoSpeed in Hz
+	VibratoDepth, VibratoSpeed float32
+	// after ArpTime the frequency jumps by ArpMult, for jingles
+	ArpTime, ArpMult float32
+
+	// Volume of zero is treated as a half, full volume clips easily when
+	// mixed
+	Volume float32
+}
+
+// Generate renders a sound as mono samples at rate samples a second
+func Generate(p Params, rate int) *wav.Sound {
+	n := int((p.Attack + p.Sustain + p.Decay) * float32(rate))
+	data := make([]byte, 0, n*2)
+
+	duty := float64(p.Duty)
+	if duty == 0 {
+		duty = 0.5
+	}
+	volume := float64(p.Volume)
+	if volume == 0 {
+		volume = 0.5
+	}
+	freq := float64(p.Freq)
+	slide := math.Pow(2, float64(p.Slide)/float64(rate))
+	arpDone := p.ArpMult == 0
+	var phase, noisePos float64
+
+	for i := 0; i < n; i++ {
+		t := float64(i) / float64(rate)
+
+		if !arpDone && t >= float64(p.ArpTime) {
+			freq *= float64(p.ArpMult)
+			arpDone = true
+		}
+		freq *= slide
+		if p.MinFreq > 0 && freq < float64(p.MinFreq) {
+			break
+		}
+		f := freq
+		if p.VibratoDepth > 0 {
+			f *= 1 + float64(p.VibratoDepth)*math.Sin(2*math.Pi*float64(p.VibratoSpeed)*t)
+		}
+		phase += f / float64(rate)
+		noisePos += f / float64(rate)
+		phase -= math.Floor(phase)
+
+		var s float64
+		switch p.Wave {
+		case Square:
+			s = 1
+			if phase >= duty {
+				s = -1
+			}
+		case Sawtooth:
+			s = 1 - 2*phase
+		case Sine:
+			s = math.Sin(2 * math.Pi * phase)
+		case Noise:
+			// several noise features per cycle sounds like a hiss at the
+			// pitch. Snoise2 is unscaled, 40 brings it to about -1 to 1.
+			s = 40 * float64(noise.Snoise2(float32(noisePos*8), 0.5))
+		}
+
+		s *= envelope(p, t) * volume
+		if s > 1 {
+			s = 1
+		} else if s < -1 {
+			s = -1
+		}
+		data = append(data, 0, 0)
+		binary.LittleEndian.PutUint16(data[len(data)-2:], uint16(int16(s*32767)))
+	}
+	return &wav.Sound{Freq: rate, Channels: 1, Data: data}
+}
+
+func envelope(p Params, t float64) float64 {
+	attack, sustain, decay := float64(p.Attack), float64(p.Sustain), float64(p.Decay)
+	switch {
+	case t < attack:
+		return t / attack
+	case t < attack+sustain:
+		return 1 + float64(p.Punch)*(1-(t-attack)/sustain)
+	case decay > 0:
+		return math.Max(0, 1-(t-attack-sustain)/decay)
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package synth
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"testing"
+
+	"github.com/stephen-mahon/games-with-go/wav"
+)
+
+func TestGenerate(t *testing.T) {
+	p := Params{Wave: Square, Sustain: 0.1, Decay: 0.1, Freq: 100, Volume: 1}
+	s := Generate(p, 1000)
+	if s.Freq != 1000 || s.Channels != 1 || len(s.Data) != 400 {
+		t.Fatalf("generated %d Hz, %d channels and %d bytes", s.Freq, s.Channels, len(s.Data))
+	}
+	sample := func(i int) int16 { return int16(binary.LittleEndian.Uint16(s.Data[i*2:])) }
+	// high for the first half of each 10 sample cycle, then low
+	if sample(2) != 32767 || sample(7) != -32767 {
+		t.Errorf("square wave is %d then %d", sample(2), sample(7))
+	}
+	// fading out over the decay
+	if a, b := sample(122), sample(182); !(a > b && b > 0) {
+		t.Errorf("decay went from %d to %d", a, b)
+	}
+
+	p.Slide, p.MinFreq = -20, 50
+	if short := Generate(p, 1000); len(short.Data) >= len(s.Data) {
+		t.Error("sliding below the minimum frequency didn't stop the sound")
+	}
+}
+
+func TestExportPresets(t *testing.T) {
+	dir := t.TempDir()
+	if err := ExportPresets(dir, 22050); err != nil {
+		t.Fatal(err)
+	}
+	for name, p := range Presets {
+		s, err := wav.Load(filepath.Join(dir, name+".wav"))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if want := Generate(p, 22050); s.Freq != 22050 || string(s.Data) != string(want.Data) {
+			t.Errorf("%s was saved as %d bytes at %d Hz", name, len(s.Data), s.Freq)
+		}
+	}
+	if err := ExportPresets(filepath.Join(dir, "missing"), 22050); err == nil {
+		t.Error("exported to a directory that doesn't exist")
+	}
+}
@@ -0,0 +1,116 @@
+// Reads and writes pcm wav files as signed 16 bit samples
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// Sound is interleaved signed 16 bit little endian samples
+type Sound struct {
+	Freq     int
+	Channels int
+	Data     []byte
+}
+
+var errFormat = errors.New("wav: not a pcm wav file")
+
+// Decode reads an 8 or 16 bit pcm wav file. 8 bit samples are widened to
+// 16 bits.
+func Decode(r io.Reader) (*Sound, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" {
+		return nil, errFormat
+	}
+	var s Sound
+	bits := 0
+	for p := 12; p+8 <= len(b); {
+		id := string(b[p : p+4])
+		size := int(binary.LittleEndian.Uint32(b[p+4:]))
+		p += 8
+		if size < 0 || p+size > len(b) {
+			// some writers leave the data size wrong, take what is there
+			size = len(b) - p
+		}
+		chunk := b[p : p+size]
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 || binary.LittleEndian.Uint16(chunk) != 1 {
+				return nil, errFormat
+			}
+			s.Channels = int(binary.LittleEndian.Uint16(chunk[2:]))
+			s.Freq = int(binary.LittleEndian.Uint32(chunk[4:]))
+			bits = int(binary.LittleEndian.Uint16(chunk[14:]))
+		case "data":
+			if s.Channels == 0 {
+				return nil, errFormat
+			}
+			switch bits {
+			case 16:
+				s.Data = append([]byte(nil), chunk[:len(chunk)&^1]...)
+			case 8:
+				s.Data = make([]byte, len(chunk)*2)
+				for i, v := range chunk {
+					binary.LittleEndian.PutUint16(s.Data[i*2:], uint16(int16(int(v)-128)<<8))
+				}
+			default:
+				return nil, errors.New("wav: only 8 and 16 bit samples are supported")
+			}
+			return &s, nil
+		}
+		// chunks are padded to an even length
+		p += size + size&1
+	}
+	return nil, errFormat
+}
+
+// Load reads a wav file
+func Load(filename string) (*Sound, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// Encode writes a sound as a 16 bit pcm wav file
+func Encode(w io.Writer, s *Sound) error {
+	size := len(s.Data) &^ 1
+	h := make([]byte, 44)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], uint32(36+size))
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], 1)
+	binary.LittleEndian.PutUint16(h[22:], uint16(s.Channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(s.Freq))
+	binary.LittleEndian.PutUint32(h[28:], uint32(s.Freq*s.Channels*2))
+	binary.LittleEndian.PutUint16(h[32:], uint16(s.Channels*2))
+	binary.LittleEndian.PutUint16(h[34:], 16)
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], uint32(size))
+	if _, err := w.Write(h); err != nil {
+		return err
+	}
+	_, err := w.Write(s.Data[:size])
+	return err
+}
+
+// Save writes a sound to a wav file
+func Save(filename string, s *Sound) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = Encode(f, s)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}